@@ -7,11 +7,14 @@ import (
 	"time"
 
 	"github.com/maine/vietnam_bot_news/internal/app"
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/cluster"
 	"github.com/maine/vietnam_bot_news/internal/config"
 	"github.com/maine/vietnam_bot_news/internal/filter"
 	"github.com/maine/vietnam_bot_news/internal/formatter"
 	"github.com/maine/vietnam_bot_news/internal/gemini"
 	"github.com/maine/vietnam_bot_news/internal/ranking"
+	"github.com/maine/vietnam_bot_news/internal/safety"
 	"github.com/maine/vietnam_bot_news/internal/sources"
 	"github.com/maine/vietnam_bot_news/internal/state"
 	"github.com/maine/vietnam_bot_news/internal/telegram"
@@ -37,57 +40,75 @@ func main() {
 		log.Fatalf("load env config: %v", err)
 	}
 
+	// Одни часы на все этапы: при REPLAY_AT весь пайплайн живёт во времени прошлого дня
+	clk := clock.System()
+	if !envCfg.ReplayAt.IsZero() {
+		clk = clock.StartingAt(envCfg.ReplayAt)
+		log.Printf("REPLAY_AT: pipeline clock starts at %s", envCfg.ReplayAt.Format(time.RFC3339))
+	}
+
 	// Инициализируем модули
 	httpClient := &http.Client{Timeout: 15 * time.Second}
-	collector := sources.NewRSSCollector(sitesCfg.Sites, httpClient, time.Now)
-	f := filter.New(rootCfg.Pipeline)
+	// Лимит запросов и пауза вежливости для сайта общие для всех трёх коллекторов
+	collector := sources.NewMultiCollector(
+		sources.NewRSSCollector(sitesCfg.Sites, rootCfg.Sources, httpClient, clk),
+		sources.NewScrapeCollector(sitesCfg.Sites, rootCfg.Sources, httpClient, clk),
+		sources.NewSitemapCollector(sitesCfg.Sites, rootCfg.Sources, time.Duration(rootCfg.Pipeline.RecencyMaxHours)*time.Hour, httpClient, clk),
+	)
+	f := filter.New(rootCfg.Pipeline, clk)
 	stateStore := state.NewFileStore("state/state.json")
 	tgClient := telegram.NewClient(envCfg.TelegramBotToken)
 
-	// Инициализируем Gemini клиент только если не пропускаем Gemini
-	var geminiClient *gemini.Client
+	// Клиенты языковой модели создаём только если не пропускаем Gemini
 	var categorizer app.Categorizer
 	var ranker app.Ranker
 	var summarizer app.Summarizer
 	var msgFormatter app.Formatter
 	var sender app.Sender
+	var budget app.GeminiBudget
+	var quotaExempt map[string]bool
 
 	if !envCfg.SkipGemini {
-		// Клиент явно читает GEMINI_API_KEY из переменной окружения
-		var err error
-		geminiClient, err = gemini.NewClient()
+		// Провайдер и модель каждого этапа задаются блоком llm; клиент Gemini читает GEMINI_API_KEY
+		stages, err := newLLMStages(rootCfg, clk)
 		if err != nil {
-			log.Fatalf("failed to create Gemini client: %v", err)
+			log.Fatalf("failed to create LLM clients: %v", err)
 		}
 
 		// Инициализируем все модули пайплайна
-		categorizer = gemini.NewCategorizer(geminiClient, rootCfg.Gemini, rootCfg.Pipeline)
-		ranker = ranking.NewRanker(rootCfg.Pipeline, geminiClient, rootCfg.Gemini)
-		summarizer = gemini.NewSummarizer(geminiClient, rootCfg.Gemini)
-		msgFormatter = formatter.NewFormatter(rootCfg.Pipeline)
-		sender = telegram.NewSender(tgClient)
+		categorizer = gemini.NewCategorizer(stages.clients[config.StageCategorization], stages.models, rootCfg.Pipeline)
+		ranker = ranking.NewRanker(rootCfg.Pipeline, stages.clients[config.StageRanking], stages.models)
+		summarizer = gemini.NewSummarizer(stages.clients[config.StageSummary], stages.models)
+		budget = stages.budget
+		quotaExempt = stages.exempt
+		msgFormatter = formatter.NewFormatter(rootCfg.Pipeline, clk)
+		sender = telegram.NewSender(tgClient, clk)
 	} else {
 		// Если пропускаем Gemini, все равно инициализируем sender для тестового сообщения
-		sender = telegram.NewSender(tgClient)
+		sender = telegram.NewSender(tgClient, clk)
 	}
 
 	var recipientResolver app.RecipientResolver
 	if rootCfg.Pipeline.AutoSubscribe {
-		recipientResolver = telegram.NewRecipientManager(tgClient, true)
+		recipientResolver = telegram.NewRecipientManager(tgClient, true, clk)
 	}
 
 	// Создаём пайплайн
 	p := app.NewPipeline(app.PipelineDeps{
 		Collector:       collector,
 		Filter:          f,
+		Clusterer:       cluster.New(rootCfg.Pipeline),
 		Categorizer:     categorizer,
+		Safety:          safety.New(rootCfg.Pipeline.Safety),
 		Ranker:          ranker,
 		Summarizer:      summarizer,
 		Formatter:       msgFormatter,
 		Sender:          sender,
 		Recipients:      recipientResolver,
 		StateStore:      stateStore,
-		Clock:           nil, // используем time.Now по умолчанию
+		Budget:          budget,
+		QuotaExempt:     quotaExempt,
+		Clock:           clk,
 		ForceDispatch:   envCfg.ForceDispatch,
 		SkipGemini:      envCfg.SkipGemini,
 		SendTestMessage: envCfg.SendTestMessage,
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/app"
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/gemini"
+	"github.com/maine/vietnam_bot_news/internal/llm"
+)
+
+// llmStages — клиенты языковой модели для этапов, собранные по блоку llm конфига.
+// Клиент Gemini создаётся, только если на него ссылается хотя бы один этап,
+// поэтому с локальной моделью пайплайн работает без GEMINI_API_KEY.
+type llmStages struct {
+	clients map[string]llm.Client // Этап -> клиент (с переключением на запасной провайдер, если он задан)
+	models  config.Gemini         // Блок gemini с моделями этапов из llm.stages
+	budget  app.GeminiBudget      // Журнал квоты Gemini; nil, если Gemini не нужен ни одному этапу
+	exempt  map[string]bool       // Этапы, которые не планируются под квоту Gemini (app.PipelineDeps.QuotaExempt)
+}
+
+func newLLMStages(root config.Root, clk clock.Clock) (*llmStages, error) {
+	stages := &llmStages{clients: make(map[string]llm.Client), models: root.Gemini, exempt: make(map[string]bool)}
+
+	var geminiClient *gemini.Client
+	providers := make(map[string]llm.Client)
+	provider := func(name string) (llm.Client, error) {
+		if client, ok := providers[name]; ok {
+			return client, nil
+		}
+		cfg, _ := root.LLM.Provider(name) // Ссылки на провайдеров проверены при загрузке конфига
+		var client llm.Client
+		switch cfg.Type {
+		case config.ProviderGemini:
+			// Все провайдеры типа gemini делят один клиент: квота у них общая
+			if geminiClient == nil {
+				var err error
+				geminiClient, err = gemini.NewClient(root.Gemini, clk)
+				if err != nil {
+					return nil, err
+				}
+			}
+			client = geminiClient
+		case config.ProviderOpenAI:
+			var apiKey string
+			if cfg.APIKeyEnv != "" {
+				apiKey = os.Getenv(cfg.APIKeyEnv)
+				if apiKey == "" {
+					return nil, fmt.Errorf("provider %s: %s environment variable is required", name, cfg.APIKeyEnv)
+				}
+			}
+			client = llm.NewOpenAI(llm.OpenAIConfig{
+				BaseURL: cfg.BaseURL,
+				APIKey:  apiKey,
+				Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+			}, clk)
+		}
+		providers[name] = client
+		return client, nil
+	}
+
+	isGemini := func(name string) bool {
+		cfg, _ := root.LLM.Provider(name)
+		return cfg.Type == config.ProviderGemini
+	}
+	for _, name := range []string{config.StageCategorization, config.StageRanking, config.StageSummary} {
+		stage := root.LLM.Stage(name)
+		client, err := provider(stage.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: %w", name, err)
+		}
+		// Под квоту планируются только этапы, которым без Gemini не обойтись: этап
+		// с запасным провайдером другого типа при исчерпанной квоте просто переключится
+		stages.exempt[name] = !isGemini(stage.Provider) || (stage.Fallback != "" && !isGemini(stage.Fallback))
+		if stage.Fallback != "" {
+			fallback, err := provider(stage.Fallback)
+			if err != nil {
+				return nil, fmt.Errorf("stage %s fallback: %w", name, err)
+			}
+			client = llm.NewFailover(client, fallback, stage.FallbackModel)
+		}
+		stages.clients[name] = client
+		stages.setModel(name, stage.Model)
+	}
+
+	// Журнал квоты ведётся всегда, когда есть клиент Gemini, даже если им пользуется один этап
+	if geminiClient != nil {
+		stages.budget = geminiClient.Limiter()
+	}
+	return stages, nil
+}
+
+// setModel подставляет модель этапа из llm.stages вместо модели из блока gemini.
+func (s *llmStages) setModel(stage, model string) {
+	if model == "" {
+		return
+	}
+	switch stage {
+	case config.StageCategorization:
+		s.models.ModelCategorization = model
+	case config.StageRanking:
+		s.models.ModelRanking = model
+	case config.StageSummary:
+		s.models.ModelSummary = model
+	}
+}
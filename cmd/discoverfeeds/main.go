@@ -0,0 +1,82 @@
+// Команда discoverfeeds находит RSS/Atom-ленты сайта и печатает готовый блок для configs/sites.yaml
+// с категориями, предложенными по pipeline.category_keywords.
+//
+//	go run ./cmd/discoverfeeds -url https://vnexpress.net
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/sources"
+)
+
+func main() {
+	siteURL := flag.String("url", "", "адрес главной страницы сайта (обязательно)")
+	id := flag.String("id", "", "id сайта в sites.yaml (по умолчанию — из домена)")
+	name := flag.String("name", "", "название сайта (по умолчанию — <title> главной страницы)")
+	priority := flag.Int("priority", 1, "приоритет источника")
+	pipelinePath := flag.String("config", "configs/pipeline.yaml", "путь к pipeline.yaml (категории и category_keywords)")
+	verify := flag.Bool("verify", true, "загружать найденные ленты и отбрасывать те, что не разбираются")
+	flag.Parse()
+
+	if strings.TrimSpace(*siteURL) == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rootCfg, err := config.LoadRoot(*pipelinePath)
+	if err != nil {
+		log.Fatalf("load pipeline config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	discoverer := sources.NewFeedDiscoverer(&http.Client{Timeout: 15 * time.Second}, rootCfg.Pipeline, *verify)
+	feeds, title, err := discoverer.Discover(ctx, *siteURL)
+	if err != nil {
+		log.Fatalf("discover feeds: %v", err)
+	}
+	if len(feeds) == 0 {
+		log.Fatalf("no feeds found on %s", *siteURL)
+	}
+	log.Printf("Found %d feeds on %s", len(feeds), *siteURL)
+
+	site := config.Site{
+		ID:       *id,
+		Name:     *name,
+		URL:      strings.TrimSuffix(*siteURL, "/"),
+		Priority: *priority,
+	}
+	if site.ID == "" {
+		site.ID = siteIDFromURL(*siteURL)
+	}
+	if site.Name == "" {
+		site.Name = title
+	}
+
+	if err := sources.WriteSiteYAML(os.Stdout, site, feeds); err != nil {
+		log.Fatalf("write sites.yaml block: %v", err)
+	}
+}
+
+// siteIDFromURL берёт id из домена: https://www.thanhnien.vn → thanhnien.
+func siteIDFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if i := strings.Index(host, "."); i > 0 {
+		host = host[:i]
+	}
+	return host
+}
@@ -0,0 +1,47 @@
+// Команда feedhealth печатает отчёт о здоровье RSS-лент и страниц-листингов
+// по данным state/state.json: статус, число статей, последняя успешная загрузка,
+// ошибки подряд и срок карантина.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/sources"
+	"github.com/maine/vietnam_bot_news/internal/state"
+)
+
+func main() {
+	sitesPath := flag.String("sites", "configs/sites.yaml", "путь к sites.yaml")
+	statePath := flag.String("state", "state/state.json", "путь к state.json")
+	failOnUnhealthy := flag.Bool("fail-on-unhealthy", false, "завершиться с кодом 1, если есть проблемные ленты")
+	flag.Parse()
+
+	sitesCfg, err := config.LoadSites(*sitesPath)
+	if err != nil {
+		log.Fatalf("load sites config: %v", err)
+	}
+
+	st, err := state.NewFileStore(*statePath).Load(context.Background())
+	if err != nil {
+		log.Fatalf("load state: %v", err)
+	}
+
+	report := sources.HealthReport(sitesCfg.Sites, st, time.Now())
+	if err := sources.WriteHealthReport(os.Stdout, report); err != nil {
+		log.Fatalf("write report: %v", err)
+	}
+
+	if *failOnUnhealthy {
+		for _, row := range report {
+			switch row.Status {
+			case sources.FeedStatusFailing, sources.FeedStatusEmpty, sources.FeedStatusQuarantined:
+				os.Exit(1)
+			}
+		}
+	}
+}
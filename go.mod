@@ -3,6 +3,8 @@ module github.com/maine/vietnam_bot_news
 go 1.23
 
 require (
+	golang.org/x/net v0.29.0
+	golang.org/x/text v0.18.0
 	google.golang.org/genai v0.3.0
 	gopkg.in/yaml.v3 v3.0.1
 )
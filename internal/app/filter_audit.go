@@ -0,0 +1,45 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// logFilterAudit печатает сводку фильтра: причины отсева в целом и по каждому источнику.
+// Полный список решений сохраняется в отчёт (StateStore.SaveFilterAudit).
+func logFilterAudit(audit news.FilterAudit) {
+	log.Printf("Filter audit: kept %d of %d (%s)", audit.Kept, audit.Total, formatReasonCounts(audit.ByReason))
+
+	sources := make([]string, 0, len(audit.BySource))
+	for source := range audit.BySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		log.Printf("    [%s] %s", source, formatReasonCounts(audit.BySource[source]))
+	}
+}
+
+// formatReasonCounts выводит счётчики причин по убыванию: "kept=10, too_old=4".
+func formatReasonCounts(counts map[string]int) string {
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		parts = append(parts, fmt.Sprintf("%s=%d", reason, counts[reason]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,30 @@
+package app
+
+import "testing"
+
+func TestFormatReasonCounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[string]int
+		want   string
+	}{
+		{
+			name:   "empty",
+			counts: map[string]int{},
+			want:   "",
+		},
+		{
+			name:   "by count then name",
+			counts: map[string]int{"too_old": 4, "kept": 10, "duplicate": 4},
+			want:   "kept=10, duplicate=4, too_old=4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatReasonCounts(tt.counts); got != tt.want {
+				t.Errorf("formatReasonCounts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,11 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"sort"
-	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/config"
 	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
 )
 
 const maxSentHistory = 500
@@ -17,17 +17,23 @@ const maxSentHistory = 500
 // ErrNotConfigured возвращается, когда пайплайн запущен без обязательных зависимостей.
 var ErrNotConfigured = errors.New("pipeline dependencies not configured")
 
-// Clock определяет источник времени (удобно подменять в тестах).
-type Clock func() time.Time
-
 // SourceCollector агрегирует новости из подключённых источников.
+// Ошибки отдельных лент возвращаются вместе с частично собранными статьями.
+// Служебные данные источников (кэш лент) читаются из state и возвращаются обновлёнными.
 type SourceCollector interface {
-	Collect(ctx context.Context) ([]news.ArticleRaw, error)
+	Collect(ctx context.Context, state news.State) (news.State, []news.ArticleRaw, error)
 }
 
 // Filter отвечает за отсев старых, дублирующихся или неуместных новостей.
+// Для каждой входной статьи возвращается решение с причиной (см. news.FilterDecision).
 type Filter interface {
-	Apply(ctx context.Context, articles []news.ArticleRaw, state news.State) ([]news.ArticleRaw, error)
+	Apply(ctx context.Context, articles []news.ArticleRaw, state news.State) ([]news.ArticleRaw, []news.FilterDecision, error)
+}
+
+// Clusterer склеивает пересказы одной новости из разных источников,
+// оставляя по одному представителю с заполненным Coverage.
+type Clusterer interface {
+	Cluster(ctx context.Context, articles []news.ArticleRaw) ([]news.ArticleRaw, error)
 }
 
 // Categorizer распределяет новости по фиксированным категориям.
@@ -35,11 +41,37 @@ type Categorizer interface {
 	Categorize(ctx context.Context, articles []news.ArticleRaw) ([]news.CategorizedArticle, error)
 }
 
+// SafetyGuard помечает тяжёлые материалы и применяет к ним политику: удаляет статью
+// или записывает в неё SafetyAction для суммаризатора и форматтера.
+type SafetyGuard interface {
+	Review(ctx context.Context, articles []news.CategorizedArticle) ([]news.CategorizedArticle, error)
+}
+
 // Ranker сортирует и выбирает топ-N в каждой категории.
 type Ranker interface {
 	Rank(ctx context.Context, categorized []news.CategorizedArticle) ([]news.CategorizedArticle, error)
 }
 
+// OfflineRanker ранжирует без запросов к Gemini; пайплайн переходит на него,
+// когда на ранжирование не хватает дневной квоты.
+type OfflineRanker interface {
+	RankOffline(ctx context.Context, categorized []news.CategorizedArticle) ([]news.CategorizedArticle, error)
+}
+
+// BatchResizer реализуют этапы Gemini, размер батча которых пайплайн подбирает под остаток квоты.
+type BatchResizer interface {
+	BatchLimits() (size, maxSize int) // Размер батча из конфига и допустимый потолок
+	SetBatchSize(size int)
+}
+
+// GeminiBudget ведёт суточный журнал запросов к Gemini. Журнал восстанавливается из state
+// в начале запуска и сохраняется обратно вместе с ним.
+type GeminiBudget interface {
+	Restore(ledger news.QuotaLedger)
+	Ledger() news.QuotaLedger
+	Remaining() int // Сколько запросов осталось на сегодня; <0 — лимит не задан
+}
+
 // Summarizer создаёт краткие русскоязычные summary.
 type Summarizer interface {
 	Summarize(ctx context.Context, articles []news.CategorizedArticle) ([]news.DigestEntry, error)
@@ -67,20 +99,25 @@ type StateStore interface {
 	LoadDigest(ctx context.Context) (*news.Digest, error)
 	SaveDigest(ctx context.Context, digest *news.Digest) error
 	DeleteDigest(ctx context.Context) error
+	SaveFilterAudit(ctx context.Context, audit news.FilterAudit) error
 }
 
 // PipelineDeps перечисляет зависимости пайплайна.
 type PipelineDeps struct {
 	Collector       SourceCollector
 	Filter          Filter
+	Clusterer       Clusterer // Опционально: без него дубликаты отсеивает только Gemini
 	Categorizer     Categorizer
+	Safety          SafetyGuard // Опционально: без него тяжёлые материалы не помечаются
 	Ranker          Ranker
 	Summarizer      Summarizer
 	Formatter       Formatter
 	Sender          Sender
 	Recipients      RecipientResolver
 	StateStore      StateStore
-	Clock           Clock
+	Budget          GeminiBudget    // Опционально: без него запросы к Gemini не планируются под дневную квоту
+	QuotaExempt     map[string]bool // Этапы (config.Stage*), которые не планируются под квоту Gemini: другой провайдер или есть запасной
+	Clock           clock.Clock     // Опционально: по умолчанию системные часы
 	ForceDispatch   bool
 	SkipGemini      bool
 	SendTestMessage bool
@@ -93,14 +130,18 @@ type PipelineDeps struct {
 type Pipeline struct {
 	collector       SourceCollector
 	filter          Filter
+	clusterer       Clusterer
 	categorizer     Categorizer
+	safety          SafetyGuard
 	ranker          Ranker
 	summarizer      Summarizer
 	formatter       Formatter
 	sender          Sender
 	recipients      RecipientResolver
 	stateStore      StateStore
-	clock           Clock
+	budget          GeminiBudget
+	quotaExempt     map[string]bool
+	clock           clock.Clock
 	forceDispatch   bool
 	skipGemini      bool
 	sendTestMessage bool
@@ -111,22 +152,21 @@ type Pipeline struct {
 
 // NewPipeline создаёт новый экземпляр пайплайна.
 func NewPipeline(deps PipelineDeps) *Pipeline {
-	clock := deps.Clock
-	if clock == nil {
-		clock = time.Now
-	}
-
 	return &Pipeline{
 		collector:       deps.Collector,
 		filter:          deps.Filter,
+		clusterer:       deps.Clusterer,
 		categorizer:     deps.Categorizer,
+		safety:          deps.Safety,
 		ranker:          deps.Ranker,
 		summarizer:      deps.Summarizer,
 		formatter:       deps.Formatter,
 		sender:          deps.Sender,
 		recipients:      deps.Recipients,
 		stateStore:      deps.StateStore,
-		clock:           clock,
+		budget:          deps.Budget,
+		quotaExempt:     deps.QuotaExempt,
+		clock:           clock.OrSystem(deps.Clock),
 		forceDispatch:   deps.ForceDispatch,
 		skipGemini:      deps.SkipGemini,
 		sendTestMessage: deps.SendTestMessage,
@@ -137,7 +177,7 @@ func NewPipeline(deps PipelineDeps) *Pipeline {
 }
 
 // Run исполняет полный цикл обработки новостей.
-func (p *Pipeline) Run(ctx context.Context) error {
+func (p *Pipeline) Run(ctx context.Context) (err error) {
 	if err := p.validateDeps(); err != nil {
 		return err
 	}
@@ -146,6 +186,10 @@ func (p *Pipeline) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("load state: %w", err)
 	}
+	loaded := state
+	if p.budget != nil {
+		p.budget.Restore(state.GeminiQuota)
+	}
 
 	var recipients []news.RecipientBinding
 	if p.recipients != nil {
@@ -183,24 +227,10 @@ func (p *Pipeline) Run(ctx context.Context) error {
 			// Если дайджеста нет, запускаем полный пайплайн в обычном режиме
 			// Это fallback на случай, если build workflow не успел выполниться
 			// Создаем новый пайплайн без sendMode для избежания рекурсии
-			fallbackPipeline := &Pipeline{
-				collector:       p.collector,
-				filter:          p.filter,
-				categorizer:     p.categorizer,
-				ranker:          p.ranker,
-				summarizer:      p.summarizer,
-				formatter:       p.formatter,
-				sender:          p.sender,
-				recipients:      p.recipients,
-				stateStore:      p.stateStore,
-				clock:           p.clock,
-				forceDispatch:   p.forceDispatch,
-				skipGemini:      p.skipGemini,
-				sendTestMessage: p.sendTestMessage,
-				buildMode:       false,
-				sendMode:        false,
-				cfg:             p.cfg,
-			}
+			// Копия целиком, чтобы не потерять зависимости и настройки, добавленные позже
+			fallbackPipeline := *p
+			fallbackPipeline.buildMode = false
+			fallbackPipeline.sendMode = false
 			log.Println("SEND_MODE: Fallback pipeline started (this will build and send digest in one run)")
 			return fallbackPipeline.Run(ctx)
 		}
@@ -255,30 +285,57 @@ func (p *Pipeline) Run(ctx context.Context) error {
 	}
 
 	log.Println("Step 1: Collecting articles from RSS feeds...")
-	rawArticles, err := p.collector.Collect(ctx)
+	collectedState, rawArticles, err := p.collector.Collect(ctx, state)
 	if err != nil {
-		return fmt.Errorf("collect articles: %w", err)
+		// Недоступность части лент не повод отменять дайджест — падаем, только если не собрано ничего
+		if len(rawArticles) == 0 {
+			return fmt.Errorf("collect articles: %w", err)
+		}
+		log.Printf("WARNING: some sources failed, continuing with %d collected articles: %v", len(rawArticles), err)
 	}
+	// Кэш лент сохраняется только вместе с результатом запуска: если пайплайн упадёт дальше,
+	// следующий запуск должен заново получить ленты целиком, а не 304 Not Modified
+	state = collectedState
 	log.Printf("Collected %d raw articles", len(rawArticles))
 
 	log.Println("Step 2: Filtering articles...")
-	filtered, err := p.filter.Apply(ctx, rawArticles, state)
+	filtered, decisions, err := p.filter.Apply(ctx, rawArticles, state)
 	if err != nil {
 		return fmt.Errorf("filter articles: %w", err)
 	}
 	log.Printf("After filtering: %d articles", len(filtered))
+	audit := news.NewFilterAudit(decisions, p.clock.Now())
+	logFilterAudit(audit)
+	// Отчёт нужен только для настройки порогов, поэтому его ошибка не останавливает запуск
+	if err := p.stateStore.SaveFilterAudit(ctx, audit); err != nil {
+		log.Printf("WARNING: failed to save filter audit: %v", err)
+	}
+
+	// Склеиваем пересказы одного события до лимита и Gemini: лимит не тратится на дубликаты,
+	// а размер группы становится сигналом для ранкера
+	if p.clusterer != nil {
+		filtered, err = p.clusterer.Cluster(ctx, filtered)
+		if err != nil {
+			return fmt.Errorf("cluster articles: %w", err)
+		}
+		log.Printf("After clustering: %d stories", len(filtered))
+	}
 
 	// Оптимизация RPD: ограничиваем количество статей перед отправкой в Gemini
-	// Берем только самые свежие статьи, чтобы не превысить лимит RPD=20
+	// Берем самые свежие статьи с поправкой на приоритет источника, чтобы не превысить лимит RPD=20
 	// Это критично, так как даже с батчами 100, 1859 статей = ~19 запросов только на категоризацию
 	if p.cfg.MaxArticlesBeforeGemini > 0 && len(filtered) > p.cfg.MaxArticlesBeforeGemini {
-		// Сортируем по дате публикации (самые свежие первыми)
-		sort.Slice(filtered, func(i, j int) bool {
-			return filtered[i].PublishedAt.After(filtered[j].PublishedAt)
-		})
 		originalCount := len(filtered)
-		filtered = filtered[:p.cfg.MaxArticlesBeforeGemini]
-		log.Printf("Limited articles from %d to %d (taking most recent) to optimize Gemini API usage (RPD limit)", originalCount, len(filtered))
+		var quotas map[string]int
+		filtered, quotas = selectWithStrategy(filtered, p.cfg.MaxArticlesBeforeGemini, priorityBoost(p.cfg.PriorityBoostHours), p.cfg.Selection)
+		strategy := p.cfg.Selection.Strategy
+		if strategy == "" {
+			strategy = config.SelectionRecency
+		}
+		log.Printf("Limited articles from %d to %d (strategy %s, boosted by source priority) to optimize Gemini API usage (RPD limit)", originalCount, len(filtered), strategy)
+		if len(quotas) > 0 {
+			log.Printf("Selection quotas: %s", formatReasonCounts(quotas))
+		}
 	}
 
 	// Детальная статистика по отобранным статьям
@@ -294,9 +351,16 @@ func (p *Pipeline) Run(ctx context.Context) error {
 		for source, count := range sourceCount {
 			log.Printf("  - %s: %d articles", source, count)
 		}
-		// Показываем диапазон дат
-		oldest := filtered[len(filtered)-1].PublishedAt
-		newest := filtered[0].PublishedAt
+		// Показываем диапазон дат (после отбора с учётом приоритета порядок не строго по дате)
+		oldest, newest := filtered[0].PublishedAt, filtered[0].PublishedAt
+		for _, article := range filtered {
+			if article.PublishedAt.Before(oldest) {
+				oldest = article.PublishedAt
+			}
+			if article.PublishedAt.After(newest) {
+				newest = article.PublishedAt
+			}
+		}
 		log.Printf("Date range: %s (oldest) to %s (newest)", oldest.Format("2006-01-02 15:04"), newest.Format("2006-01-02 15:04"))
 
 		// Детальный список отобранных статей для отправки в Gemini
@@ -324,6 +388,35 @@ func (p *Pipeline) Run(ctx context.Context) error {
 		return nil
 	}
 
+	skipRanking := false
+	if p.budget != nil {
+		// Потраченные запросы остаются потраченными, даже если запуск упадёт дальше:
+		// журнал сохраняем в state в том виде, в каком он был загружен, без кэша лент
+		defer func() {
+			if err != nil {
+				loaded.GeminiQuota = p.budget.Ledger()
+				if saveErr := p.stateStore.Save(ctx, loaded); saveErr != nil {
+					log.Printf("WARNING: failed to save Gemini quota ledger: %v", saveErr)
+				}
+			}
+		}()
+
+		plan, err := p.planGemini(len(filtered))
+		if err != nil {
+			return err
+		}
+		if plan.Articles < len(filtered) {
+			filtered, _ = selectWithStrategy(filtered, plan.Articles, priorityBoost(p.cfg.PriorityBoostHours), p.cfg.Selection)
+		}
+		if resizer, ok := p.categorizer.(BatchResizer); ok && plan.CategorizationBatch > 0 {
+			resizer.SetBatchSize(plan.CategorizationBatch)
+		}
+		if resizer, ok := p.summarizer.(BatchResizer); ok && plan.SummaryBatch > 0 {
+			resizer.SetBatchSize(plan.SummaryBatch)
+		}
+		skipRanking = plan.SkipRanking
+	}
+
 	log.Println("Step 3: Categorizing articles with Gemini...")
 	categorized, err := p.categorizer.Categorize(ctx, filtered)
 	if err != nil {
@@ -331,16 +424,23 @@ func (p *Pipeline) Run(ctx context.Context) error {
 	}
 	log.Printf("Categorized %d articles", len(categorized))
 
-	// Задержка 1 минута между этапами для сброса TPM лимита
-	log.Println("Waiting 1 minute before ranking (TPM limit reset)...")
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(1 * time.Minute):
+	// Тяжёлые материалы отсеиваем до ранжирования, чтобы не тратить на них запросы
+	if p.safety != nil {
+		categorized, err = p.safety.Review(ctx, categorized)
+		if err != nil {
+			return fmt.Errorf("safety review: %w", err)
+		}
+		log.Printf("After safety review: %d articles", len(categorized))
 	}
 
-	log.Println("Step 4: Ranking articles with Gemini...")
-	ranked, err := p.ranker.Rank(ctx, categorized)
+	var ranked []news.CategorizedArticle
+	if offline, ok := p.ranker.(OfflineRanker); ok && skipRanking {
+		log.Println("Step 4: Ranking articles without Gemini (daily quota plan)...")
+		ranked, err = offline.RankOffline(ctx, categorized)
+	} else {
+		log.Println("Step 4: Ranking articles with Gemini...")
+		ranked, err = p.ranker.Rank(ctx, categorized)
+	}
 	if err != nil {
 		return fmt.Errorf("rank articles: %w", err)
 	}
@@ -357,13 +457,16 @@ func (p *Pipeline) Run(ctx context.Context) error {
 		if p.buildMode {
 			digest := &news.Digest{
 				Messages:   []string{serviceMessage},
-				CreatedAt:  p.clock(),
+				CreatedAt:  p.clock.Now(),
 				ArticleIDs: nil,
 			}
 			if err := p.stateStore.SaveDigest(ctx, digest); err != nil {
 				return fmt.Errorf("save digest (no-news service message): %w", err)
 			}
 			log.Println("Saved 'no news today' service digest to state/digest.json")
+			if err := p.stateStore.Save(ctx, p.withLedger(state)); err != nil {
+				return fmt.Errorf("save state: %w", err)
+			}
 			return nil
 		}
 
@@ -380,18 +483,14 @@ func (p *Pipeline) Run(ctx context.Context) error {
 			log.Println("No recipients to send 'no news today' service message (FORCE_DISPATCH enabled, but no chats)")
 		}
 
-		// В этом кейсе статьи не отправлялись, состояние по отправленным новостям не меняется
+		// В этом кейсе статьи не отправлялись, состояние по отправленным новостям не меняется,
+		// но кэш лент сохраняем
+		if err := p.stateStore.Save(ctx, p.withLedger(state)); err != nil {
+			return fmt.Errorf("save state: %w", err)
+		}
 		return nil
 	}
 
-	// Задержка 1 минута между этапами для сброса TPM лимита
-	log.Println("Waiting 1 minute before summarization (TPM limit reset)...")
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(1 * time.Minute):
-	}
-
 	log.Println("Step 5: Summarizing articles with Gemini...")
 	digestEntries, err := p.summarizer.Summarize(ctx, ranked)
 	if err != nil {
@@ -413,21 +512,28 @@ func (p *Pipeline) Run(ctx context.Context) error {
 
 	// Собираем ID статей для отслеживания отправленных
 	articleIDs := make([]string, 0, len(digestEntries))
+	sentArticles := make([]news.StateArticle, 0, len(digestEntries))
 	for _, entry := range digestEntries {
 		articleIDs = append(articleIDs, entry.ID)
+		sentArticles = append(sentArticles, sentRecord(entry))
 	}
 
 	// Режим build: сохраняем дайджест и не отправляем
 	if p.buildMode {
 		digest := &news.Digest{
 			Messages:   messages,
-			CreatedAt:  p.clock(),
+			CreatedAt:  p.clock.Now(),
 			ArticleIDs: articleIDs,
+			Articles:   sentArticles,
 		}
 		if err := p.stateStore.SaveDigest(ctx, digest); err != nil {
 			return fmt.Errorf("save digest: %w", err)
 		}
 		log.Printf("Digest saved to state/digest.json (%d messages, %d articles)", len(messages), len(articleIDs))
+		// Отправленные статьи отметит режим send, здесь сохраняем только кэш лент
+		if err := p.stateStore.Save(ctx, p.withLedger(state)); err != nil {
+			return fmt.Errorf("save state: %w", err)
+		}
 		return nil
 	}
 
@@ -444,13 +550,57 @@ func (p *Pipeline) Run(ctx context.Context) error {
 	}
 
 	newState := p.updateState(state, digestEntries)
-	if err := p.stateStore.Save(ctx, newState); err != nil {
+	if err := p.stateStore.Save(ctx, p.withLedger(newState)); err != nil {
 		return fmt.Errorf("save state: %w", err)
 	}
 
 	return nil
 }
 
+// planGemini раскладывает запросы к Gemini под остаток дневной квоты до первого запроса.
+func (p *Pipeline) planGemini(articles int) (geminiPlan, error) {
+	in := planInput{
+		Budget:      p.budget.Remaining(),
+		Articles:    articles,
+		Categories:  len(p.cfg.Categories),
+		PerCategory: p.cfg.MaxArticlesPerCategory,
+		Exempt:      p.quotaExempt,
+	}
+	if in.PerCategory <= 0 {
+		in.PerCategory = 5 // как в ранкере по умолчанию
+	}
+	if resizer, ok := p.categorizer.(BatchResizer); ok {
+		in.CatBatch, in.CatMaxBatch = resizer.BatchLimits()
+	}
+	if resizer, ok := p.summarizer.(BatchResizer); ok {
+		in.SumBatch, in.SumMaxBatch = resizer.BatchLimits()
+	}
+	// Ранжирование с запасным провайдером квоту не ограничивает: отказываться от него незачем
+	_, in.CanSkipRanking = p.ranker.(OfflineRanker)
+	in.CanSkipRanking = in.CanSkipRanking && !in.Exempt[config.StageRanking]
+
+	plan, err := planGemini(in)
+	if err != nil {
+		return geminiPlan{}, err
+	}
+	if in.Budget >= 0 {
+		log.Printf("Gemini quota plan: %d of %d remaining requests today (%d articles, categorization batch %d, summary batch %d, skip ranking: %v)",
+			plan.Requests, in.Budget, plan.Articles, plan.CategorizationBatch, plan.SummaryBatch, plan.SkipRanking)
+		if plan.Articles < articles {
+			log.Printf("WARNING: daily quota is short, limited articles for Gemini from %d to %d", articles, plan.Articles)
+		}
+	}
+	return plan, nil
+}
+
+// withLedger записывает в state журнал квоты Gemini за текущие сутки.
+func (p *Pipeline) withLedger(state news.State) news.State {
+	if p.budget != nil {
+		state.GeminiQuota = p.budget.Ledger()
+	}
+	return state
+}
+
 func (p *Pipeline) validateDeps() error {
 	// recipients опционален - он может быть nil, если auto_subscribe отключен
 	// В этом случае pipeline будет работать только в режиме force_dispatch
@@ -491,7 +641,7 @@ func (p *Pipeline) validateDeps() error {
 }
 
 func (p *Pipeline) updateStateFromDigest(prev news.State, digest *news.Digest) news.State {
-	now := p.clock()
+	now := p.clock.Now()
 	prev.LastRun = now
 
 	existing := make(map[string]struct{}, len(prev.SentArticles))
@@ -501,14 +651,19 @@ func (p *Pipeline) updateStateFromDigest(prev news.State, digest *news.Digest) n
 		filtered = append(filtered, item)
 	}
 
-	for _, articleID := range digest.ArticleIDs {
-		if _, ok := existing[articleID]; ok {
+	// Дайджесты старого формата содержат только ID
+	records := digest.Articles
+	if len(records) == 0 {
+		for _, articleID := range digest.ArticleIDs {
+			records = append(records, news.StateArticle{ID: articleID})
+		}
+	}
+	for _, record := range records {
+		if _, ok := existing[record.ID]; ok {
 			continue
 		}
-		filtered = append(filtered, news.StateArticle{
-			ID:     articleID,
-			SentAt: now,
-		})
+		record.SentAt = now
+		filtered = append(filtered, record)
 	}
 
 	prev.SentArticles = filtered
@@ -516,7 +671,7 @@ func (p *Pipeline) updateStateFromDigest(prev news.State, digest *news.Digest) n
 }
 
 func (p *Pipeline) updateState(prev news.State, entries []news.DigestEntry) news.State {
-	now := p.clock()
+	now := p.clock.Now()
 	prev.LastRun = now
 
 	existing := make(map[string]struct{}, len(prev.SentArticles))
@@ -530,10 +685,9 @@ func (p *Pipeline) updateState(prev news.State, entries []news.DigestEntry) news
 		if _, ok := existing[entry.ID]; ok {
 			continue
 		}
-		filtered = append(filtered, news.StateArticle{
-			ID:     entry.ID,
-			SentAt: now,
-		})
+		record := sentRecord(entry)
+		record.SentAt = now
+		filtered = append(filtered, record)
 	}
 
 	if len(filtered) > maxSentHistory {
@@ -543,3 +697,15 @@ func (p *Pipeline) updateState(prev news.State, entries []news.DigestEntry) news
 	prev.SentArticles = filtered
 	return prev
 }
+
+// sentRecord собирает запись об отправленной статье: кроме ID сохраняются канонические ссылки
+// (из ленты и со страницы) и отпечаток заголовка, по которым фильтр узнаёт статью при смене даты публикации.
+func sentRecord(entry news.DigestEntry) news.StateArticle {
+	return news.StateArticle{
+		ID:            entry.ID,
+		Source:        entry.Source,
+		CanonicalURL:  entry.CanonicalURL,
+		PageCanonical: entry.PageCanonical,
+		TitleHash:     textnorm.TitleFingerprint(entry.Title),
+	}
+}
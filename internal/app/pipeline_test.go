@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// Заглушки этапов: пропускают статьи дальше без внешних вызовов.
+
+type stubCollector struct{ articles []news.ArticleRaw }
+
+func (s stubCollector) Collect(ctx context.Context, state news.State) (news.State, []news.ArticleRaw, error) {
+	return state, s.articles, nil
+}
+
+type stubFilter struct{}
+
+func (stubFilter) Apply(ctx context.Context, articles []news.ArticleRaw, state news.State) ([]news.ArticleRaw, []news.FilterDecision, error) {
+	decisions := make([]news.FilterDecision, 0, len(articles))
+	for _, a := range articles {
+		decisions = append(decisions, news.FilterDecision{ArticleID: a.ID, Source: a.Source, Kept: true, Reason: news.FilterKept})
+	}
+	return articles, decisions, nil
+}
+
+type stubCategorizer struct{}
+
+func (stubCategorizer) Categorize(ctx context.Context, articles []news.ArticleRaw) ([]news.CategorizedArticle, error) {
+	result := make([]news.CategorizedArticle, 0, len(articles))
+	for _, a := range articles {
+		result = append(result, news.CategorizedArticle{Article: a, Category: "Общество", RelevanceScore: 8})
+	}
+	return result, nil
+}
+
+type stubRanker struct{}
+
+func (stubRanker) Rank(ctx context.Context, categorized []news.CategorizedArticle) ([]news.CategorizedArticle, error) {
+	return categorized, nil
+}
+
+type stubSummarizer struct{}
+
+func (stubSummarizer) Summarize(ctx context.Context, articles []news.CategorizedArticle) ([]news.DigestEntry, error) {
+	entries := make([]news.DigestEntry, 0, len(articles))
+	for _, a := range articles {
+		entries = append(entries, news.DigestEntry{ID: a.Article.ID, Category: a.Category, Title: a.Article.Title, SummaryRU: "Кратко"})
+	}
+	return entries, nil
+}
+
+type stubFormatter struct{}
+
+func (stubFormatter) BuildMessages(entries []news.DigestEntry) ([]string, error) {
+	return []string{"digest"}, nil
+}
+
+type stubSender struct{ sent [][]string }
+
+func (s *stubSender) Send(ctx context.Context, recipients []news.RecipientBinding, messages []string) error {
+	s.sent = append(s.sent, messages)
+	return nil
+}
+
+type stubRecipients struct{}
+
+func (stubRecipients) Resolve(ctx context.Context, state news.State) (news.State, []news.RecipientBinding, error) {
+	return state, state.Recipients, nil
+}
+
+type memoryStore struct {
+	state news.State
+	audit news.FilterAudit
+}
+
+func (m *memoryStore) Load(ctx context.Context) (news.State, error)              { return m.state, nil }
+func (m *memoryStore) Save(ctx context.Context, state news.State) error          { m.state = state; return nil }
+func (m *memoryStore) LoadDigest(ctx context.Context) (*news.Digest, error)      { return nil, nil }
+func (m *memoryStore) SaveDigest(ctx context.Context, digest *news.Digest) error { return nil }
+func (m *memoryStore) DeleteDigest(ctx context.Context) error                    { return nil }
+func (m *memoryStore) SaveFilterAudit(ctx context.Context, audit news.FilterAudit) error {
+	m.audit = audit
+	return nil
+}
+
+func TestPipeline_Run_FakeClock(t *testing.T) {
+	start := time.Date(2025, 1, 15, 0, 30, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	store := &memoryStore{state: news.State{
+		Recipients: []news.RecipientBinding{{Name: "user", ChatID: "1"}},
+	}}
+	sender := &stubSender{}
+
+	p := NewPipeline(PipelineDeps{
+		Collector: stubCollector{articles: []news.ArticleRaw{
+			{ID: "a1", Source: "vnexpress", Title: "Tin 1", PublishedAt: start.Add(-time.Hour)},
+		}},
+		Filter:      stubFilter{},
+		Categorizer: stubCategorizer{},
+		Ranker:      stubRanker{},
+		Summarizer:  stubSummarizer{},
+		Formatter:   stubFormatter{},
+		Sender:      sender,
+		Recipients:  stubRecipients{},
+		StateStore:  store,
+		Clock:       clk,
+	})
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Паузы для лимитов Gemini выдерживает лимитер клиента, сам пайплайн не ждёт
+	if slept := clk.Slept(); len(slept) != 0 {
+		t.Errorf("Slept() = %v, want no pauses", slept)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d batches, want 1", len(sender.sent))
+	}
+
+	wantNow := start
+	if !store.state.LastRun.Equal(wantNow) {
+		t.Errorf("LastRun = %v, want %v", store.state.LastRun, wantNow)
+	}
+	if len(store.state.SentArticles) != 1 || !store.state.SentArticles[0].SentAt.Equal(wantNow) {
+		t.Errorf("SentArticles = %+v, want a1 sent at %v", store.state.SentArticles, wantNow)
+	}
+	if !store.audit.GeneratedAt.Equal(start) || store.audit.Kept != 1 {
+		t.Errorf("filter audit = %+v, want generated at %v with 1 kept", store.audit, start)
+	}
+}
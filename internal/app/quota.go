@@ -0,0 +1,135 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+)
+
+// ErrGeminiBudget возвращается, когда остатка дневной квоты Gemini не хватает даже на урезанный дайджест.
+var ErrGeminiBudget = errors.New("gemini daily budget too small for a digest")
+
+// geminiPlan — раскладка запросов к Gemini на запуск, рассчитанная до первого запроса.
+type geminiPlan struct {
+	Articles            int  // Сколько статей отправить в категоризацию
+	CategorizationBatch int  // Размер батча категоризации
+	SummaryBatch        int  // Размер батча суммаризации
+	SkipRanking         bool // Ранжировать без Gemini (OfflineRanker)
+	Requests            int  // Сколько запросов потратит запуск в худшем случае
+}
+
+// planInput описывает запуск для планировщика. Размер батча 0 — этап не поддерживает
+// BatchResizer и считается одним запросом. Этапы из Exempt квоту не расходуют и в план
+// не входят: они идут через другого провайдера или переключаются на запасного.
+type planInput struct {
+	Budget         int // Остаток дневной квоты; <0 — без ограничения
+	Articles       int
+	Categories     int // Ранжирование тратит по запросу на каждую непустую категорию
+	PerCategory    int // Сколько статей категории доходит до суммаризации
+	CatBatch       int
+	CatMaxBatch    int
+	SumBatch       int
+	SumMaxBatch    int
+	CanSkipRanking bool
+	Exempt         map[string]bool // Этап (config.Stage*) -> не планируется под квоту
+}
+
+// planGemini подбирает план под остаток квоты, деградируя по шагам:
+//  1. батчи из конфига;
+//  2. укрупнённые батчи (не больше max_batch_size_*);
+//  3. меньше статей, но не меньше, чем помещается в дайджест, — ранжирование сохраняется;
+//  4. ранжирование без Gemini и столько статей, сколько позволяет квота.
+//
+// Если не помещается даже одна статья, возвращает ErrGeminiBudget.
+func planGemini(in planInput) (geminiPlan, error) {
+	if in.Articles == 0 || in.Budget < 0 {
+		return in.plan(in.Articles, in.CatBatch, in.SumBatch, false), nil
+	}
+
+	// fit оставляет батчи из конфига, если они укладываются в квоту (шаги 1 и 2)
+	if plan, ok := in.fit(in.Articles, false); ok {
+		return plan, nil
+	}
+	// Статей меньше, чем места в дайджесте, брать не стоит: ранжировать почти нечего
+	floor := min(in.Articles, max(1, in.Categories*in.PerCategory))
+	for articles := in.Articles - 1; articles >= floor; articles-- {
+		if plan, ok := in.fit(articles, false); ok {
+			return plan, nil
+		}
+	}
+	if in.CanSkipRanking {
+		for articles := in.Articles; articles > 0; articles-- {
+			if plan, ok := in.fit(articles, true); ok {
+				return plan, nil
+			}
+		}
+	}
+
+	minimal := in.plan(1, in.CatBatch, in.SumBatch, in.CanSkipRanking)
+	return geminiPlan{}, fmt.Errorf("%w: %d requests left today, need at least %d", ErrGeminiBudget, in.Budget, minimal.Requests)
+}
+
+// fit ищет наименьшие батчи, при которых articles статей укладываются в квоту.
+// Сначала укрупняется категоризация (на неё уходит больше всего запросов), затем суммаризация.
+func (in planInput) fit(articles int, skipRanking bool) (geminiPlan, bool) {
+	for _, sumBatch := range []int{in.SumBatch, in.SumMaxBatch} {
+		catBatch := in.CatBatch
+		if !in.Exempt[config.StageCategorization] {
+			plan := in.plan(articles, catBatch, sumBatch, skipRanking)
+			catRequests := in.Budget - (plan.Requests - batches(articles, catBatch))
+			if catRequests <= 0 {
+				continue
+			}
+			if catBatch > 0 {
+				catBatch = max(catBatch, batches(articles, catRequests))
+				if catBatch > in.CatMaxBatch {
+					continue
+				}
+			}
+		}
+		plan := in.plan(articles, catBatch, sumBatch, skipRanking)
+		if plan.Requests <= in.Budget {
+			return plan, true
+		}
+	}
+	return geminiPlan{}, false
+}
+
+// plan считает запросы для заданных размеров батчей.
+func (in planInput) plan(articles, catBatch, sumBatch int, skipRanking bool) geminiPlan {
+	plan := geminiPlan{
+		Articles:            articles,
+		CategorizationBatch: catBatch,
+		SummaryBatch:        sumBatch,
+		SkipRanking:         skipRanking,
+	}
+	if articles == 0 {
+		return plan
+	}
+	if !in.Exempt[config.StageCategorization] {
+		plan.Requests += batches(articles, catBatch)
+	}
+	if !skipRanking && !in.Exempt[config.StageRanking] {
+		plan.Requests += min(max(1, in.Categories), articles)
+	}
+	if !in.Exempt[config.StageSummary] {
+		digest := articles
+		if in.Categories > 0 && in.PerCategory > 0 {
+			digest = min(articles, in.Categories*in.PerCategory)
+		}
+		plan.Requests += batches(digest, sumBatch)
+	}
+	return plan
+}
+
+// batches возвращает число запросов для items элементов батчами по size; size 0 — один запрос.
+func batches(items, size int) int {
+	if items == 0 {
+		return 0
+	}
+	if size <= 0 {
+		return 1
+	}
+	return (items + size - 1) / size
+}
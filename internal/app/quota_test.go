@@ -0,0 +1,241 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+func TestPlanGemini(t *testing.T) {
+	// 10 категорий по 5 статей: дайджест до 50 статей
+	base := planInput{
+		Articles:       500,
+		Categories:     10,
+		PerCategory:    5,
+		CatBatch:       100,
+		CatMaxBatch:    250,
+		SumBatch:       30,
+		SumMaxBatch:    50,
+		CanSkipRanking: true,
+	}
+
+	tests := []struct {
+		name    string
+		budget  int
+		noSkip  bool
+		exempt  map[string]bool
+		want    geminiPlan
+		wantErr error
+	}{
+		{
+			name:   "unlimited keeps config",
+			budget: -1,
+			want:   geminiPlan{Articles: 500, CategorizationBatch: 100, SummaryBatch: 30, Requests: 17},
+		},
+		{
+			name:   "config fits",
+			budget: 20,
+			want:   geminiPlan{Articles: 500, CategorizationBatch: 100, SummaryBatch: 30, Requests: 17},
+		},
+		{
+			name:   "categorization batch grows",
+			budget: 15,
+			want:   geminiPlan{Articles: 500, CategorizationBatch: 167, SummaryBatch: 30, Requests: 15},
+		},
+		{
+			name:   "summary batch grows too",
+			budget: 13,
+			want:   geminiPlan{Articles: 500, CategorizationBatch: 250, SummaryBatch: 50, Requests: 13},
+		},
+		{
+			name:   "fewer articles keep ranking",
+			budget: 12,
+			want:   geminiPlan{Articles: 250, CategorizationBatch: 250, SummaryBatch: 50, Requests: 12},
+		},
+		{
+			name:   "ranking skipped when input would drop below digest size",
+			budget: 3,
+			want:   geminiPlan{Articles: 500, CategorizationBatch: 250, SummaryBatch: 50, SkipRanking: true, Requests: 3},
+		},
+		{
+			name:    "without offline ranker input shrinks to digest size only",
+			budget:  3,
+			noSkip:  true,
+			wantErr: ErrGeminiBudget,
+		},
+		{
+			name:    "budget exhausted",
+			budget:  1,
+			wantErr: ErrGeminiBudget,
+		},
+		{
+			name:   "summary with fallback is not planned",
+			budget: 13,
+			exempt: map[string]bool{config.StageSummary: true},
+			want:   geminiPlan{Articles: 500, CategorizationBatch: 167, SummaryBatch: 30, Requests: 13},
+		},
+		{
+			name:   "only summary on Gemini",
+			budget: 1,
+			exempt: map[string]bool{config.StageCategorization: true, config.StageRanking: true},
+			want:   geminiPlan{Articles: 500, CategorizationBatch: 100, SummaryBatch: 50, Requests: 1},
+		},
+		{
+			name:   "every stage has a fallback",
+			budget: 0,
+			exempt: map[string]bool{config.StageCategorization: true, config.StageRanking: true, config.StageSummary: true},
+			want:   geminiPlan{Articles: 500, CategorizationBatch: 100, SummaryBatch: 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := base
+			in.Budget = tt.budget
+			in.CanSkipRanking = !tt.noSkip
+			in.Exempt = tt.exempt
+			got, err := planGemini(in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("planGemini() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("planGemini() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// stubBudget — журнал квоты в памяти: каждый вызов этапа «тратит» запрос.
+type stubBudget struct {
+	ledger news.QuotaLedger
+	rpd    int
+}
+
+func (b *stubBudget) Restore(ledger news.QuotaLedger) { b.ledger = ledger }
+func (b *stubBudget) Ledger() news.QuotaLedger        { return b.ledger }
+func (b *stubBudget) Remaining() int                  { return max(0, b.rpd-len(b.ledger.Requests)) }
+
+func (b *stubBudget) spend(stage string) {
+	b.ledger.Requests = append(b.ledger.Requests, news.QuotaRequest{Stage: stage})
+}
+
+type spendingCategorizer struct {
+	budget *stubBudget
+	err    error
+}
+
+func (c spendingCategorizer) Categorize(ctx context.Context, articles []news.ArticleRaw) ([]news.CategorizedArticle, error) {
+	c.budget.spend("categorization")
+	if c.err != nil {
+		return nil, c.err
+	}
+	return stubCategorizer{}.Categorize(ctx, articles)
+}
+
+type offlineRanker struct{ offline *bool }
+
+func (r offlineRanker) Rank(ctx context.Context, categorized []news.CategorizedArticle) ([]news.CategorizedArticle, error) {
+	return categorized, nil
+}
+
+func (r offlineRanker) RankOffline(ctx context.Context, categorized []news.CategorizedArticle) ([]news.CategorizedArticle, error) {
+	*r.offline = true
+	return categorized, nil
+}
+
+var errBoom = errors.New("boom")
+
+func TestPipeline_Run_QuotaLedger(t *testing.T) {
+	start := time.Date(2025, 1, 15, 0, 30, 0, 0, time.UTC)
+	articles := []news.ArticleRaw{
+		{ID: "a1", Source: "vnexpress", Title: "Tin 1", PublishedAt: start.Add(-time.Hour)},
+		{ID: "a2", Source: "tuoitre", Title: "Tin 2", PublishedAt: start.Add(-2 * time.Hour)},
+	}
+	previous := news.QuotaLedger{Day: "2025-01-14", Requests: []news.QuotaRequest{{Stage: "categorization"}, {Stage: "summary"}}}
+
+	tests := []struct {
+		name          string
+		rpd           int
+		categorizeErr error
+		wantErr       error
+		wantRequests  int
+		wantOffline   bool
+		exempt        map[string]bool
+		sendMode      bool // Без сохранённого дайджеста send-режим запускает полный пайплайн
+		wantFeeds     bool // Кэш лент сохраняется только при успешном запуске
+	}{
+		{name: "ranking with Gemini", rpd: 20, wantRequests: 3, wantFeeds: true},
+		{name: "short budget ranks offline", rpd: 4, wantRequests: 3, wantOffline: true, wantFeeds: true},
+		{name: "no budget fails before first call", rpd: 2, wantErr: ErrGeminiBudget, wantRequests: 2},
+		{name: "failed run still saves ledger", rpd: 20, categorizeErr: errBoom, wantErr: errBoom, wantRequests: 3},
+		{
+			name:         "stages with fallback run without remaining budget",
+			rpd:          2,
+			exempt:       map[string]bool{config.StageCategorization: true, config.StageRanking: true, config.StageSummary: true},
+			wantRequests: 3,
+			wantFeeds:    true,
+		},
+		{
+			name:         "send mode fallback keeps stages with fallback out of the plan",
+			rpd:          2,
+			exempt:       map[string]bool{config.StageCategorization: true, config.StageRanking: true, config.StageSummary: true},
+			sendMode:     true,
+			wantRequests: 3,
+			wantFeeds:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStore{state: news.State{
+				Recipients:  []news.RecipientBinding{{Name: "user", ChatID: "1"}},
+				GeminiQuota: previous,
+			}}
+			budget := &stubBudget{rpd: tt.rpd}
+			offline := false
+			p := NewPipeline(PipelineDeps{
+				Collector:   feedCollector{stubCollector{articles: articles}},
+				Filter:      stubFilter{},
+				Categorizer: spendingCategorizer{budget: budget, err: tt.categorizeErr},
+				Ranker:      offlineRanker{offline: &offline},
+				Summarizer:  stubSummarizer{},
+				Formatter:   stubFormatter{},
+				Sender:      &stubSender{},
+				Recipients:  stubRecipients{},
+				StateStore:  store,
+				Budget:      budget,
+				QuotaExempt: tt.exempt,
+				SendMode:    tt.sendMode,
+				Clock:       clock.NewFake(start),
+				Config:      config.Pipeline{Categories: []string{"Общество"}, MaxArticlesPerCategory: 5},
+			})
+
+			err := p.Run(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := len(store.state.GeminiQuota.Requests); got != tt.wantRequests {
+				t.Errorf("saved ledger has %d requests, want %d", got, tt.wantRequests)
+			}
+			if offline != tt.wantOffline {
+				t.Errorf("offline ranking = %v, want %v", offline, tt.wantOffline)
+			}
+			if gotFeeds := len(store.state.Feeds) > 0; gotFeeds != tt.wantFeeds {
+				t.Errorf("feeds saved = %v, want %v", gotFeeds, tt.wantFeeds)
+			}
+		})
+	}
+}
+
+// feedCollector отмечает ленту в state, чтобы проверить, что при падении кэш лент не сохраняется.
+type feedCollector struct{ stubCollector }
+
+func (c feedCollector) Collect(ctx context.Context, state news.State) (news.State, []news.ArticleRaw, error) {
+	state.Feeds = map[string]news.FeedState{"https://vnexpress.net/rss": {SiteID: "vnexpress"}}
+	return state, c.articles, nil
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"sort"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// defaultPriorityBoost — сколько «часов свежести» даёт единица приоритета источника,
+// если priority_boost_hours не задан.
+const defaultPriorityBoost = 6 * time.Hour
+
+// selectForGemini оставляет не больше limit статей перед отправкой в Gemini.
+// Статьи упорядочиваются по «эффективному времени»: дата публикации плюс boost за каждую
+// единицу приоритета источника. Так надёжный источник не вытесняется шумным, публикующим чаще,
+// но и старые статьи приоритетного источника не занимают весь лимит.
+// Возвращает статьи в порядке отбора (лучшие первыми); при limit <= 0 только сортирует.
+func selectForGemini(articles []news.ArticleRaw, limit int, boost time.Duration) []news.ArticleRaw {
+	if boost < 0 {
+		boost = 0
+	}
+	effective := func(a news.ArticleRaw) time.Time {
+		return a.PublishedAt.Add(time.Duration(a.Priority) * boost)
+	}
+
+	selected := make([]news.ArticleRaw, len(articles))
+	copy(selected, articles)
+	sort.SliceStable(selected, func(i, j int) bool {
+		ei, ej := effective(selected[i]), effective(selected[j])
+		if !ei.Equal(ej) {
+			return ei.After(ej)
+		}
+		// При равенстве выигрывает более приоритетный источник, затем — более свежая статья
+		if selected[i].Priority != selected[j].Priority {
+			return selected[i].Priority > selected[j].Priority
+		}
+		return selected[i].PublishedAt.After(selected[j].PublishedAt)
+	})
+
+	if limit > 0 && len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
+
+// priorityBoost возвращает boost из конфига: 0 — значение по умолчанию, отрицательное — отключить.
+func priorityBoost(hours int) time.Duration {
+	switch {
+	case hours > 0:
+		return time.Duration(hours) * time.Hour
+	case hours < 0:
+		return 0
+	default:
+		return defaultPriorityBoost
+	}
+}
+
+// bucket — группа статей одного источника (или источника и категории ленты) для отбора с квотами.
+type bucket struct {
+	key      string
+	articles []news.ArticleRaw // В порядке отбора selectForGemini
+}
+
+// selectWithStrategy оставляет не больше limit статей по стратегии из pipeline.selection.
+// recency — общий список selectForGemini; proportional и round_robin делят лимит между корзинами,
+// чтобы источник, публикующий десятки заметок в час, не вытеснял остальных.
+// Возвращает отобранные статьи в общем порядке selectForGemini и квоты корзин (для логов).
+func selectWithStrategy(articles []news.ArticleRaw, limit int, boost time.Duration, sel config.Selection) ([]news.ArticleRaw, map[string]int) {
+	ordered := selectForGemini(articles, 0, boost)
+	if limit <= 0 || len(ordered) <= limit {
+		return ordered, nil
+	}
+
+	buckets := splitIntoBuckets(ordered, sel.BucketBy)
+	var quotas []int
+	switch sel.Strategy {
+	case config.SelectionProportional:
+		quotas = proportionalQuotas(buckets, limit, sel.MinPerBucket)
+	case config.SelectionRoundRobin:
+		quotas = roundRobinQuotas(buckets, limit)
+	default:
+		return ordered[:limit], nil
+	}
+
+	chosen := make(map[string]int, len(buckets)) // Ключ корзины -> квота
+	taken := make(map[string]int, len(buckets))
+	for i, b := range buckets {
+		chosen[b.key] = quotas[i]
+	}
+	selected := make([]news.ArticleRaw, 0, limit)
+	for _, article := range ordered {
+		key := bucketKey(article, sel.BucketBy)
+		if taken[key] < chosen[key] {
+			taken[key]++
+			selected = append(selected, article)
+		}
+	}
+	return selected, chosen
+}
+
+// splitIntoBuckets раскладывает упорядоченные статьи по корзинам. Корзины идут в порядке
+// появления своей лучшей статьи, поэтому раздача остатков детерминирована.
+func splitIntoBuckets(ordered []news.ArticleRaw, bucketBy string) []bucket {
+	var buckets []bucket
+	index := make(map[string]int)
+	for _, article := range ordered {
+		key := bucketKey(article, bucketBy)
+		i, ok := index[key]
+		if !ok {
+			i = len(buckets)
+			index[key] = i
+			buckets = append(buckets, bucket{key: key})
+		}
+		buckets[i].articles = append(buckets[i].articles, article)
+	}
+	return buckets
+}
+
+// bucketKey возвращает ключ корзины статьи для bucket_by.
+func bucketKey(article news.ArticleRaw, bucketBy string) string {
+	if bucketBy == config.BucketBySourceCategory {
+		return article.Source + "/" + article.Metadata["rss_category"]
+	}
+	return article.Source
+}
+
+// proportionalQuotas даёт каждой корзине min(minPerBucket, размер), а остаток лимита делит
+// пропорционально размерам корзин, у которых ещё есть статьи (метод наибольших остатков). Если минимумы не помещаются
+// в лимит, лимит делится по очереди (round_robin).
+func proportionalQuotas(buckets []bucket, limit, minPerBucket int) []int {
+	quotas := make([]int, len(buckets))
+	used := 0
+	for i, b := range buckets {
+		quotas[i] = min(minPerBucket, len(b.articles))
+		used += quotas[i]
+	}
+	if used > limit {
+		return roundRobinQuotas(buckets, limit)
+	}
+
+	for remaining := limit - used; remaining > 0; {
+		weight := 0
+		for i, b := range buckets {
+			if quotas[i] < len(b.articles) {
+				weight += len(b.articles)
+			}
+		}
+		if weight == 0 {
+			break
+		}
+
+		given := 0
+		fractions := make([]int, 0, len(buckets)) // Индексы корзин со свободными статьями
+		for i, b := range buckets {
+			if free := len(b.articles) - quotas[i]; free > 0 {
+				share := min(remaining*len(b.articles)/weight, free)
+				quotas[i] += share
+				given += share
+				fractions = append(fractions, i)
+			}
+		}
+		// Нераспределённый остаток — по одной статье корзинам с наибольшей дробной частью доли
+		sort.SliceStable(fractions, func(a, b int) bool {
+			ra := remaining * len(buckets[fractions[a]].articles) % weight
+			rb := remaining * len(buckets[fractions[b]].articles) % weight
+			return ra > rb
+		})
+		for _, i := range fractions {
+			if given == remaining {
+				break
+			}
+			if quotas[i] < len(buckets[i].articles) {
+				quotas[i]++
+				given++
+			}
+		}
+		remaining -= given
+	}
+	return quotas
+}
+
+// roundRobinQuotas раздаёт лимит по одной статье каждой корзине по кругу.
+func roundRobinQuotas(buckets []bucket, limit int) []int {
+	quotas := make([]int, len(buckets))
+	for given := 0; given < limit; {
+		progressed := false
+		for i, b := range buckets {
+			if given == limit {
+				break
+			}
+			if quotas[i] < len(b.articles) {
+				quotas[i]++
+				given++
+				progressed = true
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+	return quotas
+}
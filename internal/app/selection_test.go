@@ -0,0 +1,189 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+func TestSelectForGemini(t *testing.T) {
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	article := func(id string, ageHours, priority int) news.ArticleRaw {
+		return news.ArticleRaw{ID: id, PublishedAt: now.Add(-time.Duration(ageHours) * time.Hour), Priority: priority}
+	}
+
+	tests := []struct {
+		name     string
+		articles []news.ArticleRaw
+		limit    int
+		boost    time.Duration
+		wantIDs  []string
+	}{
+		{
+			name:     "no priority - pure recency",
+			articles: []news.ArticleRaw{article("old", 5, 0), article("new", 1, 0), article("mid", 3, 0)},
+			limit:    2,
+			boost:    6 * time.Hour,
+			wantIDs:  []string{"new", "mid"},
+		},
+		{
+			name:     "priority outweighs a few hours of freshness",
+			articles: []news.ArticleRaw{article("noisy-1", 1, 0), article("noisy-2", 2, 0), article("trusted", 4, 1)},
+			limit:    2,
+			boost:    6 * time.Hour,
+			wantIDs:  []string{"trusted", "noisy-1"},
+		},
+		{
+			name:     "old articles of trusted source do not win",
+			articles: []news.ArticleRaw{article("trusted-old", 20, 1), article("noisy", 1, 0)},
+			limit:    1,
+			boost:    6 * time.Hour,
+			wantIDs:  []string{"noisy"},
+		},
+		{
+			name:     "priority breaks ties",
+			articles: []news.ArticleRaw{article("low", 2, 0), article("high", 2, 2)},
+			limit:    1,
+			boost:    0,
+			wantIDs:  []string{"high"},
+		},
+		{
+			name:     "no limit keeps everything",
+			articles: []news.ArticleRaw{article("a", 1, 0), article("b", 2, 0)},
+			limit:    0,
+			boost:    6 * time.Hour,
+			wantIDs:  []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectForGemini(tt.articles, tt.limit, tt.boost)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("selectForGemini() len = %d, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if got[i].ID != id {
+					t.Errorf("selectForGemini()[%d] = %q, want %q", i, got[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectWithStrategy(t *testing.T) {
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	article := func(id, source, rssCategory string, ageHours int) news.ArticleRaw {
+		return news.ArticleRaw{
+			ID:          id,
+			Source:      source,
+			PublishedAt: now.Add(-time.Duration(ageHours) * time.Hour),
+			Metadata:    map[string]string{"rss_category": rssCategory},
+		}
+	}
+	// Шумный источник публикует свежее всех и без квот занял бы весь лимит
+	articles := []news.ArticleRaw{
+		article("noisy-1", "noisy", "", 1),
+		article("noisy-2", "noisy", "", 2),
+		article("noisy-3", "noisy", "", 3),
+		article("noisy-4", "noisy", "", 4),
+		article("noisy-5", "noisy", "", 5),
+		article("noisy-6", "noisy", "", 6),
+		article("calm-1", "calm", "", 7),
+		article("calm-2", "calm", "", 8),
+		article("rare-1", "rare", "", 9),
+	}
+
+	tests := []struct {
+		name       string
+		articles   []news.ArticleRaw
+		limit      int
+		sel        config.Selection
+		wantIDs    []string
+		wantQuotas map[string]int
+	}{
+		{
+			name:     "recency by default",
+			articles: articles,
+			limit:    3,
+			sel:      config.Selection{},
+			wantIDs:  []string{"noisy-1", "noisy-2", "noisy-3"},
+		},
+		{
+			name:       "round robin gives every source a turn",
+			articles:   articles,
+			limit:      5,
+			sel:        config.Selection{Strategy: config.SelectionRoundRobin},
+			wantIDs:    []string{"noisy-1", "noisy-2", "calm-1", "calm-2", "rare-1"},
+			wantQuotas: map[string]int{"noisy": 2, "calm": 2, "rare": 1},
+		},
+		{
+			name:       "proportional with minimum per source",
+			articles:   articles,
+			limit:      6,
+			sel:        config.Selection{Strategy: config.SelectionProportional, MinPerBucket: 1},
+			wantIDs:    []string{"noisy-1", "noisy-2", "noisy-3", "calm-1", "calm-2", "rare-1"},
+			wantQuotas: map[string]int{"noisy": 3, "calm": 2, "rare": 1},
+		},
+		{
+			name:       "proportional without minimum follows source size",
+			articles:   articles,
+			limit:      3,
+			sel:        config.Selection{Strategy: config.SelectionProportional},
+			wantIDs:    []string{"noisy-1", "noisy-2", "calm-1"},
+			wantQuotas: map[string]int{"noisy": 2, "calm": 1, "rare": 0},
+		},
+		{
+			name:       "minimums above limit fall back to round robin",
+			articles:   articles,
+			limit:      2,
+			sel:        config.Selection{Strategy: config.SelectionProportional, MinPerBucket: 2},
+			wantIDs:    []string{"noisy-1", "calm-1"},
+			wantQuotas: map[string]int{"noisy": 1, "calm": 1, "rare": 0},
+		},
+		{
+			name: "buckets by source and rss category",
+			articles: []news.ArticleRaw{
+				article("biz-1", "vnexpress", "business", 1),
+				article("biz-2", "vnexpress", "business", 2),
+				article("biz-3", "vnexpress", "business", 3),
+				article("law-1", "vnexpress", "law", 4),
+			},
+			limit:      2,
+			sel:        config.Selection{Strategy: config.SelectionRoundRobin, BucketBy: config.BucketBySourceCategory},
+			wantIDs:    []string{"biz-1", "law-1"},
+			wantQuotas: map[string]int{"vnexpress/business": 1, "vnexpress/law": 1},
+		},
+		{
+			name:     "under limit keeps everything",
+			articles: articles[6:],
+			limit:    5,
+			sel:      config.Selection{Strategy: config.SelectionRoundRobin},
+			wantIDs:  []string{"calm-1", "calm-2", "rare-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, quotas := selectWithStrategy(tt.articles, tt.limit, 6*time.Hour, tt.sel)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("selectWithStrategy() returned %d articles, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if got[i].ID != id {
+					t.Errorf("selectWithStrategy()[%d] = %s, want %s", i, got[i].ID, id)
+				}
+			}
+			if len(quotas) != len(tt.wantQuotas) {
+				t.Errorf("quotas = %v, want %v", quotas, tt.wantQuotas)
+			}
+			for key, want := range tt.wantQuotas {
+				if quotas[key] != want {
+					t.Errorf("quotas[%s] = %d, want %d", key, quotas[key], want)
+				}
+			}
+		})
+	}
+}
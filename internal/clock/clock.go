@@ -0,0 +1,119 @@
+// Package clock даёт пайплайну общий источник времени и ожиданий: в проде это системные часы,
+// при воспроизведении прошлого дня — часы со сдвигом, в тестах — ручные часы без реальных пауз.
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock — источник текущего времени и пауз между запросами.
+type Clock interface {
+	Now() time.Time
+	// Sleep ждёт d или отмены контекста (тогда возвращает ctx.Err()).
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// System возвращает системные часы.
+func System() Clock {
+	return systemClock{}
+}
+
+// OrSystem возвращает c или системные часы, если c не задан. Для конструкторов с необязательными часами.
+func OrSystem(c Clock) Clock {
+	if c == nil {
+		return System()
+	}
+	return c
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, d)
+}
+
+// StartingAt возвращает часы, которые начинают отсчёт со start и идут в реальном темпе.
+// Паузы настоящие: при воспроизведении прошлого дня внешние API ограничивают запросы как обычно.
+func StartingAt(start time.Time) Clock {
+	return shiftedClock{offset: time.Until(start)}
+}
+
+type shiftedClock struct {
+	offset time.Duration
+}
+
+func (c shiftedClock) Now() time.Time {
+	return time.Now().Add(c.offset)
+}
+
+func (shiftedClock) Sleep(ctx context.Context, d time.Duration) error {
+	return sleep(ctx, d)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Fake — ручные часы для тестов: время стоит, пока его не сдвинут, а Sleep не ждёт,
+// а сразу переводит часы вперёд и запоминает паузу.
+type Fake struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept []time.Duration
+}
+
+// NewFake создаёт ручные часы, показывающие now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now реализует Clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Sleep реализует Clock.
+func (f *Fake) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d > 0 {
+		f.now = f.now.Add(d)
+	}
+	f.slept = append(f.slept, d)
+	return nil
+}
+
+// Advance переводит часы вперёд на d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Slept возвращает паузы, запрошенные через Sleep, в порядке вызовов.
+func (f *Fake) Slept() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Duration(nil), f.slept...)
+}
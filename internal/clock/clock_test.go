@@ -0,0 +1,46 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	if err := f.Sleep(context.Background(), time.Minute); err != nil {
+		t.Fatalf("Sleep() error = %v", err)
+	}
+	f.Advance(30 * time.Second)
+	if got, want := f.Now(), start.Add(90*time.Second); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := f.Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep(cancelled) error = %v, want context.Canceled", err)
+	}
+	if slept := f.Slept(); len(slept) != 1 || slept[0] != time.Minute {
+		t.Errorf("Slept() = %v, want [1m]", slept)
+	}
+}
+
+func TestStartingAt(t *testing.T) {
+	start := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	c := StartingAt(start)
+	if got := c.Now().Sub(start); got < 0 || got > time.Minute {
+		t.Errorf("Now() - start = %v, want just after start", got)
+	}
+}
+
+func TestSystemSleep_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := System().Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep(cancelled) error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,291 @@
+// Package cluster склеивает пересказы одной новости из разных источников до отправки в Gemini.
+package cluster
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
+)
+
+const (
+	// defaultTextSimilarity — порог оценки Jaccard по шинглам текста, если cluster_similarity не задан.
+	defaultTextSimilarity = 0.45
+	// titleSimilarity — порог Jaccard по словам заголовка: разные издания редко пишут
+	// настолько похожие заголовки о разных событиях.
+	titleSimilarity = 0.6
+	// minTitleWords — короче этого заголовки не сравниваются («Tin tức 24h»).
+	minTitleWords = 4
+	// shingleSize — длина шингла в словах. Вьетнамское слово часто состоит из двух слогов,
+	// поэтому три слога ≈ одно-два слова.
+	shingleSize = 3
+	// maxShingledWords ограничивает объём текста: начало статьи пересказывает суть события.
+	maxShingledWords = 300
+	// signatureSize — число хэш-функций MinHash; ошибка оценки сходства ~1/sqrt(64) ≈ 0.12.
+	signatureSize = 64
+)
+
+// Clusterer реализует app.Clusterer: группирует почти одинаковые статьи, оставляет от каждой
+// группы одного представителя и записывает в него число источников группы (news.ArticleRaw.Coverage).
+type Clusterer struct {
+	textSimilarity float64
+	seeds          [signatureSize]uint64
+}
+
+// New создаёт экземпляр. Отрицательный cluster_similarity отключает склейку.
+func New(cfg config.Pipeline) *Clusterer {
+	threshold := cfg.ClusterSimilarity
+	if threshold == 0 {
+		threshold = defaultTextSimilarity
+	}
+	c := &Clusterer{textSimilarity: threshold}
+	// Фиксированные seed: одинаковый вход всегда даёт одинаковые кластеры
+	seed := uint64(0x9e3779b97f4a7c15)
+	for i := range c.seeds {
+		seed = splitmix64(seed)
+		c.seeds[i] = seed
+	}
+	return c
+}
+
+// fingerprint — признаки статьи для сравнения.
+type fingerprint struct {
+	title     map[string]struct{}
+	signature []uint64 // nil, если текст короче одного шингла
+}
+
+// Cluster реализует app.Clusterer.
+// Порядок статей сохраняется: представитель группы стоит на месте первой статьи группы.
+func (c *Clusterer) Cluster(ctx context.Context, articles []news.ArticleRaw) ([]news.ArticleRaw, error) {
+	if c.textSimilarity < 0 || len(articles) < 2 {
+		return articles, nil
+	}
+
+	prints := make([]fingerprint, len(articles))
+	for i, article := range articles {
+		prints[i] = c.fingerprint(article)
+	}
+
+	// Попарное сравнение: после фильтра за сутки остаётся до пары тысяч статей,
+	// а сравнение подписей MinHash фиксированной длины дешёвое
+	groups := newUnionFind(len(articles))
+	for i := range articles {
+		if i%200 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		for j := i + 1; j < len(articles); j++ {
+			if c.similar(prints[i], prints[j]) {
+				groups.union(i, j)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for i := range articles {
+		root := groups.find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	result := make([]news.ArticleRaw, 0, len(roots))
+	merged := 0
+	for _, root := range roots {
+		group := members[root]
+		best := representative(articles, group)
+		result = append(result, withCoverage(articles, group, best))
+		if len(group) > 1 {
+			merged += len(group) - 1
+			logCluster(articles, group, best)
+		}
+	}
+	log.Printf("Clustering: %d articles -> %d stories (%d near-duplicates merged)", len(articles), len(result), merged)
+
+	return result, nil
+}
+
+func (c *Clusterer) fingerprint(article news.ArticleRaw) fingerprint {
+	titleWords := words(article.Title)
+	fp := fingerprint{}
+	if len(titleWords) >= minTitleWords {
+		fp.title = make(map[string]struct{}, len(titleWords))
+		for _, w := range titleWords {
+			fp.title[w] = struct{}{}
+		}
+	}
+
+	text := append(titleWords, words(article.RawContent)...)
+	if len(text) > maxShingledWords {
+		text = text[:maxShingledWords]
+	}
+	if len(text) < shingleSize {
+		return fp
+	}
+
+	fp.signature = make([]uint64, signatureSize)
+	for i := range fp.signature {
+		fp.signature[i] = ^uint64(0)
+	}
+	for i := 0; i+shingleSize <= len(text); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(text[i:i+shingleSize], " ")))
+		shingle := h.Sum64()
+		for k, seed := range c.seeds {
+			if v := splitmix64(shingle ^ seed); v < fp.signature[k] {
+				fp.signature[k] = v
+			}
+		}
+	}
+	return fp
+}
+
+// similar сообщает, что статьи пересказывают одно событие: совпадает большая часть
+// шинглов текста или почти все слова заголовка.
+func (c *Clusterer) similar(a, b fingerprint) bool {
+	if a.signature != nil && b.signature != nil && estimateJaccard(a.signature, b.signature) >= c.textSimilarity {
+		return true
+	}
+	return a.title != nil && b.title != nil && jaccard(a.title, b.title) >= titleSimilarity
+}
+
+// representative выбирает статью, которая пойдёт дальше: надёжнее источник,
+// затем полный текст, затем больше текста, затем раньше опубликована (первоисточник).
+func representative(articles []news.ArticleRaw, group []int) int {
+	best := group[0]
+	for _, idx := range group[1:] {
+		if better(articles[idx], articles[best]) {
+			best = idx
+		}
+	}
+	return best
+}
+
+func better(a, b news.ArticleRaw) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	aFull, bFull := a.Metadata["full_text"] == "1", b.Metadata["full_text"] == "1"
+	if aFull != bFull {
+		return aFull
+	}
+	if la, lb := len([]rune(a.RawContent)), len([]rune(b.RawContent)); la != lb {
+		return la > lb
+	}
+	return a.PublishedAt.Before(b.PublishedAt)
+}
+
+// withCoverage возвращает представителя группы с числом разных источников в ней и списком остальных источников.
+// Несколько статей одного издания (например, из разных лент) считаются одним источником.
+func withCoverage(articles []news.ArticleRaw, group []int, best int) news.ArticleRaw {
+	article := articles[best]
+	var others []string
+	seen := map[string]bool{article.Source: true}
+	for _, idx := range group {
+		if source := articles[idx].Source; !seen[source] {
+			seen[source] = true
+			others = append(others, source)
+		}
+	}
+	article.Coverage = len(seen)
+	if len(others) == 0 {
+		return article
+	}
+	sort.Strings(others)
+
+	metadata := make(map[string]string, len(article.Metadata)+1)
+	for k, v := range article.Metadata {
+		metadata[k] = v
+	}
+	metadata[news.MetaAlsoReportedBy] = strings.Join(others, ", ")
+	article.Metadata = metadata
+	return article
+}
+
+func logCluster(articles []news.ArticleRaw, group []int, best int) {
+	log.Printf("Cluster of %d: keeping [%s] %s", len(group), articles[best].Source, articles[best].Title)
+	for _, idx := range group {
+		if idx != best {
+			log.Printf("    merged [%s] %s", articles[idx].Source, articles[idx].Title)
+		}
+	}
+}
+
+// words разбивает текст на слова (вьетнамские слоги) в NFC и нижнем регистре.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(textnorm.NFC(s)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+}
+
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for w := range a {
+		if _, ok := b[w]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// estimateJaccard оценивает сходство множеств шинглов по доле совпавших минимумов MinHash.
+func estimateJaccard(a, b []uint64) float64 {
+	equal := 0
+	for i := range a {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a))
+}
+
+// splitmix64 — быстрый перемешивающий хэш для построения семейства хэш-функций MinHash.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+type unionFind struct {
+	parent []int
+}
+
+func newUnionFind(n int) *unionFind {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	return &unionFind{parent: parent}
+}
+
+func (u *unionFind) find(i int) int {
+	for u.parent[i] != i {
+		u.parent[i] = u.parent[u.parent[i]]
+		i = u.parent[i]
+	}
+	return i
+}
+
+// union объединяет группы; корнем остаётся меньший индекс, чтобы группа стояла на месте первой статьи.
+func (u *unionFind) union(i, j int) {
+	ri, rj := u.find(i), u.find(j)
+	switch {
+	case ri < rj:
+		u.parent[rj] = ri
+	case rj < ri:
+		u.parent[ri] = rj
+	}
+}
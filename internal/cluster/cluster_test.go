@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+const (
+	metroText = "Tuyến metro số 1 Bến Thành - Suối Tiên chính thức vận hành thương mại từ sáng nay. " +
+		"Hành khách được miễn phí vé trong tháng đầu tiên, sau đó giá vé từ 7.000 đến 20.000 đồng mỗi lượt. " +
+		"Tuyến dài gần 20 km với 14 nhà ga, dự kiến phục vụ hơn 40.000 lượt khách mỗi ngày."
+	stormText = "Bão số 5 đang di chuyển vào vùng biển các tỉnh miền Trung với sức gió mạnh cấp 12. " +
+		"Các địa phương đã sơ tán hàng nghìn hộ dân khỏi vùng nguy hiểm và cấm tàu thuyền ra khơi."
+)
+
+func TestClusterer_Cluster(t *testing.T) {
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	articles := []news.ArticleRaw{
+		{ID: "vne", Source: "vnexpress", Title: "Metro số 1 chính thức vận hành thương mại", RawContent: metroText, PublishedAt: now.Add(-time.Hour)},
+		// Та же статья того же издания из другой ленты не добавляет источник
+		{ID: "vne-2", Source: "vnexpress", Title: "Metro số 1 chính thức vận hành thương mại", RawContent: metroText, PublishedAt: now.Add(-time.Hour)},
+		{ID: "storm", Source: "tuoitre", Title: "Bão số 5 tiến vào miền Trung", RawContent: stormText, PublishedAt: now},
+		// Перепечатка с небольшими правками и хвостом от редакции
+		{ID: "vnp", Source: "vietnamplus", Title: "TP.HCM: Metro số 1 vận hành thương mại", Priority: 1,
+			RawContent: "(TTXVN) " + metroText + " Người dân bày tỏ sự hào hứng.", PublishedAt: now.Add(-30 * time.Minute)},
+		// Другой текст, но почти тот же заголовок
+		{ID: "tn", Source: "thanhnien", Title: "Metro số 1 chính thức vận hành thương mại từ hôm nay",
+			RawContent: "Sáng nay, hàng nghìn người dân đã xếp hàng trải nghiệm chuyến tàu đầu tiên.", PublishedAt: now},
+	}
+
+	got, err := New(config.Pipeline{}).Cluster(context.Background(), articles)
+	if err != nil {
+		t.Fatalf("Cluster() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Cluster() len = %d, want 2 stories: %+v", len(got), got)
+	}
+
+	// Группа стоит на месте первой статьи, представитель — статья приоритетного источника
+	metro := got[0]
+	if metro.ID != "vnp" || metro.Coverage != 3 {
+		t.Errorf("metro story = %s (coverage %d), want vnp with coverage 3", metro.ID, metro.Coverage)
+	}
+	if also := metro.Metadata[news.MetaAlsoReportedBy]; also != "thanhnien, vnexpress" {
+		t.Errorf("also_reported_by = %q, want other sources sorted", also)
+	}
+	if articles[3].Metadata != nil {
+		t.Error("Cluster() must not modify input metadata")
+	}
+	if got[1].ID != "storm" || got[1].Coverage != 1 {
+		t.Errorf("storm story = %s (coverage %d), want unique story with coverage 1", got[1].ID, got[1].Coverage)
+	}
+}
+
+func TestClusterer_Cluster_Disabled(t *testing.T) {
+	articles := []news.ArticleRaw{
+		{ID: "a", Title: "Metro số 1 chính thức vận hành thương mại", RawContent: metroText},
+		{ID: "b", Title: "Metro số 1 chính thức vận hành thương mại", RawContent: metroText},
+	}
+	got, err := New(config.Pipeline{ClusterSimilarity: -1}).Cluster(context.Background(), articles)
+	if err != nil {
+		t.Fatalf("Cluster() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Coverage != 0 {
+		t.Errorf("Cluster() with negative similarity should return input unchanged, got %+v", got)
+	}
+}
+
+func TestBetter(t *testing.T) {
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	long := strings.Repeat("chữ ", 50)
+	tests := []struct {
+		name string
+		a, b news.ArticleRaw
+		want bool
+	}{
+		{"higher priority", news.ArticleRaw{Priority: 2}, news.ArticleRaw{Priority: 1, RawContent: long}, true},
+		{"full text beats teaser", news.ArticleRaw{Metadata: map[string]string{"full_text": "1"}}, news.ArticleRaw{RawContent: long}, true},
+		{"longer text", news.ArticleRaw{RawContent: long}, news.ArticleRaw{RawContent: "chữ"}, true},
+		{"earlier publication", news.ArticleRaw{PublishedAt: now}, news.ArticleRaw{PublishedAt: now.Add(time.Hour)}, true},
+		{"later publication", news.ArticleRaw{PublishedAt: now.Add(time.Hour)}, news.ArticleRaw{PublishedAt: now}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := better(tt.a, tt.b); got != tt.want {
+				t.Errorf("better() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
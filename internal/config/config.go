@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,28 +14,117 @@ type (
 	Root struct {
 		Pipeline Pipeline `yaml:"pipeline"`
 		Gemini   Gemini   `yaml:"gemini"`
+		LLM      LLM      `yaml:"llm"`
+		Sources  Sources  `yaml:"sources"`
 	}
 
 	// Pipeline описывает параметры главного пайплайна (см. docs/architecture.md).
 	Pipeline struct {
-		MaxArticlesPerCategory  int      `yaml:"max_articles_per_category"`
-		Categories              []string `yaml:"categories"`
-		RecencyMaxHours         int      `yaml:"recency_max_hours"`
-		MinContentLength        int      `yaml:"min_content_length"`
-		MaxTotalMessages        int      `yaml:"max_total_messages"`
-		MaxArticlesBeforeGemini int      `yaml:"max_articles_before_gemini"` // Лимит статей перед отправкой в Gemini (для оптимизации RPD)
-		AutoSubscribe           bool     `yaml:"auto_subscribe"`
-		ForceDispatchEnv        string   `yaml:"force_dispatch_env"`
+		MaxArticlesPerCategory  int          `yaml:"max_articles_per_category"`
+		Categories              []string     `yaml:"categories"`
+		RecencyMaxHours         int          `yaml:"recency_max_hours"`
+		MinContentLength        int          `yaml:"min_content_length"`
+		MaxTotalMessages        int          `yaml:"max_total_messages"`
+		MaxArticlesBeforeGemini int          `yaml:"max_articles_before_gemini"` // Лимит статей перед отправкой в Gemini (для оптимизации RPD)
+		PriorityBoostHours      int          `yaml:"priority_boost_hours"`       // На сколько часов «свежее» считается статья за каждую единицу приоритета источника при отборе перед Gemini
+		PriorityRankingBoost    float64      `yaml:"priority_ranking_boost"`     // Прибавка к оценке актуальности за единицу приоритета при сортировке в ранкере
+		ClusterSimilarity       float64      `yaml:"cluster_similarity"`         // Порог сходства текстов (Jaccard по шинглам) для склейки пересказов одной новости; <0 — не склеивать
+		CoverageRankingBoost    float64      `yaml:"coverage_ranking_boost"`     // Прибавка к оценке актуальности за каждый дополнительный источник той же новости
+		AutoSubscribe           bool         `yaml:"auto_subscribe"`
+		ForceDispatchEnv        string       `yaml:"force_dispatch_env"`
+		Rules                   []FilterRule `yaml:"rules"`     // Правила фильтра: include/exclude/boost по ключевым словам, источнику и категории ленты
+		Selection               Selection    `yaml:"selection"` // Как делить max_articles_before_gemini между источниками
+		Safety                  Safety       `yaml:"safety"`    // Отсев и пометка тяжёлых материалов (подробности преступлений, гибели людей)
+
+		CategoryKeywords map[string][]string `yaml:"category_keywords,omitempty"` // Подсказки discoverfeeds: категория -> части URL и названия ленты
+	}
+
+	// Safety задаёт проверку статей на тяжёлое содержание и политику для помеченных статей.
+	// Статья помечается по словарю (слова из заголовка и текста) и, если use_llm, по ответу Gemini
+	// в том же запросе, что и категоризация. Политика выбирается по категории статьи.
+	Safety struct {
+		Enabled    bool              `yaml:"enabled"`
+		UseLLM     bool              `yaml:"use_llm"`              // Дополнительно просить Gemini пометить статью при категоризации
+		Keywords   []string          `yaml:"keywords,omitempty"`   // Дополнительные слова к встроенному словарю; с учётом диакритики, без учёта регистра
+		Action     string            `yaml:"action"`               // Политика по умолчанию: exclude, warn (раздел с предупреждением), soften (сдержанное резюме) или allow; пусто = warn
+		Categories map[string]string `yaml:"categories,omitempty"` // Политика для отдельных категорий: категория -> действие
+	}
+
+	// Selection задаёт стратегию отбора статей перед Gemini, когда их больше max_articles_before_gemini.
+	// Внутри корзины статьи берутся по «эффективному времени» (свежесть плюс priority_boost_hours).
+	Selection struct {
+		Strategy     string `yaml:"strategy"`       // recency (по умолчанию) — общий список, proportional — доля по размеру корзины, round_robin — по очереди
+		BucketBy     string `yaml:"bucket_by"`      // source (по умолчанию) или source_category — источник вместе с категорией ленты
+		MinPerBucket int    `yaml:"min_per_bucket"` // Для proportional: гарантированный минимум статей каждой корзины
+	}
+
+	// FilterRule — одно правило секции pipeline.rules. Условия правила объединяются через И,
+	// значения внутри одного списка — через ИЛИ; незаданное условие выполняется всегда.
+	// Правила include и exclude проверяются по порядку, решает первое сработавшее;
+	// все сработавшие boost складываются.
+	FilterRule struct {
+		Name          string   `yaml:"name"`
+		Action        string   `yaml:"action"`                   // include, exclude или boost
+		Keywords      []string `yaml:"keywords,omitempty"`       // Слова и фразы целиком, без учёта регистра и диакритики
+		Regex         string   `yaml:"regex,omitempty"`          // Регулярное выражение; без учёта регистра и диакритики
+		Fields        []string `yaml:"fields,omitempty"`         // Где искать: title, content; пусто = оба
+		Sources       []string `yaml:"sources,omitempty"`        // ID сайтов из sites.yaml
+		RSSCategories []string `yaml:"rss_categories,omitempty"` // Категории лент (category в sites.yaml)
+		Boost         int      `yaml:"boost,omitempty"`          // Для boost: прибавка к приоритету статьи (может быть отрицательной)
 	}
 
 	// Gemini содержит настройки моделей и размеров батчей.
 	Gemini struct {
-		ModelCategorization   string `yaml:"model_categorization"`
-		ModelSummary          string `yaml:"model_summary"`
-		ModelRanking          string `yaml:"model_ranking"`
-		BatchSizeCategorization int   `yaml:"batch_size_categorization"`
-		BatchSizeSummary      int    `yaml:"batch_size_summary"`
-		BatchSizeRanking      int    `yaml:"batch_size_ranking"`
+		ModelCategorization        string       `yaml:"model_categorization"`
+		ModelSummary               string       `yaml:"model_summary"`
+		ModelRanking               string       `yaml:"model_ranking"`
+		BatchSizeCategorization    int          `yaml:"batch_size_categorization"`
+		BatchSizeSummary           int          `yaml:"batch_size_summary"`
+		BatchSizeRanking           int          `yaml:"batch_size_ranking"`
+		MaxBatchSizeCategorization int          `yaml:"max_batch_size_categorization"` // До какого размера пайплайн укрупняет батч, если не хватает дневной квоты; 0 — не укрупнять
+		MaxBatchSizeSummary        int          `yaml:"max_batch_size_summary"`        // То же для суммаризации
+		RateLimits                 GeminiLimits `yaml:"rate_limits"`                   // Общий лимитер всех запросов к Gemini
+	}
+
+	// GeminiLimits описывает лимиты тарифа Gemini API. 0 — лимит не применяется.
+	GeminiLimits struct {
+		RPM int `yaml:"rpm"` // Запросов в минуту
+		TPM int `yaml:"tpm"` // Токенов в минуту (оценка по длине промпта, уточняется по ответу)
+		RPD int `yaml:"rpd"` // Запросов в сутки
+	}
+
+	// LLM выбирает провайдера языковой модели для каждого этапа. Без блока llm все этапы
+	// работают через Gemini с моделями из блока gemini.
+	LLM struct {
+		Providers map[string]LLMProvider `yaml:"providers"` // Имя провайдера -> настройки; имя gemini без описания — встроенный клиент Gemini
+		Stages    map[string]LLMStage    `yaml:"stages"`    // categorization, ranking, summary
+	}
+
+	// LLMProvider описывает одного провайдера.
+	LLMProvider struct {
+		Type           string `yaml:"type"`            // gemini или openai (любой OpenAI-совместимый API: OpenAI, llama.cpp server, Ollama)
+		BaseURL        string `yaml:"base_url"`        // Для openai: адрес API, например http://localhost:11434/v1
+		APIKeyEnv      string `yaml:"api_key_env"`     // Переменная окружения с ключом; пусто — без авторизации
+		TimeoutSeconds int    `yaml:"timeout_seconds"` // Таймаут одного запроса; 0 — 5 минут
+	}
+
+	// LLMStage задаёт провайдера и модель этапа и запасного провайдера на случай ошибки основного.
+	LLMStage struct {
+		Provider      string `yaml:"provider"`       // Имя из llm.providers; пусто — gemini
+		Model         string `yaml:"model"`          // Пусто — модель этапа из блока gemini
+		Fallback      string `yaml:"fallback"`       // Провайдер, к которому переходить при ошибке; пусто — без переключения
+		FallbackModel string `yaml:"fallback_model"` // Модель запасного провайдера
+	}
+
+	// Sources содержит настройки сбора новостей из источников.
+	Sources struct {
+		MaxConcurrency          int `yaml:"max_concurrency"`           // Размер общего пула загрузчиков (ленты, страницы, статьи)
+		PerHostConcurrency      int `yaml:"per_host_concurrency"`      // Одновременных запросов к одному хосту
+		PolitenessDelayMs       int `yaml:"politeness_delay_ms"`       // Пауза между запросами к одному хосту (<0 — без паузы)
+		FullTextMaxAgeHours     int `yaml:"full_text_max_age_hours"`   // Полный текст догружается только для статей не старше N часов
+		QuarantineAfterFailures int `yaml:"quarantine_after_failures"` // После N ошибок подряд лента уходит в карантин
+		QuarantineBaseHours     int `yaml:"quarantine_base_hours"`     // Первый срок карантина; каждая следующая ошибка удваивает его
+		QuarantineMaxHours      int `yaml:"quarantine_max_hours"`      // Максимальный срок карантина
 	}
 
 	// SitesRoot описывает список источников для парсинга.
@@ -48,7 +139,30 @@ type (
 		URL       string     `yaml:"url"`
 		RSS       string     `yaml:"rss,omitempty"`       // Обратная совместимость: одна RSS-лента
 		RSSFeeds  []RSSFeed  `yaml:"rss_feeds,omitempty"` // Новый формат: массив RSS-лент с категориями
-		Priority  int        `yaml:"priority"`
+		Scrape    *Scrape    `yaml:"scrape,omitempty"`    // HTML-скрапинг листингов для сайтов без RSS
+		Sitemaps  []RSSFeed  `yaml:"sitemaps,omitempty"`  // News sitemap (sitemap-news.xml) или индекс sitemap с категориями, как у rss_feeds
+		FullText  bool       `yaml:"full_text,omitempty"` // Догружать полный текст статьи вместо анонса из ленты
+		Priority  int        `yaml:"priority"`            // Приоритет источника: больше — надёжнее (0 — нейтральный)
+	}
+
+	// Scrape описывает, как извлекать статьи со страниц-листингов сайта без RSS.
+	// Селекторы — упрощённый CSS: tag, .class, #id, [attr], [attr=value], потомок (пробел) и ">".
+	// Селекторы Link, Title, Date и Summary применяются внутри блока, найденного по Item.
+	Scrape struct {
+		Pages      []ScrapePage `yaml:"pages"`                 // Страницы-листинги
+		Item       string       `yaml:"item"`                  // Блок одной статьи в листинге
+		Link       string       `yaml:"link,omitempty"`        // Ссылка на статью (href); пусто = первая <a> в блоке
+		Title      string       `yaml:"title,omitempty"`       // Заголовок; пусто = текст ссылки
+		Date       string       `yaml:"date,omitempty"`        // Дата публикации; пусто = время сбора
+		DateAttr   string       `yaml:"date_attr,omitempty"`   // Атрибут с датой (например, datetime); пусто = текст узла
+		DateFormat string       `yaml:"date_format,omitempty"` // Go layout даты; пусто = стандартные форматы RSS
+		Summary    string       `yaml:"summary,omitempty"`     // Анонс статьи
+	}
+
+	// ScrapePage описывает одну страницу-листинг с опциональной категорией (аналогично RSSFeed).
+	ScrapePage struct {
+		URL      string `yaml:"url"`
+		Category string `yaml:"category,omitempty"`
 	}
 
 	// RSSFeed описывает одну RSS-ленту с опциональной категорией.
@@ -90,9 +204,201 @@ func LoadRoot(path string) (Root, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return Root{}, fmt.Errorf("unmarshal config: %w", err)
 	}
+	if err := validateRules(cfg.Pipeline.Rules); err != nil {
+		return Root{}, fmt.Errorf("pipeline rules: %w", err)
+	}
+	if err := validateSelection(cfg.Pipeline.Selection); err != nil {
+		return Root{}, fmt.Errorf("pipeline selection: %w", err)
+	}
+	if err := validateSafety(cfg.Pipeline.Safety, cfg.Pipeline.Categories); err != nil {
+		return Root{}, fmt.Errorf("pipeline safety: %w", err)
+	}
+	if err := validateCategoryKeywords(cfg.Pipeline.CategoryKeywords, cfg.Pipeline.Categories); err != nil {
+		return Root{}, fmt.Errorf("pipeline category_keywords: %w", err)
+	}
+	if err := validateLLM(cfg.LLM); err != nil {
+		return Root{}, fmt.Errorf("llm: %w", err)
+	}
 	return cfg, nil
 }
 
+// Действия правил фильтра (FilterRule.Action).
+const (
+	RuleInclude = "include"
+	RuleExclude = "exclude"
+	RuleBoost   = "boost"
+)
+
+// Стратегии и корзины отбора перед Gemini (Selection).
+const (
+	SelectionRecency      = "recency"
+	SelectionProportional = "proportional"
+	SelectionRoundRobin   = "round_robin"
+
+	BucketBySource         = "source"
+	BucketBySourceCategory = "source_category"
+)
+
+// Действия для статей, помеченных проверкой безопасности (Safety.Action).
+const (
+	SafetyExclude = "exclude"
+	SafetyWarn    = "warn"
+	SafetySoften  = "soften"
+	SafetyAllow   = "allow"
+)
+
+// Типы провайдеров (LLMProvider.Type) и этапы пайплайна, которые ходят в языковую модель (LLM.Stages).
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+
+	StageCategorization = "categorization"
+	StageRanking        = "ranking"
+	StageSummary        = "summary"
+)
+
+// Stage возвращает настройки этапа; провайдер по умолчанию — gemini.
+func (l LLM) Stage(name string) LLMStage {
+	stage := l.Stages[name]
+	if stage.Provider == "" {
+		stage.Provider = ProviderGemini
+	}
+	return stage
+}
+
+// Provider возвращает провайдера по имени. Имя gemini без описания означает встроенный клиент Gemini.
+func (l LLM) Provider(name string) (LLMProvider, bool) {
+	provider, ok := l.Providers[name]
+	if !ok && name == ProviderGemini {
+		return LLMProvider{Type: ProviderGemini}, true
+	}
+	return provider, ok
+}
+
+// validateLLM проверяет, что этапы ссылаются на описанных провайдеров известных типов.
+func validateLLM(l LLM) error {
+	for name, provider := range l.Providers {
+		switch provider.Type {
+		case ProviderGemini:
+		case ProviderOpenAI:
+			if provider.BaseURL == "" {
+				return fmt.Errorf("provider %s: base_url is required for openai", name)
+			}
+		default:
+			return fmt.Errorf("provider %s: unknown type %q (want gemini or openai)", name, provider.Type)
+		}
+	}
+	for name := range l.Stages {
+		switch name {
+		case StageCategorization, StageRanking, StageSummary:
+		default:
+			return fmt.Errorf("unknown stage %q (want categorization, ranking or summary)", name)
+		}
+		stage := l.Stage(name)
+		if _, ok := l.Provider(stage.Provider); !ok {
+			return fmt.Errorf("stage %s: unknown provider %q", name, stage.Provider)
+		}
+		if stage.Fallback == "" {
+			continue
+		}
+		if _, ok := l.Provider(stage.Fallback); !ok {
+			return fmt.Errorf("stage %s: unknown fallback provider %q", name, stage.Fallback)
+		}
+		if stage.FallbackModel == "" {
+			return fmt.Errorf("stage %s: fallback_model is required with fallback", name)
+		}
+	}
+	return nil
+}
+
+// validateSafety проверяет действия и то, что политика задана для существующих категорий.
+func validateSafety(safety Safety, categories []string) error {
+	if !validSafetyAction(safety.Action, true) {
+		return fmt.Errorf("unknown action %q (want exclude, warn, soften or allow)", safety.Action)
+	}
+	for category, action := range safety.Categories {
+		if !validSafetyAction(action, false) {
+			return fmt.Errorf("category %q: unknown action %q (want exclude, warn, soften or allow)", category, action)
+		}
+		if !slices.Contains(categories, category) {
+			return fmt.Errorf("category %q is not in pipeline.categories", category)
+		}
+	}
+	return nil
+}
+
+// validateCategoryKeywords проверяет, что подсказки заданы для существующих категорий:
+// после переименования категории discoverfeeds иначе молча перестал бы её предлагать.
+func validateCategoryKeywords(keywords map[string][]string, categories []string) error {
+	for category := range keywords {
+		if !slices.Contains(categories, category) {
+			return fmt.Errorf("category %q is not in pipeline.categories", category)
+		}
+	}
+	return nil
+}
+
+func validSafetyAction(action string, allowEmpty bool) bool {
+	switch action {
+	case SafetyExclude, SafetyWarn, SafetySoften, SafetyAllow:
+		return true
+	case "":
+		return allowEmpty
+	}
+	return false
+}
+
+// validateSelection проверяет секцию selection: неизвестная стратегия молча превратилась бы в recency.
+func validateSelection(sel Selection) error {
+	switch sel.Strategy {
+	case "", SelectionRecency, SelectionProportional, SelectionRoundRobin:
+	default:
+		return fmt.Errorf("unknown strategy %q (want recency, proportional or round_robin)", sel.Strategy)
+	}
+	switch sel.BucketBy {
+	case "", BucketBySource, BucketBySourceCategory:
+	default:
+		return fmt.Errorf("unknown bucket_by %q (want source or source_category)", sel.BucketBy)
+	}
+	if sel.MinPerBucket < 0 {
+		return fmt.Errorf("min_per_bucket must not be negative")
+	}
+	return nil
+}
+
+// validateRules проверяет правила при загрузке, чтобы опечатка в конфиге не отключила фильтр молча.
+func validateRules(rules []FilterRule) error {
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		switch rule.Action {
+		case RuleInclude, RuleExclude:
+		case RuleBoost:
+			if rule.Boost == 0 {
+				return fmt.Errorf("rule %s: boost must be non-zero", name)
+			}
+		default:
+			return fmt.Errorf("rule %s: unknown action %q (want include, exclude or boost)", name, rule.Action)
+		}
+		if len(rule.Keywords) == 0 && rule.Regex == "" && len(rule.Sources) == 0 && len(rule.RSSCategories) == 0 {
+			return fmt.Errorf("rule %s: no conditions (keywords, regex, sources or rss_categories)", name)
+		}
+		for _, field := range rule.Fields {
+			if field != "title" && field != "content" {
+				return fmt.Errorf("rule %s: unknown field %q (want title or content)", name, field)
+			}
+		}
+		if rule.Regex != "" {
+			if _, err := regexp.Compile(rule.Regex); err != nil {
+				return fmt.Errorf("rule %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
 // LoadSites читаёт конфиг со списком источников.
 func LoadSites(path string) (SitesRoot, error) {
 	data, err := os.ReadFile(path)
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
 // EnvConfig содержит токены и другие переменные окружения.
@@ -10,10 +11,11 @@ type EnvConfig struct {
 	TelegramBotToken string
 	GeminiAPIKey     string
 	ForceDispatch    bool
-	SkipGemini       bool // Пропустить этапы Gemini (только логи фильтрации)
-	SendTestMessage  bool // Отправить только тестовое сообщение без обработки новостей
-	BuildMode        bool // Режим формирования дайджеста (сохраняет, не отправляет)
-	SendMode         bool // Режим отправки дайджеста (читает сохраненный, отправляет)
+	SkipGemini       bool      // Пропустить этапы Gemini (только логи фильтрации)
+	SendTestMessage  bool      // Отправить только тестовое сообщение без обработки новостей
+	BuildMode        bool      // Режим формирования дайджеста (сохраняет, не отправляет)
+	SendMode         bool      // Режим отправки дайджеста (читает сохраненный, отправляет)
+	ReplayAt         time.Time // Если задан (REPLAY_AT в RFC3339), пайплайн считает, что сейчас это время
 }
 
 // LoadEnvConfig читает переменные окружения и возвращает конфигурацию.
@@ -37,6 +39,15 @@ func LoadEnvConfig() (*EnvConfig, error) {
 	buildMode := os.Getenv("BUILD_MODE") == "1"
 	sendMode := os.Getenv("SEND_MODE") == "1"
 
+	var replayAt time.Time
+	if value := os.Getenv("REPLAY_AT"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("REPLAY_AT must be RFC3339 (e.g. 2025-01-15T00:30:00Z): %w", err)
+		}
+		replayAt = t
+	}
+
 	return &EnvConfig{
 		TelegramBotToken: tgToken,
 		GeminiAPIKey:     geminiKey,
@@ -45,5 +56,6 @@ func LoadEnvConfig() (*EnvConfig, error) {
 		SendTestMessage:  sendTestMessage,
 		BuildMode:        buildMode,
 		SendMode:         sendMode,
+		ReplayAt:         replayAt,
 	}, nil
 }
@@ -2,75 +2,185 @@ package filter
 
 import (
 	"context"
+	"log"
+	"math"
 	"strings"
 	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/config"
 	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
+	"github.com/maine/vietnam_bot_news/internal/urlnorm"
 )
 
 // Filter реализует бизнес-правила отсечения новостей (docs/architecture.md).
 type Filter struct {
-	cfg config.Pipeline
+	cfg   config.Pipeline
+	rules []rule
+	clock clock.Clock
 }
 
-// New создаёт экземпляр фильтра.
-func New(cfg config.Pipeline) *Filter {
-	return &Filter{cfg: cfg}
+// New создаёт экземпляр фильтра. Если clk == nil, используются системные часы.
+func New(cfg config.Pipeline, clk clock.Clock) *Filter {
+	return &Filter{cfg: cfg, rules: compileRules(cfg.Rules), clock: clock.OrSystem(clk)}
 }
 
-// Apply реализует app.Filter.
-func (f *Filter) Apply(ctx context.Context, articles []news.ArticleRaw, state news.State) ([]news.ArticleRaw, error) {
+// Apply реализует app.Filter. Для каждой входной статьи возвращает решение с причиной,
+// чтобы пороги recency_max_hours и min_content_length можно было подбирать по данным.
+func (f *Filter) Apply(ctx context.Context, articles []news.ArticleRaw, state news.State) ([]news.ArticleRaw, []news.FilterDecision, error) {
 	_ = ctx // на MVP фильтр не использует контекст
 
+	// Отправленное узнаём не только по ID: в нём есть время публикации, и статья с исправленной
+	// или отсутствующей датой получает новый ID при каждом запуске
 	sentIDs := make(map[string]struct{}, len(state.SentArticles))
+	sentURLs := make(map[string]struct{}, len(state.SentArticles))
+	sentTitles := make(map[string][]news.StateArticle, len(state.SentArticles))
 	for _, item := range state.SentArticles {
 		sentIDs[item.ID] = struct{}{}
+		for _, u := range []string{item.CanonicalURL, item.PageCanonical} {
+			if u != "" {
+				sentURLs[strings.ToLower(u)] = struct{}{}
+			}
+		}
+		if item.TitleHash != "" {
+			sentTitles[item.TitleHash] = append(sentTitles[item.TitleHash], item)
+		}
 	}
 
-	now := time.Now()
+	now := f.clock.Now()
 	cutoff := now.Add(-time.Duration(f.cfg.RecencyMaxHours) * time.Hour)
 
-	seen := make(map[string]struct{})
+	// Заголовок — слабый признак: рубрики вроде «Погода на выходные» повторяются, а другое
+	// издание может выйти с тем же заголовком. Поэтому повтором считается только статья того же
+	// источника, отправленная в пределах окна актуальности
+	sentTitle := func(article news.ArticleRaw) bool {
+		fingerprint := textnorm.TitleFingerprint(article.Title)
+		if fingerprint == "" {
+			return false
+		}
+		for _, item := range sentTitles[fingerprint] {
+			// В записях старого формата источника нет
+			sameSource := item.Source == "" || item.Source == article.Source
+			if sameSource && !item.SentAt.Before(cutoff) {
+				return true
+			}
+		}
+		return false
+	}
+
+	seen := make(map[string]string) // Ключ дедупликации -> ID первой статьи
 	filtered := make([]news.ArticleRaw, 0, len(articles))
+	decisions := make([]news.FilterDecision, 0, len(articles))
 
 	for _, article := range articles {
+		contentLength := len([]rune(strings.TrimSpace(article.RawContent)))
+		decide := func(reason, detail string) {
+			decisions = append(decisions, news.FilterDecision{
+				ArticleID:     article.ID,
+				Source:        article.Source,
+				Title:         article.Title,
+				URL:           article.URL,
+				PublishedAt:   article.PublishedAt,
+				AgeHours:      math.Round(now.Sub(article.PublishedAt).Hours()*10) / 10,
+				ContentLength: contentLength,
+				Kept:          reason == news.FilterKept,
+				Reason:        reason,
+				Detail:        detail,
+			})
+		}
+
 		// Фильтруем старые статьи
 		if article.PublishedAt.Before(cutoff) {
+			decide(news.FilterTooOld, "")
 			continue
 		}
 
 		// Фильтруем статьи с датой в будущем (некорректные даты в RSS)
 		if article.PublishedAt.After(now) {
+			decide(news.FilterFutureDate, "")
+			continue
+		}
+
+		if contentLength < f.cfg.MinContentLength {
+			decide(news.FilterShortContent, "")
 			continue
 		}
 
-		if len([]rune(strings.TrimSpace(article.RawContent))) < f.cfg.MinContentLength {
+		// Правила из pipeline.rules: отсев по ключевым словам и поправка приоритета
+		decision := evaluate(f.rules, article)
+		if decision.drop {
+			log.Printf("Filter: rule %q dropped [%s] %s", decision.rule, article.Source, article.Title)
+			decide(news.FilterRuleExcluded, decision.rule)
 			continue
 		}
+		article.Priority += decision.boost
 
-		key := canonicalKey(article)
-		if _, ok := seen[key]; ok {
+		keys := dedupKeys(article)
+		if firstID, ok := firstSeen(seen, keys); ok {
+			decide(news.FilterDuplicate, firstID)
 			continue
 		}
 
 		if _, alreadySent := sentIDs[article.ID]; alreadySent {
+			decide(news.FilterAlreadySent, "id")
+			continue
+		}
+		if anySent(sentURLs, keys) {
+			decide(news.FilterAlreadySent, "url")
+			continue
+		}
+		if sentTitle(article) {
+			decide(news.FilterAlreadySent, "title")
 			continue
 		}
 
-		seen[key] = struct{}{}
+		for _, key := range keys {
+			seen[key] = article.ID
+		}
 		filtered = append(filtered, article)
+		decide(news.FilterKept, decision.rule)
 	}
 
-	return filtered, nil
+	return filtered, decisions, nil
 }
 
+// canonicalKey возвращает ключ дедупликации: каноническую ссылку (без меток отслеживания,
+// AMP и мобильных вариантов) без учёта регистра, а для статей без ссылки — заголовок.
 func canonicalKey(article news.ArticleRaw) string {
-	base := strings.ToLower(strings.TrimSpace(article.URL))
+	base := article.CanonicalURL
+	if base == "" {
+		base = urlnorm.Canonical(article.URL)
+	}
 	if base == "" {
-		base = strings.ToLower(strings.TrimSpace(article.Title))
+		base = strings.TrimSpace(article.Title)
 	}
-	return base
+	return strings.ToLower(base)
 }
 
+// dedupKeys возвращает ключи дедупликации статьи: canonicalKey и rel=canonical со страницы, если он известен.
+func dedupKeys(article news.ArticleRaw) []string {
+	keys := []string{canonicalKey(article)}
+	if page := strings.ToLower(article.PageCanonicalURL()); page != "" && page != keys[0] {
+		keys = append(keys, page)
+	}
+	return keys
+}
 
+func firstSeen(seen map[string]string, keys []string) (string, bool) {
+	for _, key := range keys {
+		if id, ok := seen[key]; ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func anySent(sent map[string]struct{}, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := sent[key]; ok {
+			return true
+		}
+	}
+	return false
+}
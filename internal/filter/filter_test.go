@@ -6,8 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/config"
 	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
 )
 
 func TestFilter_Apply(t *testing.T) {
@@ -16,7 +18,7 @@ func TestFilter_Apply(t *testing.T) {
 		RecencyMaxHours:  48,
 		MinContentLength: 100,
 	}
-	f := New(cfg)
+	f := New(cfg, nil)
 
 	tests := []struct {
 		name     string
@@ -97,6 +99,107 @@ func TestFilter_Apply(t *testing.T) {
 			},
 			want: 1, // Only new article
 		},
+		{
+			name: "filter already sent by canonical URL after pubDate change",
+			articles: []news.ArticleRaw{
+				{
+					ID:          "site-abc-1733220000", // ID с новой датой публикации
+					Title:       "Edited news",
+					URL:         "https://example.com/sent?utm_source=rss",
+					PublishedAt: now,
+					RawContent:  strings.Repeat("A very long content that exceeds minimum length requirement for filtering. ", 2), // 100+ chars
+				},
+			},
+			state: news.State{
+				SentArticles: []news.StateArticle{
+					{ID: "site-abc-1733216400", CanonicalURL: "https://example.com/sent", SentAt: now.Add(-1 * time.Hour)},
+				},
+			},
+			want: 0,
+		},
+		{
+			name: "filter already sent by page canonical with another feed link",
+			articles: []news.ArticleRaw{
+				{
+					ID:           "site-amp-1",
+					Title:        "Amp news",
+					URL:          "https://example.com/amp/sent",
+					CanonicalURL: "https://example.com/amp/sent",
+					Metadata:     map[string]string{news.MetaPageCanonical: "https://example.com/thoi-su/sent"},
+					PublishedAt:  now,
+					RawContent:   strings.Repeat("A very long content that exceeds minimum length requirement for filtering. ", 2), // 100+ chars
+				},
+				{
+					ID:          "site-fetch-failed",
+					Title:       "Fetch failed news",
+					URL:         "https://example.com/sent",
+					PublishedAt: now,
+					RawContent:  strings.Repeat("A very long content that exceeds minimum length requirement for filtering. ", 2), // 100+ chars
+				},
+			},
+			state: news.State{
+				SentArticles: []news.StateArticle{
+					{ID: "site-1", CanonicalURL: "https://example.com/sent", PageCanonical: "https://example.com/thoi-su/sent", SentAt: now.Add(-1 * time.Hour)},
+				},
+			},
+			want: 0,
+		},
+		{
+			name: "filter already sent by title fingerprint",
+			articles: []news.ArticleRaw{
+				{
+					ID:          "vnexpress-2",
+					Source:      "vnexpress",
+					Title:       "Hà Nội cấm xe máy ở nội thành từ năm 2030.",
+					URL:         "https://other.example.com/ha-noi-cam-xe-may",
+					PublishedAt: now,
+					RawContent:  strings.Repeat("A very long content that exceeds minimum length requirement for filtering. ", 2), // 100+ chars
+				},
+				{
+					ID:          "short-title",
+					Title:       "Tin mới",
+					URL:         "https://other.example.com/tin-moi",
+					PublishedAt: now,
+					RawContent:  strings.Repeat("A very long content that exceeds minimum length requirement for filtering. ", 2), // 100+ chars
+				},
+			},
+			state: news.State{
+				SentArticles: []news.StateArticle{
+					{ID: "vnexpress-1", Source: "vnexpress", TitleHash: textnorm.TitleFingerprint("Hà Nội cấm xe máy ở nội thành từ năm 2030"), SentAt: now.Add(-1 * time.Hour)},
+					{ID: "site-2", TitleHash: textnorm.TitleFingerprint("Tin mới"), SentAt: now.Add(-1 * time.Hour)},
+				},
+			},
+			want: 1, // Короткие заголовки не сравниваются
+		},
+		{
+			name: "title fingerprint ignores other sources and history older than recency window",
+			articles: []news.ArticleRaw{
+				{
+					ID:          "tuoitre-1",
+					Source:      "tuoitre",
+					Title:       "Hà Nội cấm xe máy ở nội thành từ năm 2030",
+					URL:         "https://tuoitre.example.com/ha-noi-cam-xe-may",
+					PublishedAt: now,
+					RawContent:  strings.Repeat("A very long content that exceeds minimum length requirement for filtering. ", 2), // 100+ chars
+				},
+				{
+					ID:          "tuoitre-2",
+					Source:      "tuoitre",
+					Title:       "Thời tiết cuối tuần ở Hà Nội và TP.HCM",
+					URL:         "https://tuoitre.example.com/thoi-tiet-cuoi-tuan",
+					PublishedAt: now,
+					RawContent:  strings.Repeat("A very long content that exceeds minimum length requirement for filtering. ", 2), // 100+ chars
+				},
+			},
+			state: news.State{
+				SentArticles: []news.StateArticle{
+					{ID: "vnexpress-1", Source: "vnexpress", TitleHash: textnorm.TitleFingerprint("Hà Nội cấm xe máy ở nội thành từ năm 2030"), SentAt: now.Add(-1 * time.Hour)},
+					// Еженедельная рубрика: прошлый выпуск отправлен неделю назад
+					{ID: "tuoitre-0", Source: "tuoitre", TitleHash: textnorm.TitleFingerprint("Thời tiết cuối tuần ở Hà Nội và TP.HCM"), SentAt: now.Add(-7 * 24 * time.Hour)},
+				},
+			},
+			want: 2,
+		},
 		{
 			name: "filter duplicates by URL",
 			articles: []news.ArticleRaw{
@@ -190,17 +293,80 @@ func TestFilter_Apply(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := context.Background()
-			got, err := f.Apply(ctx, tt.articles, tt.state)
+			got, decisions, err := f.Apply(ctx, tt.articles, tt.state)
 			if err != nil {
 				t.Fatalf("Apply() error = %v", err)
 			}
 			if len(got) != tt.want {
 				t.Errorf("Apply() len = %v, want %v", len(got), tt.want)
 			}
+			if len(decisions) != len(tt.articles) {
+				t.Errorf("Apply() decisions = %d, want one per article (%d)", len(decisions), len(tt.articles))
+			}
 		})
 	}
 }
 
+func TestFilter_ApplyDecisions(t *testing.T) {
+	now := time.Now()
+	f := New(config.Pipeline{
+		RecencyMaxHours:  24,
+		MinContentLength: 10,
+		Rules: []config.FilterRule{
+			{Name: "lottery", Action: config.RuleExclude, Keywords: []string{"xổ số"}},
+		},
+	}, clock.NewFake(now))
+	content := "Nội dung đủ dài cho bộ lọc"
+	articles := []news.ArticleRaw{
+		{ID: "old", Source: "a", Title: "Tin cũ", URL: "https://a.vn/old", PublishedAt: now.Add(-30 * time.Hour), RawContent: content},
+		{ID: "future", Source: "a", Title: "Tin tương lai", URL: "https://a.vn/future", PublishedAt: now.Add(time.Hour), RawContent: content},
+		{ID: "short", Source: "b", Title: "Tin ngắn", URL: "https://b.vn/short", PublishedAt: now, RawContent: "ngắn"},
+		{ID: "lottery", Source: "b", Title: "Kết quả xổ số", URL: "https://b.vn/xs", PublishedAt: now, RawContent: content},
+		{ID: "kept", Source: "b", Title: "Tin mới", URL: "https://b.vn/new", PublishedAt: now, RawContent: content},
+		{ID: "dup", Source: "a", Title: "Tin mới", URL: "https://www.b.vn/new?utm_source=rss", PublishedAt: now, RawContent: content},
+		{ID: "sent", Source: "a", Title: "Tin đã gửi", URL: "https://a.vn/sent", PublishedAt: now, RawContent: content},
+	}
+	state := news.State{SentArticles: []news.StateArticle{{ID: "sent"}}}
+
+	_, decisions, err := f.Apply(context.Background(), articles, state)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	want := []struct{ reason, detail string }{
+		{news.FilterTooOld, ""},
+		{news.FilterFutureDate, ""},
+		{news.FilterShortContent, ""},
+		{news.FilterRuleExcluded, "lottery"},
+		{news.FilterKept, ""},
+		{news.FilterDuplicate, "kept"},
+		{news.FilterAlreadySent, "id"},
+	}
+	if len(decisions) != len(want) {
+		t.Fatalf("Apply() decisions = %d, want %d", len(decisions), len(want))
+	}
+	for i, w := range want {
+		d := decisions[i]
+		if d.ArticleID != articles[i].ID || d.Reason != w.reason || d.Detail != w.detail {
+			t.Errorf("decision[%d] = %s %s/%q, want %s %s/%q", i, d.ArticleID, d.Reason, d.Detail, articles[i].ID, w.reason, w.detail)
+		}
+		if d.Kept != (w.reason == news.FilterKept) {
+			t.Errorf("decision[%d].Kept = %v", i, d.Kept)
+		}
+	}
+	if decisions[0].AgeHours != 30 || decisions[2].ContentLength != 4 {
+		t.Errorf("age = %v, content length = %d, want 30h and 4", decisions[0].AgeHours, decisions[2].ContentLength)
+	}
+
+	audit := news.NewFilterAudit(decisions, now)
+	if audit.Total != 7 || audit.Kept != 1 {
+		t.Errorf("audit total/kept = %d/%d, want 7/1", audit.Total, audit.Kept)
+	}
+	if audit.ByReason[news.FilterAlreadySent] != 1 || audit.BySource["b"][news.FilterShortContent] != 1 || audit.BySource["a"][news.FilterTooOld] != 1 {
+		t.Errorf("audit summary = %v / %v", audit.ByReason, audit.BySource)
+	}
+}
+
 func TestFilter_canonicalKey(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -239,6 +405,23 @@ func TestFilter_canonicalKey(t *testing.T) {
 			},
 			want: "https://example.com/news",
 		},
+		{
+			name: "tracking params and AMP variant",
+			article: news.ArticleRaw{
+				URL:   "http://m.example.com/amp/news/?utm_source=rss&zarsrc=1",
+				Title: "Title",
+			},
+			want: "https://example.com/news",
+		},
+		{
+			name: "canonical URL from collector wins",
+			article: news.ArticleRaw{
+				URL:          "https://example.com/news-draft",
+				CanonicalURL: "https://example.com/news",
+				Title:        "Title",
+			},
+			want: "https://example.com/news",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,180 @@
+package filter
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
+)
+
+// rule — скомпилированное правило из pipeline.rules.
+type rule struct {
+	name       string
+	action     string
+	boost      int
+	keywords   []string // Свёрнутые фразы, окружённые пробелами, для поиска целых слов
+	regex      *regexp.Regexp
+	inTitle    bool
+	inContent  bool
+	sources    map[string]bool
+	categories map[string]bool // Свёрнутые категории лент
+}
+
+// compileRules готовит правила к проверке. Некорректные правила отсекает config.LoadRoot,
+// здесь они только логируются и пропускаются.
+func compileRules(cfgs []config.FilterRule) []rule {
+	rules := make([]rule, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		r := rule{
+			name:   cfg.Name,
+			action: cfg.Action,
+			boost:  cfg.Boost,
+		}
+		if r.name == "" {
+			r.name = fmt.Sprintf("#%d", i+1)
+		}
+
+		for _, keyword := range cfg.Keywords {
+			if folded := foldWords(keyword); strings.TrimSpace(folded) != "" {
+				r.keywords = append(r.keywords, folded)
+			}
+		}
+		if cfg.Regex != "" {
+			// Регулярное выражение применяется к тексту без диакритики, поэтому и из него её убираем
+			re, err := regexp.Compile("(?i)" + textnorm.StripDiacritics(cfg.Regex))
+			if err != nil {
+				log.Printf("Filter: skipping rule %s: %v", r.name, err)
+				continue
+			}
+			r.regex = re
+		}
+
+		r.inTitle, r.inContent = len(cfg.Fields) == 0, len(cfg.Fields) == 0
+		for _, field := range cfg.Fields {
+			switch field {
+			case "title":
+				r.inTitle = true
+			case "content":
+				r.inContent = true
+			}
+		}
+
+		if len(cfg.Sources) > 0 {
+			r.sources = make(map[string]bool, len(cfg.Sources))
+			for _, source := range cfg.Sources {
+				r.sources[source] = true
+			}
+		}
+		if len(cfg.RSSCategories) > 0 {
+			r.categories = make(map[string]bool, len(cfg.RSSCategories))
+			for _, category := range cfg.RSSCategories {
+				r.categories[textnorm.Fold(strings.TrimSpace(category))] = true
+			}
+		}
+
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// foldedArticle — текст статьи, подготовленный для сравнения с правилами (считается один раз).
+type foldedArticle struct {
+	titleWords, contentWords string // Слова через пробел с пробелами по краям
+	title, content           string // Без диакритики и регистра, с пунктуацией — для regex
+	category                 string
+}
+
+func foldArticle(article news.ArticleRaw) foldedArticle {
+	title, content := textnorm.Fold(article.Title), textnorm.Fold(article.RawContent)
+	return foldedArticle{
+		titleWords:   joinWords(title),
+		contentWords: joinWords(content),
+		title:        title,
+		content:      content,
+		category:     textnorm.Fold(strings.TrimSpace(article.Metadata["rss_category"])),
+	}
+}
+
+// matches проверяет все условия правила.
+func (r rule) matches(article news.ArticleRaw, folded foldedArticle) bool {
+	if r.sources != nil && !r.sources[article.Source] {
+		return false
+	}
+	if r.categories != nil && !r.categories[folded.category] {
+		return false
+	}
+
+	if len(r.keywords) > 0 {
+		found := false
+		for _, keyword := range r.keywords {
+			if (r.inTitle && strings.Contains(folded.titleWords, keyword)) ||
+				(r.inContent && strings.Contains(folded.contentWords, keyword)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if r.regex != nil {
+		if !(r.inTitle && r.regex.MatchString(folded.title)) && !(r.inContent && r.regex.MatchString(folded.content)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleDecision — итог проверки статьи правилами.
+type ruleDecision struct {
+	drop  bool
+	rule  string // Правило, решившее судьбу статьи (include/exclude)
+	boost int
+}
+
+// evaluate применяет правила к статье: первое сработавшее include/exclude решает, оставить ли её,
+// boost от всех сработавших правил суммируются.
+func evaluate(rules []rule, article news.ArticleRaw) ruleDecision {
+	var decision ruleDecision
+	if len(rules) == 0 {
+		return decision
+	}
+
+	folded := foldArticle(article)
+	decided := false
+	for _, r := range rules {
+		if r.action != config.RuleBoost && decided {
+			continue
+		}
+		if !r.matches(article, folded) {
+			continue
+		}
+		switch r.action {
+		case config.RuleBoost:
+			decision.boost += r.boost
+		case config.RuleExclude:
+			decision.drop, decision.rule, decided = true, r.name, true
+		case config.RuleInclude:
+			decision.rule, decided = r.name, true
+		}
+	}
+	return decision
+}
+
+// foldWords сворачивает фразу и возвращает её слова через пробел с пробелами по краям.
+func foldWords(s string) string {
+	return joinWords(textnorm.Fold(s))
+}
+
+func joinWords(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
@@ -0,0 +1,145 @@
+package filter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+func TestFilter_ApplyRules(t *testing.T) {
+	rules := []config.FilterRule{
+		{
+			Name:     "keep-lottery-policy",
+			Action:   config.RuleInclude,
+			Keywords: []string{"Bộ Tài chính"},
+		},
+		{
+			Name:     "lottery",
+			Action:   config.RuleExclude,
+			Keywords: []string{"xo so", "tử vi"},
+			Fields:   []string{"title"},
+		},
+		{
+			Name:          "roundup",
+			Action:        config.RuleExclude,
+			Regex:         `^tai nạn .* \d+ người`,
+			Sources:       []string{"vnexpress"},
+			RSSCategories: []string{"Thời sự"},
+		},
+		{
+			Name:     "economy",
+			Action:   config.RuleBoost,
+			Keywords: []string{"lãi suất"},
+			Boost:    2,
+		},
+		{
+			Name:     "celebrity",
+			Action:   config.RuleBoost,
+			Keywords: []string{"showbiz"},
+			Boost:    -1,
+		},
+	}
+	f := New(config.Pipeline{RecencyMaxHours: 48, Rules: rules}, nil)
+
+	now := time.Now().Add(-time.Hour)
+	article := func(id, source, category, title, content string) news.ArticleRaw {
+		return news.ArticleRaw{
+			ID:          id,
+			Source:      source,
+			Title:       title,
+			URL:         "https://example.vn/" + id,
+			PublishedAt: now,
+			RawContent:  content,
+			Priority:    1,
+			Metadata:    map[string]string{"rss_category": category},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		article      news.ArticleRaw
+		wantKept     bool
+		wantPriority int
+	}{
+		{
+			name:         "keyword without diacritics matches title",
+			article:      article("1", "tuoitre", "Đời sống", "Kết quả XỔ SỐ miền Bắc hôm nay", "Nội dung"),
+			wantKept:     false,
+			wantPriority: 1,
+		},
+		{
+			name:         "keyword only in content ignored for title field",
+			article:      article("2", "tuoitre", "Đời sống", "Chuyện nghề bán vé", "Người bán vé xổ số kể chuyện"),
+			wantKept:     true,
+			wantPriority: 1,
+		},
+		{
+			name:         "whole words only",
+			article:      article("3", "tuoitre", "Đời sống", "Xoso App ra mắt phiên bản mới", "Nội dung"),
+			wantKept:     true,
+			wantPriority: 1,
+		},
+		{
+			name:         "include rule listed first wins",
+			article:      article("4", "tuoitre", "Kinh doanh", "Bộ Tài chính siết quản lý xổ số", "Nội dung"),
+			wantKept:     true,
+			wantPriority: 1,
+		},
+		{
+			name:         "regex scoped by source and category",
+			article:      article("5", "vnexpress", "Thời sự", "Tai nạn giao thông làm 3 người chết", "Nội dung"),
+			wantKept:     false,
+			wantPriority: 1,
+		},
+		{
+			name:         "regex ignored for other source",
+			article:      article("6", "tuoitre", "Thời sự", "Tai nạn giao thông làm 3 người chết", "Nội dung"),
+			wantKept:     true,
+			wantPriority: 1,
+		},
+		{
+			name:         "boosts accumulate",
+			article:      article("7", "tuoitre", "Kinh doanh", "Lãi suất giảm, showbiz bàn tán", "Nội dung về lãi suất"),
+			wantKept:     true,
+			wantPriority: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := f.Apply(context.Background(), []news.ArticleRaw{tt.article}, news.State{})
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if kept := len(got) == 1; kept != tt.wantKept {
+				t.Fatalf("Apply() kept = %v, want %v", kept, tt.wantKept)
+			}
+			if tt.wantKept && got[0].Priority != tt.wantPriority {
+				t.Errorf("Priority = %d, want %d", got[0].Priority, tt.wantPriority)
+			}
+		})
+	}
+}
+
+func TestCompileRules_Defaults(t *testing.T) {
+	rules := compileRules([]config.FilterRule{
+		{Action: config.RuleExclude, Keywords: []string{"  Tử   vi!  ", "..."}},
+	})
+	if len(rules) != 1 {
+		t.Fatalf("compileRules() = %d rules, want 1", len(rules))
+	}
+	r := rules[0]
+	if r.name != "#1" {
+		t.Errorf("name = %q, want #1", r.name)
+	}
+	if !r.inTitle || !r.inContent {
+		t.Errorf("fields = title:%v content:%v, want both", r.inTitle, r.inContent)
+	}
+	if strings.Join(r.keywords, "|") != " tu vi " {
+		t.Errorf("keywords = %q, want [\" tu vi \"]", r.keywords)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/config"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
@@ -17,21 +18,29 @@ const (
 	headerTemplate = "Подборка дня (%d/%d) — %s\n\n"
 	// ellipsis - символы, добавляемые при обрезке сообщения
 	ellipsis = "..."
+	// zeroWidthSpace - невидимый текст ссылки на главную картинку блока
+	zeroWidthSpace = "\u200b"
+	// contentWarningCategory - раздел для тяжёлых новостей (pipeline.safety, действие warn), идёт последним
+	contentWarningCategory = "⚠️ Тяжёлые новости"
+	// contentWarningNote - пояснение под заголовком раздела тяжёлых новостей
+	contentWarningNote = "_Преступления и происшествия — открывайте по желанию_\n"
 )
 
 // Formatter реализует app.Formatter для форматирования дайджеста в Markdown.
 type Formatter struct {
 	maxMessages int
+	clock       clock.Clock // Дата в заголовках сообщений
 }
 
-// NewFormatter создаёт новый экземпляр форматтера.
-func NewFormatter(cfg config.Pipeline) *Formatter {
+// NewFormatter создаёт новый экземпляр форматтера. Если clk == nil, используются системные часы.
+func NewFormatter(cfg config.Pipeline, clk clock.Clock) *Formatter {
 	maxMessages := cfg.MaxTotalMessages
 	if maxMessages <= 0 {
 		maxMessages = 5 // дефолтное значение
 	}
 	return &Formatter{
 		maxMessages: maxMessages,
+		clock:       clock.OrSystem(clk),
 	}
 }
 
@@ -49,6 +58,9 @@ func (f *Formatter) BuildMessages(entries []news.DigestEntry) ([]string, error)
 		if category == "" {
 			category = "Другое / Разное"
 		}
+		if entry.ContentWarning {
+			category = contentWarningCategory
+		}
 		byCategory[category] = append(byCategory[category], entry)
 	}
 
@@ -65,7 +77,7 @@ func (f *Formatter) BuildMessages(entries []news.DigestEntry) ([]string, error)
 // formatCategoriesAsBlocks форматирует каждую категорию отдельно и возвращает массив блоков.
 func (f *Formatter) formatCategoriesAsBlocks(byCategory map[string][]news.DigestEntry) []string {
 	// Сортируем категории для предсказуемого порядка
-	// "Самое важное" - первым, остальные - по алфавиту, "Другое / Разное" - последним
+	// "Самое важное" - первым, остальные - по алфавиту, затем "Другое / Разное" и раздел тяжёлых новостей
 	categories := make([]string, 0, len(byCategory))
 	for cat := range byCategory {
 		categories = append(categories, cat)
@@ -83,6 +95,14 @@ func (f *Formatter) formatCategoriesAsBlocks(byCategory map[string][]news.Digest
 			return false
 		}
 
+		// Раздел тяжёлых новостей — в самом конце, чтобы его было легко пропустить
+		if catI == contentWarningCategory {
+			return false
+		}
+		if catJ == contentWarningCategory {
+			return true
+		}
+
 		// "Другое / Разное" всегда последнее
 		if catI == "Другое / Разное" {
 			return false
@@ -99,10 +119,21 @@ func (f *Formatter) formatCategoriesAsBlocks(byCategory map[string][]news.Digest
 	for _, category := range categories {
 		var sb strings.Builder
 
+		entries := byCategory[category]
+
+		// Скрытая ссылка на картинку перед заголовком: Telegram строит превью по первой ссылке
+		// сообщения, поэтому под сообщением показывается главная картинка, а не превью первой статьи.
+		// У раздела тяжёлых новостей картинку не показываем
+		if image := leadImage(entries); image != "" && category != contentWarningCategory {
+			sb.WriteString(fmt.Sprintf("[%s](%s)", zeroWidthSpace, image))
+		}
+
 		// Заголовок категории: *Категория*
 		sb.WriteString(fmt.Sprintf("*%s*\n", category))
+		if category == contentWarningCategory {
+			sb.WriteString(contentWarningNote)
+		}
 
-		entries := byCategory[category]
 		for j, entry := range entries {
 			// Используем переведенный заголовок, если он есть, иначе оригинальный
 			title := entry.TitleRU
@@ -111,6 +142,10 @@ func (f *Formatter) formatCategoriesAsBlocks(byCategory map[string][]news.Digest
 			}
 			// Формат: [Заголовок](URL) — summary
 			line := fmt.Sprintf("[%s](%s) — %s", title, entry.URL, entry.SummaryRU)
+			if author := markdownPlain(entry.Author); author != "" {
+				// Подпись автора: _(Nguyễn Văn A)_
+				line += fmt.Sprintf(" _(%s)_", author)
+			}
 			sb.WriteString(line)
 			if j < len(entries)-1 {
 				sb.WriteString("\n")
@@ -123,6 +158,28 @@ func (f *Formatter) formatCategoriesAsBlocks(byCategory map[string][]news.Digest
 	return blocks
 }
 
+// leadImage возвращает картинку первой статьи блока, у которой она есть.
+func leadImage(entries []news.DigestEntry) string {
+	for _, entry := range entries {
+		// Скобки в URL закрыли бы Markdown-ссылку раньше времени
+		if entry.ImageURL != "" && !strings.ContainsAny(entry.ImageURL, "() ") {
+			return entry.ImageURL
+		}
+	}
+	return ""
+}
+
+// markdownPlain убирает из текста символы разметки Telegram Markdown, чтобы он не сломал форматирование.
+func markdownPlain(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("_*[]`", r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
 // splitIntoMessagesByCategories разбивает блоки категорий на сообщения, не разрывая категории.
 // Каждая категория — это отдельный блок, который либо полностью помещается в сообщение, либо разрывается только в крайнем случае.
 // Количество сообщений определяется количеством категорий (без лимита).
@@ -218,7 +275,7 @@ func (f *Formatter) splitIntoMessagesByCategories(categoryBlocks []string) []str
 	if len(messages) > 1 {
 		total := len(messages)
 		// Форматируем сегодняшнюю дату
-		today := formatDateRu(time.Now())
+		today := formatDateRu(f.clock.Now())
 		result := make([]string, 0, total)
 		for i, msg := range messages {
 			header := fmt.Sprintf(headerTemplate, i+1, total, today)
@@ -3,7 +3,9 @@ package formatter
 import (
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/config"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
@@ -12,7 +14,7 @@ func TestFormatter_BuildMessages(t *testing.T) {
 	cfg := config.Pipeline{
 		MaxTotalMessages: 5,
 	}
-	f := NewFormatter(cfg)
+	f := NewFormatter(cfg, nil)
 
 	tests := []struct {
 		name    string
@@ -103,7 +105,7 @@ func TestFormatter_BuildMessages_SplitLarge(t *testing.T) {
 	cfg := config.Pipeline{
 		MaxTotalMessages: 5,
 	}
-	f := NewFormatter(cfg)
+	f := NewFormatter(cfg, nil)
 
 	// Создаём очень длинные записи, которые не поместятся в одно сообщение
 	entries := make([]news.DigestEntry, 0)
@@ -140,7 +142,7 @@ func TestFormatter_BuildMessages_MarkdownFormat(t *testing.T) {
 	cfg := config.Pipeline{
 		MaxTotalMessages: 5,
 	}
-	f := NewFormatter(cfg)
+	f := NewFormatter(cfg, nil)
 
 	entries := []news.DigestEntry{
 		{
@@ -177,7 +179,7 @@ func TestFormatter_BuildMessages_Numbering(t *testing.T) {
 	cfg := config.Pipeline{
 		MaxTotalMessages: 5,
 	}
-	f := NewFormatter(cfg)
+	f := NewFormatter(cfg, clock.NewFake(time.Date(2025, 1, 15, 8, 0, 0, 0, time.UTC)))
 
 	// Создаём достаточно записей для разбиения на несколько сообщений
 	longSummary := strings.Repeat("Длинное содержание. ", 100)
@@ -203,6 +205,9 @@ func TestFormatter_BuildMessages_Numbering(t *testing.T) {
 			if !strings.HasPrefix(msg, "Подборка дня") {
 				t.Errorf("BuildMessages() message %d should have numbering header", i)
 			}
+			if !strings.Contains(msg, "— 15 января 2025") {
+				t.Errorf("BuildMessages() message %d header should carry the clock date", i)
+			}
 			// Проверяем, что в сообщении есть номер (простая проверка)
 			if !strings.Contains(msg, "(") || !strings.Contains(msg, "/") {
 				t.Errorf("BuildMessages() message %d should contain numbering format", i)
@@ -210,3 +215,67 @@ func TestFormatter_BuildMessages_Numbering(t *testing.T) {
 		}
 	}
 }
+
+func TestFormatter_BuildMessages_LeadImageAndAuthor(t *testing.T) {
+	f := NewFormatter(config.Pipeline{MaxTotalMessages: 5}, nil)
+
+	entries := []news.DigestEntry{
+		{ID: "1", Category: "Политика", Title: "Без картинки", URL: "https://example.com/1", SummaryRU: "Текст 1"},
+		{ID: "2", Category: "Политика", Title: "С картинкой", URL: "https://example.com/2", SummaryRU: "Текст 2",
+			ImageURL: "https://cdn.example.com/2.jpg", Author: "Nguyễn_Văn *A*"},
+		{ID: "3", Category: "Экономика и бизнес", Title: "Без автора", URL: "https://example.com/3", SummaryRU: "Текст 3"},
+	}
+
+	messages, err := f.BuildMessages(entries)
+	if err != nil {
+		t.Fatalf("BuildMessages() error = %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("BuildMessages() len = %v, want 1", len(messages))
+	}
+
+	msg := messages[0]
+	if !strings.Contains(msg, "[\u200b](https://cdn.example.com/2.jpg)*Политика*") {
+		t.Errorf("BuildMessages() should start the category block with a hidden lead image link:\n%s", msg)
+	}
+	if !strings.Contains(msg, "— Текст 2 _(NguyễnVăn A)_") {
+		t.Errorf("BuildMessages() should credit the author without markdown characters:\n%s", msg)
+	}
+	if strings.Contains(msg, "Текст 3 _(") || strings.Contains(msg, "](https://example.com/3)*") {
+		t.Errorf("BuildMessages() should not add credit or image where they are missing:\n%s", msg)
+	}
+	if !strings.HasPrefix(msg, "*Экономика") && !strings.Contains(msg, "\n\n*Экономика и бизнес*") {
+		t.Errorf("BuildMessages() category without images should have a plain header:\n%s", msg)
+	}
+}
+
+func TestFormatter_BuildMessages_ContentWarning(t *testing.T) {
+	f := NewFormatter(config.Pipeline{MaxTotalMessages: 5}, nil)
+
+	entries := []news.DigestEntry{
+		{ID: "1", Category: "Общество", Title: "Суд", URL: "https://example.com/1", SummaryRU: "Тяжёлое",
+			ImageURL: "https://cdn.example.com/1.jpg", ContentWarning: true},
+		{ID: "2", Category: "Общество", Title: "Праздник", URL: "https://example.com/2", SummaryRU: "Лёгкое"},
+		{ID: "3", Category: "Другое / Разное", Title: "Курьёз", URL: "https://example.com/3", SummaryRU: "Разное"},
+	}
+
+	messages, err := f.BuildMessages(entries)
+	if err != nil {
+		t.Fatalf("BuildMessages() error = %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("BuildMessages() len = %v, want 1", len(messages))
+	}
+
+	msg := messages[0]
+	warning := strings.Index(msg, "*"+contentWarningCategory+"*\n"+contentWarningNote)
+	if warning < 0 || warning < strings.Index(msg, "*Другое / Разное*") {
+		t.Errorf("BuildMessages() should put flagged entries into the last warning section:\n%s", msg)
+	}
+	if !strings.Contains(msg[warning:], "Тяжёлое") || strings.Contains(msg[:warning], "Тяжёлое") {
+		t.Errorf("BuildMessages() flagged entry should appear only in the warning section:\n%s", msg)
+	}
+	if strings.Contains(msg, "cdn.example.com/1.jpg") {
+		t.Errorf("BuildMessages() should not show images of flagged entries:\n%s", msg)
+	}
+}
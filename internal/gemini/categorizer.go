@@ -3,25 +3,29 @@ package gemini
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
-	"time"
 
 	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/llm"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
 
-// Categorizer реализует app.Categorizer, используя Gemini API для категоризации новостей.
+// Categorizer реализует app.Categorizer: категоризирует новости через языковую модель (по умолчанию Gemini, см. config.LLM).
 type Categorizer struct {
-	client     GeminiClient
+	client     llm.Client
 	cfg        config.Gemini
 	categories []string
 	batchSize  int
+	maxBatch   int // Потолок batchSize при планировании под остаток квоты
+	// flagSensitive — просить Gemini пометить тяжёлые материалы в том же запросе (pipeline.safety.use_llm)
+	flagSensitive bool
 }
 
 // NewCategorizer создаёт новый экземпляр категоризатора.
-func NewCategorizer(client GeminiClient, geminiCfg config.Gemini, pipelineCfg config.Pipeline) *Categorizer {
+func NewCategorizer(client llm.Client, geminiCfg config.Gemini, pipelineCfg config.Pipeline) *Categorizer {
 	batchSize := geminiCfg.BatchSizeCategorization
 	if batchSize <= 0 {
 		batchSize = 15 // дефолтное значение
@@ -31,6 +35,9 @@ func NewCategorizer(client GeminiClient, geminiCfg config.Gemini, pipelineCfg co
 		cfg:        geminiCfg,
 		categories: pipelineCfg.Categories,
 		batchSize:  batchSize,
+		maxBatch:   max(batchSize, geminiCfg.MaxBatchSizeCategorization),
+
+		flagSensitive: pipelineCfg.Safety.Enabled && pipelineCfg.Safety.UseLLM,
 	}
 }
 
@@ -40,6 +47,7 @@ func (c *Categorizer) Categorize(ctx context.Context, articles []news.ArticleRaw
 	if len(articles) == 0 {
 		return nil, nil
 	}
+	ctx = WithStage(ctx, StageCategorization)
 
 	// Все статьи отправляем в Gemini для категоризации и дедупликации
 	results, err := c.categorizeWithGemini(ctx, articles)
@@ -67,6 +75,18 @@ func (c *Categorizer) Categorize(ctx context.Context, articles []news.ArticleRaw
 	return results, nil
 }
 
+// BatchLimits реализует app.BatchResizer: размер батча из конфига и допустимый потолок.
+func (c *Categorizer) BatchLimits() (size, maxSize int) {
+	return c.batchSize, c.maxBatch
+}
+
+// SetBatchSize реализует app.BatchResizer.
+func (c *Categorizer) SetBatchSize(size int) {
+	if size > 0 {
+		c.batchSize = size
+	}
+}
+
 // categorizeWithGemini отправляет статьи в Gemini для категоризации.
 func (c *Categorizer) categorizeWithGemini(ctx context.Context, articles []news.ArticleRaw) ([]news.CategorizedArticle, error) {
 	var results []news.CategorizedArticle
@@ -81,9 +101,7 @@ func (c *Categorizer) categorizeWithGemini(ctx context.Context, articles []news.
 		log.Printf("Categorizing %d articles with Gemini in %d batches (batch size: %d)", len(articles), totalBatches, c.batchSize)
 	}
 
-	// Задержка 30 секунд между запросами категоризации для соблюдения TPM (не более 2 запросов в минуту)
-	const minDelayBetweenRequests = 30 * time.Second
-	lastRequestTime := time.Now()
+	// Паузы для RPM/TPM выдерживает лимитер клиента
 	requestCount := 0
 
 	for i := 0; i < len(articles); i += effectiveBatchSize {
@@ -92,18 +110,6 @@ func (c *Categorizer) categorizeWithGemini(ctx context.Context, articles []news.
 			end = len(articles)
 		}
 
-		// Соблюдаем задержку между запросами для соблюдения TPM лимита (30 секунд = 2 запроса в минуту)
-		elapsed := time.Since(lastRequestTime)
-		if elapsed < minDelayBetweenRequests && requestCount > 0 {
-			waitTime := minDelayBetweenRequests - elapsed
-			log.Printf("Waiting %v before next Gemini categorization request (TPM limit: max 2 requests per minute)...", waitTime)
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(waitTime):
-			}
-		}
-
 		batch := articles[i:end]
 		requestCount++
 		totalBatches := (len(articles) + effectiveBatchSize - 1) / effectiveBatchSize
@@ -115,7 +121,6 @@ func (c *Categorizer) categorizeWithGemini(ctx context.Context, articles []news.
 		}
 
 		results = append(results, batchResults...)
-		lastRequestTime = time.Now()
 	}
 
 	log.Printf("Gemini categorization complete: %d articles categorized in %d API requests", len(results), requestCount)
@@ -137,6 +142,7 @@ func (c *Categorizer) categorizeBatch(ctx context.Context, articles []news.Artic
 			ID:      article.ID,
 			Title:   article.Title,
 			Content: article.RawContent,
+			Tags:    article.Tags(),
 		})
 	}
 
@@ -148,29 +154,14 @@ func (c *Categorizer) categorizeBatch(ctx context.Context, articles []news.Artic
 	// Формируем промпт согласно docs/prompting.md
 	prompt := c.buildPrompt(string(inputJSON))
 
-	// Вызываем Gemini API
-	responseText, err := c.client.GenerateText(ctx, c.cfg.ModelCategorization, prompt)
-	if err != nil {
-		// Проверяем, является ли это ошибкой квоты (RPD)
-		errStr := err.Error()
-		if strings.Contains(strings.ToLower(errStr), "quota") || strings.Contains(strings.ToLower(errStr), "rpd") {
-			log.Printf("CRITICAL: Gemini API quota exceeded during categorization. Stopping batch processing.")
-			return nil, fmt.Errorf("gemini API quota exceeded (RPD limit): %w", err)
-		}
-		return nil, fmt.Errorf("generate text: %w", err)
-	}
-
-	// Парсим JSON ответ
+	// Вызываем Gemini API: ответ приходит JSON-массивом по схеме
 	var categories []categoryResponse
-	if err := json.Unmarshal([]byte(responseText), &categories); err != nil {
-		// Пытаемся извлечь JSON из текста, если модель добавила лишнее
-		cleaned := extractJSON(responseText)
-		if cleaned == "" {
-			return nil, fmt.Errorf("unmarshal response: %w (raw: %s)", err, responseText)
-		}
-		if err := json.Unmarshal([]byte(cleaned), &categories); err != nil {
-			return nil, fmt.Errorf("unmarshal cleaned response: %w (raw: %s)", err, responseText)
+	if err := c.client.GenerateJSON(ctx, c.cfg.ModelCategorization, prompt, c.responseSchema(), &categories); err != nil {
+		if errors.Is(err, ErrDailyQuota) {
+			log.Printf("CRITICAL: Gemini API quota exceeded during categorization. Stopping batch processing.")
+			return nil, err
 		}
+		return nil, fmt.Errorf("generate categories: %w", err)
 	}
 
 	// Формируем результат с валидацией
@@ -189,8 +180,9 @@ func (c *Categorizer) categorizeBatch(ctx context.Context, articles []news.Artic
 		}
 
 		categorizedMap[article.ID] = news.CategorizedArticle{
-			Article:  article,
-			Category: category,
+			Article:   article,
+			Category:  category,
+			Sensitive: c.flagSensitive && catResp.Sensitive,
 		}
 	}
 
@@ -217,14 +209,24 @@ func (c *Categorizer) categorizeBatch(ctx context.Context, articles []news.Artic
 func (c *Categorizer) buildPrompt(inputJSON string) string {
 	categoriesList := strings.Join(c.categories, `", "`)
 
+	// Пометка тяжёлых материалов — дополнительная задача в том же запросе, без отдельного RPD
+	sensitiveTask, sensitiveField := "", ""
+	if c.flagSensitive {
+		sensitiveTask = `
+3. Для каждой оставшейся новости укажи sensitive: true, если она содержит натуралистичные подробности насилия, убийств, сексуального насилия, самоубийств, травм или описания тел погибших, которые неуместно читать за завтраком. Сухое упоминание происшествия или приговора без подробностей — sensitive: false.
+`
+		sensitiveField = `, "sensitive": <true или false>`
+	}
+
 	return fmt.Sprintf(`Ты — помощник, который классифицирует новости по заданным категориям и удаляет дубликаты.
 Тебе будет передан список новостей. Каждая новость имеет уникальный идентификатор id, заголовок и текст на вьетнамском языке (иногда на английском).
+У некоторых новостей есть поле tags — рубрики и теги, которыми новость пометил сам издатель (например, "Kinh doanh", "Thể thao"). Используй их как подсказку при выборе категории, но решай по содержанию новости.
 
 Твои задачи:
 1. Удали дублирующиеся новости (новости с одинаковым или очень похожим содержанием). Оставь только одну версию каждой новости (выбери наиболее полную или актуальную).
 2. Для каждой оставшейся новости выбери ровно одну категорию из следующего списка:
 "%s".
-
+%s
 ОСОБАЯ КАТЕГОРИЯ "Самое важное":
 Используй эту категорию для новостей глобальной или региональной значимости, которые слишком важны, чтобы их пропустить, даже если они не вписываются в тематические категории.
 
@@ -257,12 +259,24 @@ func (c *Categorizer) buildPrompt(inputJSON string) string {
 - "Другое / Разное" — для новостей, которые не вписываются в тематические категории и не настолько важны для "Самое важное", но могут быть интересны или полезны
 - Если ты удаляешь дубликат, верни в ответе только одну запись с id той новости, которую ты решил оставить. Дубликаты не должны попадать в результат.
 
-Верни результат ТОЛЬКО в виде валидного JSON-массива без markdown блоков, без дополнительных комментариев, без обрамления в code blocks.
-Формат (raw JSON):
-[{"id": "<id новости>", "category": "<одна категория из списка>"}, ...]
+Верни JSON-массив, по одному объекту на каждую оставшуюся новость:
+[{"id": "<id новости>", "category": "<одна категория из списка>"%s}, ...]
 
 Входные данные:
-%s`, categoriesList, inputJSON)
+%s`, categoriesList, sensitiveTask, sensitiveField, inputJSON)
+}
+
+// responseSchema описывает ответ категоризации. Категория не ограничивается enum в схеме:
+// неизвестная категория заменяется на "Другое / Разное", а не проваливает весь батч.
+func (c *Categorizer) responseSchema() *llm.Schema {
+	properties := map[string]*llm.Schema{
+		"id":       {Type: llm.TypeString},
+		"category": {Type: llm.TypeString, Description: "Одна категория из списка"},
+	}
+	if c.flagSensitive {
+		properties["sensitive"] = &llm.Schema{Type: llm.TypeBoolean}
+	}
+	return llm.ArrayOf(properties)
 }
 
 func (c *Categorizer) isValidCategory(category string) bool {
@@ -274,77 +288,16 @@ func (c *Categorizer) isValidCategory(category string) bool {
 	return false
 }
 
-func extractJSON(text string) string {
-	// Удаляем markdown code blocks (```json ... ``` или ``` ... ```)
-	originalText := text
-
-	// Сначала пробуем найти ```json
-	codeBlockStart := strings.Index(text, "```json")
-	if codeBlockStart != -1 {
-		// Нашли начало code block, пропускаем ```json и возможные пробелы/переносы строк
-		contentStart := codeBlockStart + 7 // ```json = 7 символов
-		// Пропускаем пробелы и переносы строк после ```json
-		for contentStart < len(text) && (text[contentStart] == ' ' || text[contentStart] == '\n' || text[contentStart] == '\r' || text[contentStart] == '\t') {
-			contentStart++
-		}
-		// Ищем конец code block (третий ```)
-		remaining := text[contentStart:]
-		codeBlockEnd := strings.Index(remaining, "```")
-		if codeBlockEnd != -1 {
-			// Извлекаем содержимое между ```json и ```
-			text = strings.TrimSpace(remaining[:codeBlockEnd])
-		}
-	} else {
-		// Пробуем найти обычный code block без указания языка
-		codeBlockStart = strings.Index(text, "```")
-		if codeBlockStart != -1 {
-			contentStart := codeBlockStart + 3 // ``` = 3 символа
-			// Пропускаем пробелы и переносы строк после ```
-			for contentStart < len(text) && (text[contentStart] == ' ' || text[contentStart] == '\n' || text[contentStart] == '\r' || text[contentStart] == '\t') {
-				contentStart++
-			}
-			// Ищем конец code block (второй ```)
-			remaining := text[contentStart:]
-			codeBlockEnd := strings.Index(remaining, "```")
-			if codeBlockEnd != -1 {
-				text = strings.TrimSpace(remaining[:codeBlockEnd])
-			}
-		}
-	}
-
-	// Если после обработки code block текст пустой, возвращаем исходный
-	if text == "" {
-		text = originalText
-	}
-
-	// Ищем JSON-массив в тексте
-	start := strings.Index(text, "[")
-	if start == -1 {
-		return ""
-	}
-
-	depth := 0
-	for i := start; i < len(text); i++ {
-		if text[i] == '[' {
-			depth++
-		} else if text[i] == ']' {
-			depth--
-			if depth == 0 {
-				return strings.TrimSpace(text[start : i+1])
-			}
-		}
-	}
-
-	return ""
-}
-
 type articleInput struct {
-	ID      string `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags,omitempty"`   // Рубрики издателя — подсказка для выбора категории
+	Soften  bool     `json:"soften,omitempty"` // Для резюме: писать без тяжёлых подробностей (pipeline.safety)
 }
 
 type categoryResponse struct {
-	ID       string `json:"id"`
-	Category string `json:"category"`
+	ID        string `json:"id"`
+	Category  string `json:"category"`
+	Sensitive bool   `json:"sensitive,omitempty"` // Только при pipeline.safety.use_llm
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/llm"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
 
@@ -22,6 +24,15 @@ func (m *mockGeminiClient) GenerateText(ctx context.Context, model string, promp
 	return "", errors.New("not implemented")
 }
 
+// GenerateJSON декодирует ответ generateTextFunc так же, как настоящий клиент.
+func (m *mockGeminiClient) GenerateJSON(ctx context.Context, model string, prompt string, schema *llm.Schema, out any) error {
+	text, err := m.GenerateText(ctx, model, prompt)
+	if err != nil {
+		return err
+	}
+	return llm.Decode(model, text, schema, out)
+}
+
 func TestCategorizer_Categorize(t *testing.T) {
 	cfg := config.Gemini{
 		ModelCategorization:   "models/gemini-2.5-flash",
@@ -147,7 +158,7 @@ func TestCategorizer_Categorize(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "json with extra text",
+			name: "json with extra text is a schema error",
 			articles: []news.ArticleRaw{
 				{
 					ID:         "article-1",
@@ -162,8 +173,7 @@ func TestCategorizer_Categorize(t *testing.T) {
 				data, _ := json.Marshal(response)
 				return "Here is the response: " + string(data) + " End of response", nil
 			},
-			wantErr: false,
-			wantLen: 1,
+			wantErr: true,
 		},
 	}
 
@@ -198,50 +208,31 @@ func TestCategorizer_Categorize(t *testing.T) {
 	}
 }
 
-func TestCategorizer_extractJSON(t *testing.T) {
-	tests := []struct {
-		name string
-		text string
-		want string
-	}{
-		{
-			name: "pure json",
-			text: `[{"id":"1","category":"Политика"}]`,
-			want: `[{"id":"1","category":"Политика"}]`,
-		},
-		{
-			name: "json with prefix",
-			text: `Here is the response: [{"id":"1","category":"Политика"}]`,
-			want: `[{"id":"1","category":"Политика"}]`,
-		},
-		{
-			name: "json with suffix",
-			text: `[{"id":"1","category":"Политика"}] End of response`,
-			want: `[{"id":"1","category":"Политика"}]`,
-		},
-		{
-			name: "json with both",
-			text: `Prefix [{"id":"1","category":"Политика"}] Suffix`,
-			want: `[{"id":"1","category":"Политика"}]`,
-		},
-		{
-			name: "no json",
-			text: `Just text without json`,
-			want: ``,
-		},
-		{
-			name: "nested json",
-			text: `Response: [{"id":"1","category":"Политика","data":{"nested":"value"}}]`,
-			want: `[{"id":"1","category":"Политика","data":{"nested":"value"}}]`,
-		},
-	}
+func TestCategorizer_Categorize_Sensitive(t *testing.T) {
+	articles := []news.ArticleRaw{{ID: "article-1", Title: "Vụ án", RawContent: "Chi tiết"}}
+	for _, useLLM := range []bool{true, false} {
+		pipelineCfg := config.Pipeline{
+			Categories: []string{"Общество"},
+			Safety:     config.Safety{Enabled: true, UseLLM: useLLM},
+		}
+		var gotPrompt string
+		mockClient := &mockGeminiClient{
+			generateTextFunc: func(ctx context.Context, model string, prompt string) (string, error) {
+				gotPrompt = prompt
+				return `[{"id": "article-1", "category": "Общество", "sensitive": true}]`, nil
+			},
+		}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := extractJSON(tt.text)
-			if got != tt.want {
-				t.Errorf("extractJSON() = %v, want %v", got, tt.want)
-			}
-		})
+		result, err := NewCategorizer(mockClient, config.Gemini{}, pipelineCfg).Categorize(context.Background(), articles)
+		if err != nil {
+			t.Fatalf("use_llm=%v: Categorize() error = %v", useLLM, err)
+		}
+		// Без use_llm модель не спрашивают, и случайное поле в ответе не учитывается
+		if asked := strings.Contains(gotPrompt, `"sensitive"`); asked != useLLM {
+			t.Errorf("use_llm=%v: prompt asks for sensitive = %v", useLLM, asked)
+		}
+		if len(result) != 1 || result[0].Sensitive != useLLM {
+			t.Errorf("use_llm=%v: Categorize() = %+v, want Sensitive = %v", useLLM, result, useLLM)
+		}
 	}
 }
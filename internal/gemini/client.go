@@ -2,32 +2,34 @@ package gemini
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/llm"
 	"google.golang.org/genai"
 )
 
-// GeminiClient определяет интерфейс для работы с Gemini API.
-// Это позволяет легко создавать моки для тестирования.
-type GeminiClient interface {
-	GenerateText(ctx context.Context, model string, prompt string) (string, error)
-}
-
 // Client инкапсулирует работу с Gemini API через официальный SDK.
 type Client struct {
-	client *genai.Client
+	client  *genai.Client
+	clock   clock.Clock // Паузы между повторами запросов
+	limiter *Limiter    // Общий RPM/TPM/RPD лимит для всех этапов
 }
 
-// Убеждаемся, что Client реализует интерфейс GeminiClient.
-var _ GeminiClient = (*Client)(nil)
+// Убеждаемся, что Client реализует интерфейс llm.Client.
+var _ llm.Client = (*Client)(nil)
 
 // NewClient создаёт новый клиент для работы с Gemini API.
 // Читает GEMINI_API_KEY из переменной окружения и явно передаёт его в SDK.
-func NewClient() (*Client, error) {
+// Все запросы проходят через лимитер с лимитами cfg.RateLimits.
+// Если clk == nil, используются системные часы.
+func NewClient(cfg config.Gemini, clk clock.Clock) (*Client, error) {
 	apiKey := os.Getenv("GEMINI_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("GEMINI_API_KEY environment variable is required")
@@ -36,66 +38,112 @@ func NewClient() (*Client, error) {
 	ctx := context.Background()
 
 	// Создаём конфигурацию с явно указанным API ключом
-	config := &genai.ClientConfig{
+	clientCfg := &genai.ClientConfig{
 		APIKey: apiKey,
 	}
 
-	client, err := genai.NewClient(ctx, config)
+	client, err := genai.NewClient(ctx, clientCfg)
 	if err != nil {
 		return nil, fmt.Errorf("create genai client: %w", err)
 	}
 
+	clk = clock.OrSystem(clk)
 	return &Client{
-		client: client,
+		client:  client,
+		clock:   clk,
+		limiter: NewLimiter(cfg.RateLimits, clk),
 	}, nil
 }
 
+// Limiter возвращает общий лимитер клиента: через него пайплайн восстанавливает
+// и сохраняет журнал дневной квоты и планирует запросы под её остаток.
+func (c *Client) Limiter() *Limiter {
+	return c.limiter
+}
+
+// jsonMIMEType — тип ответа, при котором Gemini возвращает JSON по ResponseSchema без markdown-обёрток.
+const jsonMIMEType = "application/json"
+
 // GenerateText отправляет запрос к Gemini API и возвращает текстовый ответ.
 // model - имя модели (например, "gemini-2.5-flash")
 // prompt - текстовый промпт для модели
 // Включает обработку ошибок лимитов и retry-логику для временных ошибок (503, 500, 502, 504).
 func (c *Client) GenerateText(ctx context.Context, model string, prompt string) (string, error) {
+	return c.generate(ctx, model, prompt, nil)
+}
+
+// GenerateJSON реализует llm.Client: передаёт схему ответа и JSON MIME type в SDK,
+// поэтому модель возвращает чистый JSON без markdown-обёрток и пояснений.
+// Повторы при ошибках API — как в GenerateText; ответ, не прошедший проверку схемы, не повторяется.
+func (c *Client) GenerateJSON(ctx context.Context, model string, prompt string, schema *llm.Schema, out any) error {
+	text, err := c.generate(ctx, model, prompt, &genai.GenerateContentConfig{
+		ResponseMIMEType: jsonMIMEType,
+		ResponseSchema:   toGenaiSchema(schema),
+	})
+	if err != nil {
+		return err
+	}
+	return llm.Decode(model, text, schema, out)
+}
+
+// toGenaiSchema переводит схему ответа в формат SDK: типы Gemini API записываются заглавными.
+func toGenaiSchema(schema *llm.Schema) *genai.Schema {
+	if schema == nil {
+		return nil
+	}
+	result := &genai.Schema{
+		Type:        genai.Type(strings.ToUpper(string(schema.Type))),
+		Description: schema.Description,
+		Enum:        schema.Enum,
+		Items:       toGenaiSchema(schema.Items),
+		Nullable:    schema.Nullable,
+		Required:    schema.Required,
+	}
+	if len(schema.Properties) > 0 {
+		result.Properties = make(map[string]*genai.Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			result.Properties[name] = toGenaiSchema(property)
+		}
+	}
+	return result
+}
+
+// generate выполняет запрос с повторами; genCfg == nil — обычный текстовый ответ.
+// Решение о повторе принимается по классу ошибки API (см. classifyError): дневная квота
+// возвращается сразу, минутный лимит и перегрузка ждут паузу из RetryInfo или значение по умолчанию.
+// Если есть запасной провайдер (llm.FailFast), временные ошибки не повторяются: запрос сразу уходит ему.
+func (c *Client) generate(ctx context.Context, model string, prompt string, genCfg *genai.GenerateContentConfig) (string, error) {
 	const maxRetries = 5                            // Увеличено для временных ошибок
-	const baseDelay = 12 * time.Second              // Минимум 12 секунд между запросами для соблюдения RPM=5
+	const baseDelay = 12 * time.Second              // Базовая пауза перед повтором после ошибки сервера
+	const rateLimitDelay = time.Minute              // RPM/TPM лимит сбрасывается раз в минуту
 	const serviceUnavailableDelay = 5 * time.Minute // 5 минут для ошибки 503 (модель перегружена)
 
-	var lastErr error
-	var isServiceUnavailable bool
-	var isRateLimitRPMTPM bool // Флаг для RPM/TPM ошибок (нужна задержка 1 минута)
+	var lastErr *APIError
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
-			var delay time.Duration
-			if isServiceUnavailable {
-				// Для 503 ошибки используем фиксированную паузу 5 минут
-				delay = serviceUnavailableDelay
-				log.Printf("Service unavailable (503) - waiting 5 minutes before retry (attempt %d/%d)...", attempt+1, maxRetries)
-			} else if isRateLimitRPMTPM {
-				// Для RPM/TPM ошибок ждем 1 минуту
-				delay = 1 * time.Minute
-				log.Printf("Rate limit (RPM/TPM) - waiting 1 minute before retry (attempt %d/%d)...", attempt+1, maxRetries)
-			} else {
-				// Для других ошибок - экспоненциальная задержка с минимумом для соблюдения RPM
-				delay = baseDelay * time.Duration(attempt)
-				if delay > 60*time.Second {
-					delay = 60 * time.Second
-				}
-				log.Printf("Retrying Gemini API request (attempt %d/%d) after %v...", attempt+1, maxRetries, delay)
+			delay := retryDelay(lastErr, attempt, baseDelay, rateLimitDelay, serviceUnavailableDelay)
+			log.Printf("Retrying Gemini API request (attempt %d/%d) after %v: %v", attempt+1, maxRetries, delay, lastErr)
+			if err := c.clock.Sleep(ctx, delay); err != nil {
+				return "", err
 			}
+		}
 
-			select {
-			case <-ctx.Done():
-				return "", ctx.Err()
-			case <-time.After(delay):
-			}
+		// Каждая попытка, включая повторы, расходует лимиты тарифа
+		estimated := estimateTokens(prompt)
+		if err := c.limiter.Wait(ctx, model, estimated); err != nil {
+			return "", err
 		}
 
 		result, err := c.client.Models.GenerateContent(
 			ctx,
 			model,
 			genai.Text(prompt),
-			nil,
+			genCfg,
 		)
 		if err == nil {
+			if usage := result.UsageMetadata; usage != nil && usage.TotalTokenCount > 0 {
+				c.limiter.Record(estimated, int(usage.TotalTokenCount))
+			}
 			text, textErr := result.Text()
 			if textErr != nil {
 				return "", fmt.Errorf("get text from result: %w", textErr)
@@ -103,123 +151,39 @@ func (c *Client) GenerateText(ctx context.Context, model string, prompt string)
 			return text, nil
 		}
 
-		lastErr = err
-		errStr := err.Error()
-
-		// Проверяем тип ошибки
-		// Сначала проверяем, является ли это RPD (quota exceeded) в 429 ошибке
-		if isRPDQuotaError(errStr) {
-			// RPD лимит исчерпан - не повторяем, сразу возвращаем ошибку
-			log.Printf("CRITICAL: RPD quota exceeded (daily limit reached) - stopping retries: %v", err)
-			return "", fmt.Errorf("gemini API RPD quota exceeded (daily limit reached): %w", err)
-		}
-
-		// Если это 429, но не RPD, то это RPM/TPM лимит - ждем минуту и повторяем
-		if isRateLimitError(errStr) {
-			log.Printf("Rate limit error (RPM/TPM) from Gemini API: %v", err)
-			isServiceUnavailable = false
-			isRateLimitRPMTPM = true
-			// Продолжаем retry (задержка будет применена в начале следующей итерации)
-			continue
-		}
-
-		// Сбрасываем флаги для других типов ошибок
-		isRateLimitRPMTPM = false
-
-		if isServiceUnavailableError(errStr) {
-			log.Printf("Service unavailable (503) from Gemini API - model overloaded: %v", err)
-			isServiceUnavailable = true
-			isRateLimitRPMTPM = false
-			// Продолжаем retry с длинной паузой
-			continue
+		apiErr := classifyError(err)
+		if apiErr == nil {
+			// Не ошибка API (сеть, отмена контекста) — не повторяем
+			return "", fmt.Errorf("generate content: %w", err)
 		}
-
-		if isTemporaryError(errStr) {
-			log.Printf("Temporary error from Gemini API (500/502/504): %v", err)
-			isServiceUnavailable = false
-			isRateLimitRPMTPM = false
-			// Продолжаем retry для временных ошибок
-			continue
+		if errors.Is(apiErr, ErrDailyQuota) {
+			log.Printf("CRITICAL: Gemini daily quota exhausted (%s) - stopping retries", apiErr.QuotaID)
+			return "", apiErr
 		}
-
-		// Сбрасываем флаги для других типов ошибок
-		isServiceUnavailable = false
-		isRateLimitRPMTPM = false
-
-		// Проверяем другие типы quota exceeded (не 429, но все равно quota)
-		if isQuotaExceededError(errStr) {
-			// Quota exceeded - не повторяем, это критическая ошибка
-			return "", fmt.Errorf("gemini API quota exceeded: %w", err)
+		if !apiErr.temporary() || llm.FailFast(ctx) {
+			return "", fmt.Errorf("generate content: %w", apiErr)
 		}
-
-		// Для других ошибок не повторяем
-		return "", fmt.Errorf("generate content: %w", err)
+		lastErr = apiErr
 	}
 
 	return "", fmt.Errorf("max retries exceeded: %w", lastErr)
 }
 
-// isRPDQuotaError проверяет, является ли ошибка 429 связанной с RPD (quota exceeded).
-// RPD ошибка содержит специфические признаки дневного лимита:
-// - "limit: 20" (дневной лимит запросов)
-// - "generate_content_free_tier_requests" (метрика дневных запросов)
-func isRPDQuotaError(errStr string) bool {
-	errLower := strings.ToLower(errStr)
-	// Проверяем, что это 429 ошибка
-	if !strings.Contains(errLower, "429") && !strings.Contains(errLower, "code: 429") {
-		return false
+// retryDelay выбирает паузу перед повтором: RetryInfo сервера важнее значений по умолчанию.
+func retryDelay(err *APIError, attempt int, base, rateLimit, overloaded time.Duration) time.Duration {
+	if err.RetryDelay > 0 {
+		return err.RetryDelay
 	}
-	// Проверяем специфические признаки RPD (дневной лимит)
-	// Ключевые индикаторы: "limit: 20" и "generate_content_free_tier_requests"
-	hasRPDLimit := strings.Contains(errLower, "limit: 20")
-	hasRPDMetric := strings.Contains(errLower, "generate_content_free_tier_requests")
-
-	// RPD определяется наличием обоих признаков или хотя бы одного из ключевых
-	return hasRPDLimit || hasRPDMetric
-}
-
-// isRateLimitError проверяет, является ли ошибка связанной с rate limit (RPM/TPM).
-// Это 429 ошибка, но НЕ RPD (quota exceeded).
-func isRateLimitError(errStr string) bool {
-	errLower := strings.ToLower(errStr)
-	// Если это RPD, то это не RPM/TPM
-	if isRPDQuotaError(errStr) {
-		return false
+	switch {
+	case errors.Is(err, ErrOverloaded):
+		return overloaded
+	case errors.Is(err, ErrRateLimited):
+		return rateLimit
 	}
-	// Проверяем признаки rate limit (RPM/TPM)
-	return strings.Contains(errLower, "rate limit") ||
-		strings.Contains(errLower, "429") ||
-		strings.Contains(errLower, "too many requests") ||
-		strings.Contains(errLower, "resource exhausted")
-}
-
-// isServiceUnavailableError проверяет, является ли ошибка 503 (Service Unavailable).
-// Это означает, что модель перегружена и требуется длительная пауза (5 минут).
-func isServiceUnavailableError(errStr string) bool {
-	errLower := strings.ToLower(errStr)
-	return strings.Contains(errLower, "503") ||
-		strings.Contains(errLower, "service unavailable") ||
-		strings.Contains(errLower, "overloaded") ||
-		strings.Contains(errLower, "model overloaded")
-}
-
-// isTemporaryError проверяет, является ли ошибка временной (500, 502, 504).
-// Эти ошибки означают, что сервис временно недоступен, но может восстановиться быстрее, чем при 503.
-func isTemporaryError(errStr string) bool {
-	errLower := strings.ToLower(errStr)
-	return strings.Contains(errLower, "500") ||
-		strings.Contains(errLower, "502") ||
-		strings.Contains(errLower, "504") ||
-		strings.Contains(errLower, "internal server error") ||
-		strings.Contains(errLower, "bad gateway") ||
-		strings.Contains(errLower, "gateway timeout")
-}
-
-// isQuotaExceededError проверяет, является ли ошибка связанной с превышением квоты (RPD).
-func isQuotaExceededError(errStr string) bool {
-	errLower := strings.ToLower(errStr)
-	return strings.Contains(errLower, "quota") ||
-		strings.Contains(errLower, "quota exceeded") ||
-		strings.Contains(errLower, "daily limit") ||
-		strings.Contains(errLower, "403")
+	// Для 500/502/504 - растущая задержка с минимумом для соблюдения RPM
+	delay := base * time.Duration(attempt)
+	if delay > 60*time.Second {
+		delay = 60 * time.Second
+	}
+	return delay
 }
@@ -0,0 +1,164 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/llm"
+	"google.golang.org/genai"
+)
+
+// newTestClient создаёт клиент, который ходит в handler вместо Gemini API.
+func newTestClient(t *testing.T, handler http.HandlerFunc, limits config.GeminiLimits, clk clock.Clock) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	sdk, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPClient:  server.Client(),
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("genai.NewClient() error = %v", err)
+	}
+	return &Client{client: sdk, clock: clk, limiter: NewLimiter(limits, clk)}
+}
+
+func TestClient_GenerateText_RecordsUsage(t *testing.T) {
+	tests := []struct {
+		name      string
+		usage     string
+		wantSlept []time.Duration
+	}{
+		{
+			// Промпт оценён в 1001 токен, а модель потратила весь TPM: второй запрос ждёт, пока корзина наберёт 1001
+			name:      "actual usage drains TPM bucket",
+			usage:     `,"usageMetadata":{"promptTokenCount":5000,"candidatesTokenCount":1000,"totalTokenCount":6000}`,
+			wantSlept: []time.Duration{10*time.Second + 11*time.Millisecond},
+		},
+		{
+			name: "response without usage keeps estimate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC))
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"xin chào"}]}}]%s}`, tt.usage)
+			}, config.GeminiLimits{TPM: 6000}, clk)
+
+			prompt := strings.Repeat("a", 3000)
+			for i := 0; i < 2; i++ {
+				text, err := client.GenerateText(context.Background(), "gemini-2.5-flash", prompt)
+				if err != nil {
+					t.Fatalf("GenerateText() error = %v", err)
+				}
+				if text != "xin chào" {
+					t.Errorf("GenerateText() = %q, want %q", text, "xin chào")
+				}
+			}
+			if slept := clk.Slept(); fmt.Sprint(slept) != fmt.Sprint(tt.wantSlept) {
+				t.Errorf("Slept() = %v, want %v", slept, tt.wantSlept)
+			}
+		})
+	}
+}
+
+func TestToGenaiSchema(t *testing.T) {
+	schema := llm.ArrayOf(map[string]*llm.Schema{
+		"id":        {Type: llm.TypeString},
+		"sensitive": {Type: llm.TypeBoolean, Nullable: true},
+	})
+
+	got := toGenaiSchema(schema)
+	if got.Type != genai.TypeArray || got.Items.Type != genai.TypeObject {
+		t.Fatalf("toGenaiSchema() = %+v, want array of objects", got)
+	}
+	if len(got.Items.Required) != 2 || got.Items.Required[0] != "id" {
+		t.Errorf("Required = %v, want [id sensitive]", got.Items.Required)
+	}
+	if p := got.Items.Properties["sensitive"]; p == nil || p.Type != genai.TypeBoolean || !p.Nullable {
+		t.Errorf("sensitive = %+v, want nullable boolean", p)
+	}
+	if toGenaiSchema(nil) != nil {
+		t.Error("toGenaiSchema(nil) != nil")
+	}
+}
+
+func TestClient_GenerateText_FailFast(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		failFast  bool
+		wantCalls int
+		wantErr   bool
+		wantSlept []time.Duration
+	}{
+		{
+			name:      "server error with fallback goes to fallback at once",
+			status:    http.StatusInternalServerError,
+			body:      `{"error":{"code":500,"message":"Internal error encountered.","status":"INTERNAL"}}`,
+			failFast:  true,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "rate limit with fallback goes to fallback at once",
+			status:    http.StatusTooManyRequests,
+			body:      `{"error":{"code":429,"message":"Resource exhausted.","status":"RESOURCE_EXHAUSTED"}}`,
+			failFast:  true,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "server error without fallback is retried",
+			status:    http.StatusInternalServerError,
+			body:      `{"error":{"code":500,"message":"Internal error encountered.","status":"INTERNAL"}}`,
+			wantCalls: 2,
+			wantSlept: []time.Duration{12 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC))
+			calls := 0
+			// Первый ответ — ошибка, следующие — успешные
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					w.WriteHeader(tt.status)
+					fmt.Fprint(w, tt.body)
+					return
+				}
+				fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]}}]}`)
+			}, config.GeminiLimits{}, clk)
+
+			ctx := context.Background()
+			if tt.failFast {
+				ctx = llm.WithFailFast(ctx)
+			}
+			_, err := client.GenerateText(ctx, "gemini-2.5-flash", "hi")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if slept := clk.Slept(); fmt.Sprint(slept) != fmt.Sprint(tt.wantSlept) {
+				t.Errorf("Slept() = %v, want %v", slept, tt.wantSlept)
+			}
+		})
+	}
+}
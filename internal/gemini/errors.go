@@ -0,0 +1,132 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Классы ошибок Gemini API. Вызывающий код проверяет их через errors.Is.
+var (
+	// ErrDailyQuota — исчерпан дневной лимит запросов (RPD); повторять до следующих суток бессмысленно.
+	ErrDailyQuota = errors.New("gemini daily quota exhausted")
+	// ErrRateLimited — превышен минутный лимит запросов или токенов (RPM/TPM).
+	ErrRateLimited = errors.New("gemini rate limited")
+	// ErrOverloaded — модель перегружена (503); помогает только длинная пауза.
+	ErrOverloaded = errors.New("gemini model overloaded")
+)
+
+// Типы деталей ошибки google.rpc, которые возвращает Gemini API.
+const (
+	quotaFailureType = "type.googleapis.com/google.rpc.QuotaFailure"
+	retryInfoType    = "type.googleapis.com/google.rpc.RetryInfo"
+)
+
+// APIError — ошибка Gemini API, разобранная по HTTP-статусу и деталям ответа, а не по тексту.
+type APIError struct {
+	StatusCode int
+	Status     string        // Статус google.rpc: RESOURCE_EXHAUSTED, UNAVAILABLE, ...
+	QuotaID    string        // Нарушенная квота из QuotaFailure, например GenerateRequestsPerDayPerProjectPerModel-FreeTier
+	RetryDelay time.Duration // Пауза из RetryInfo; 0 — сервер её не указал
+	class      error         // ErrDailyQuota, ErrRateLimited, ErrOverloaded или nil
+	err        error
+}
+
+func (e *APIError) Error() string {
+	if e.class != nil {
+		return fmt.Sprintf("%v: %v", e.class, e.err)
+	}
+	return e.err.Error()
+}
+
+// Is сопоставляет ошибку с её классом: errors.Is(err, ErrDailyQuota).
+func (e *APIError) Is(target error) bool {
+	return e.class != nil && target == e.class
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// temporary сообщает, что запрос стоит повторить после паузы.
+func (e *APIError) temporary() bool {
+	switch e.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	}
+	return e.class == ErrRateLimited || e.class == ErrOverloaded
+}
+
+// SDK (v0.3.0) возвращает ошибки API по значению: 4xx — genai.ClientError, 5xx — genai.ServerError.
+// Если это изменится, сборка упадёт здесь, а не errors.As в sdkError во время работы.
+var (
+	_ error = genai.ClientError{}
+	_ error = genai.ServerError{}
+)
+
+// sdkError достаёт из ошибки SDK код HTTP, статус google.rpc и детали ответа.
+func sdkError(err error) (code int, status string, details []map[string]any, ok bool) {
+	var clientErr genai.ClientError
+	if errors.As(err, &clientErr) {
+		return clientErr.Code, clientErr.Status, clientErr.Details, true
+	}
+	var serverErr genai.ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.Code, serverErr.Status, serverErr.Details, true
+	}
+	return 0, "", nil, false
+}
+
+// classifyError разбирает ошибку SDK. Для ошибок, не пришедших от API (сеть, отмена контекста),
+// возвращает nil.
+func classifyError(err error) *APIError {
+	code, status, details, ok := sdkError(err)
+	if !ok {
+		return nil
+	}
+
+	result := &APIError{StatusCode: code, Status: status, err: err}
+	for _, detail := range details {
+		switch detail["@type"] {
+		case quotaFailureType:
+			if result.QuotaID == "" {
+				result.QuotaID = quotaID(detail)
+			}
+		case retryInfoType:
+			if delay, ok := detail["retryDelay"].(string); ok {
+				if d, err := time.ParseDuration(delay); err == nil && d > 0 {
+					result.RetryDelay = d
+				}
+			}
+		}
+	}
+
+	switch {
+	case code == http.StatusTooManyRequests || status == "RESOURCE_EXHAUSTED":
+		// Дневная квота отличается от минутной только идентификатором нарушенной квоты
+		if strings.Contains(strings.ToLower(result.QuotaID), "perday") {
+			result.class = ErrDailyQuota
+		} else {
+			result.class = ErrRateLimited
+		}
+	case code == http.StatusServiceUnavailable || status == "UNAVAILABLE":
+		result.class = ErrOverloaded
+	}
+	return result
+}
+
+// quotaID достаёт идентификатор первой нарушенной квоты из детали QuotaFailure.
+func quotaID(detail map[string]any) string {
+	violations, _ := detail["violations"].([]any)
+	for _, v := range violations {
+		violation, _ := v.(map[string]any)
+		if id, ok := violation["quotaId"].(string); ok && id != "" {
+			return id
+		}
+	}
+	return ""
+}
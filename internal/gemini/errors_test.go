@@ -0,0 +1,142 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+	"google.golang.org/genai"
+)
+
+func TestClassifyError(t *testing.T) {
+	quotaFailure := func(id string) map[string]any {
+		return map[string]any{
+			"@type":      quotaFailureType,
+			"violations": []any{map[string]any{"quotaMetric": "generativelanguage.googleapis.com/generate_content_free_tier_requests", "quotaId": id}},
+		}
+	}
+	retryInfo := map[string]any{"@type": retryInfoType, "retryDelay": "37s"}
+
+	tests := []struct {
+		name          string
+		err           error
+		wantClass     error // nil — ошибка без класса
+		wantTemporary bool
+		wantDelay     time.Duration
+		wantNil       bool
+	}{
+		{
+			name:      "daily quota",
+			err:       clientError(429, "RESOURCE_EXHAUSTED", "", quotaFailure("GenerateRequestsPerDayPerProjectPerModel-FreeTier"), retryInfo),
+			wantClass: ErrDailyQuota,
+			wantDelay: 37 * time.Second,
+		},
+		{
+			name:          "per-minute limit with retry info",
+			err:           clientError(429, "RESOURCE_EXHAUSTED", "", quotaFailure("GenerateRequestsPerMinutePerProjectPerModel-FreeTier"), retryInfo),
+			wantClass:     ErrRateLimited,
+			wantTemporary: true,
+			wantDelay:     37 * time.Second,
+		},
+		{
+			name:          "overloaded model, wrapped",
+			err:           fmt.Errorf("call: %w", serverError(503, "UNAVAILABLE", "The model is overloaded")),
+			wantClass:     ErrOverloaded,
+			wantTemporary: true,
+		},
+		{
+			name:          "internal error is retried without class",
+			err:           serverError(500, "INTERNAL", ""),
+			wantTemporary: true,
+		},
+		{
+			name: "numbers in the message do not matter",
+			err:  clientError(400, "INVALID_ARGUMENT", "bad article id 503-limit: 20 quota"),
+		},
+		{
+			name:    "not an API error",
+			err:     errors.New("dial tcp: 503 connection refused"),
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyError(tt.err)
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("classifyError() = %v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("classifyError() = nil")
+			}
+			for _, class := range []error{ErrDailyQuota, ErrRateLimited, ErrOverloaded} {
+				if errors.Is(got, class) != (class == tt.wantClass) {
+					t.Errorf("errors.Is(%v) = %v, want %v", class, errors.Is(got, class), class == tt.wantClass)
+				}
+			}
+			if got.temporary() != tt.wantTemporary {
+				t.Errorf("temporary() = %v, want %v", got.temporary(), tt.wantTemporary)
+			}
+			if got.RetryDelay != tt.wantDelay {
+				t.Errorf("RetryDelay = %v, want %v", got.RetryDelay, tt.wantDelay)
+			}
+		})
+	}
+}
+
+// clientError и serverError собирают ошибки так, как их возвращает SDK: поля apiError
+// не экспортируются целиком, но доступны через встраивание.
+func clientError(code int, status, message string, details ...map[string]any) genai.ClientError {
+	var err genai.ClientError
+	err.Code, err.Status, err.Message, err.Details = code, status, message, details
+	return err
+}
+
+func serverError(code int, status, message string, details ...map[string]any) genai.ServerError {
+	var err genai.ServerError
+	err.Code, err.Status, err.Message, err.Details = code, status, message, details
+	return err
+}
+
+func TestRetryDelay(t *testing.T) {
+	const base, rateLimit, overloaded = 12 * time.Second, time.Minute, 5 * time.Minute
+	tests := []struct {
+		name    string
+		err     *APIError
+		attempt int
+		want    time.Duration
+	}{
+		{"server delay wins", &APIError{class: ErrOverloaded, RetryDelay: 20 * time.Second}, 1, 20 * time.Second},
+		{"overloaded default", &APIError{class: ErrOverloaded}, 1, overloaded},
+		{"rate limit default", &APIError{class: ErrRateLimited}, 2, rateLimit},
+		{"server error grows", &APIError{StatusCode: 502}, 3, 36 * time.Second},
+		{"server error capped", &APIError{StatusCode: 502}, 9, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryDelay(tt.err, tt.attempt, base, rateLimit, overloaded); got != tt.want {
+				t.Errorf("retryDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategorizer_DailyQuotaIsTyped(t *testing.T) {
+	mockClient := &mockGeminiClient{
+		generateTextFunc: func(ctx context.Context, model string, prompt string) (string, error) {
+			return "", &APIError{StatusCode: 429, class: ErrDailyQuota, err: errors.New("quota")}
+		},
+	}
+	categorizer := NewCategorizer(mockClient, config.Gemini{}, config.Pipeline{Categories: []string{"Общество"}})
+	_, err := categorizer.Categorize(context.Background(), []news.ArticleRaw{{ID: "article-1", Title: "Tin"}})
+	if !errors.Is(err, ErrDailyQuota) {
+		t.Errorf("Categorize() error = %v, want ErrDailyQuota", err)
+	}
+}
@@ -0,0 +1,212 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// charsPerToken — грубая оценка длины токена для вьетнамского и русского текста.
+// Оценка нужна только до ответа: после него лимитер учитывает фактический расход из UsageMetadata.
+const charsPerToken = 3
+
+// Этапы пайплайна, от имени которых идут запросы; попадают в журнал квоты.
+const (
+	StageCategorization = config.StageCategorization
+	StageRanking        = config.StageRanking
+	StageSummary        = config.StageSummary
+)
+
+// quotaLocation — дневные квоты Gemini API сбрасываются в полночь по тихоокеанскому времени.
+var quotaLocation = loadQuotaLocation()
+
+func loadQuotaLocation() *time.Location {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		// Без базы часовых поясов считаем по зимнему времени: ошибка не больше часа раз в полгода
+		return time.FixedZone("PST", -8*60*60)
+	}
+	return loc
+}
+
+type stageKey struct{}
+
+// WithStage помечает запросы, сделанные с ctx, этапом пайплайна для журнала квоты.
+func WithStage(ctx context.Context, stage string) context.Context {
+	return context.WithValue(ctx, stageKey{}, stage)
+}
+
+func stageFrom(ctx context.Context) string {
+	stage, _ := ctx.Value(stageKey{}).(string)
+	return stage
+}
+
+// Limiter — общий для всех этапов лимитер запросов к Gemini: token bucket по запросам (RPM)
+// и токенам (TPM) плюс журнал запросов за сутки (RPD). Корзины стартуют полными,
+// поэтому пара небольших запросов подряд уходит без пауз, а ждать приходится только при
+// реальном приближении к лимиту. Журнал переживает запуск через Restore/Ledger.
+type Limiter struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	requests tokenBucket
+	tokens   tokenBucket
+	rpd      int
+	ledger   news.QuotaLedger
+}
+
+// tokenBucket пополняется равномерно до capacity за минуту. capacity == 0 — лимита нет.
+type tokenBucket struct {
+	capacity float64
+	level    float64
+	updated  time.Time
+}
+
+// NewLimiter создаёт лимитер. Если clk == nil, используются системные часы.
+func NewLimiter(limits config.GeminiLimits, clk clock.Clock) *Limiter {
+	clk = clock.OrSystem(clk)
+	now := clk.Now()
+	return &Limiter{
+		clock:    clk,
+		requests: tokenBucket{capacity: float64(limits.RPM), level: float64(limits.RPM), updated: now},
+		tokens:   tokenBucket{capacity: float64(limits.TPM), level: float64(limits.TPM), updated: now},
+		rpd:      limits.RPD,
+	}
+}
+
+// Wait ждёт, пока запрос размером tokens уложится в RPM и TPM, списывает его из корзин
+// и записывает в журнал квоты вместе с моделью и этапом из ctx (см. WithStage).
+// Если дневной лимит уже израсходован, сразу возвращает ErrDailyQuota: запрос всё равно отклонят.
+func (l *Limiter) Wait(ctx context.Context, model string, tokens int) error {
+	for {
+		l.mu.Lock()
+		now := l.clock.Now()
+		l.rollover(now)
+		if used := len(l.ledger.Requests); l.rpd > 0 && used >= l.rpd {
+			l.mu.Unlock()
+			return fmt.Errorf("%w: %d of %d requests used on %s", ErrDailyQuota, used, l.rpd, l.ledger.Day)
+		}
+		l.requests.refill(now)
+		l.tokens.refill(now)
+		// Запрос больше всего TPM пропускаем при полной корзине, иначе он не уйдёт никогда
+		need := float64(tokens)
+		if l.tokens.capacity > 0 && need > l.tokens.capacity {
+			need = l.tokens.capacity
+		}
+		wait := max(l.requests.waitFor(1), l.tokens.waitFor(need))
+		if wait <= 0 {
+			l.requests.take(1)
+			l.tokens.take(need)
+			l.ledger.Requests = append(l.ledger.Requests, news.QuotaRequest{
+				Model:           model,
+				Stage:           stageFrom(ctx),
+				EstimatedTokens: tokens,
+				At:              now,
+			})
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		log.Printf("Gemini rate limiter: waiting %v (RPM/TPM)...", wait.Round(time.Second))
+		if err := l.clock.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Record поправляет корзину токенов на разницу между оценкой и фактическим расходом запроса.
+func (l *Limiter) Record(estimated, actual int) {
+	if actual <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.take(float64(actual - estimated))
+}
+
+// Used возвращает число запросов за текущие сутки квоты, включая прошлые запуски.
+func (l *Limiter) Used() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollover(l.clock.Now())
+	return len(l.ledger.Requests)
+}
+
+// Remaining возвращает, сколько запросов осталось до дневного лимита; -1 — лимит не задан.
+func (l *Limiter) Remaining() int {
+	if l.rpd <= 0 {
+		return -1
+	}
+	return max(0, l.rpd-l.Used())
+}
+
+// Restore подхватывает журнал квоты, сохранённый прошлыми запусками.
+// Журнал за прошедшие сутки отбрасывается при первом обращении.
+func (l *Limiter) Restore(ledger news.QuotaLedger) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ledger = news.QuotaLedger{Day: ledger.Day, Requests: append([]news.QuotaRequest(nil), ledger.Requests...)}
+}
+
+// Ledger возвращает копию журнала квоты для сохранения в state.
+func (l *Limiter) Ledger() news.QuotaLedger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollover(l.clock.Now())
+	return news.QuotaLedger{Day: l.ledger.Day, Requests: append([]news.QuotaRequest(nil), l.ledger.Requests...)}
+}
+
+// rollover начинает новый журнал, если сутки квоты сменились. Вызывается под l.mu.
+func (l *Limiter) rollover(now time.Time) {
+	day := quotaDay(now)
+	if l.ledger.Day != day {
+		l.ledger = news.QuotaLedger{Day: day}
+	}
+}
+
+// quotaDay возвращает сутки квоты Gemini, к которым относится момент t.
+func quotaDay(t time.Time) string {
+	return t.In(quotaLocation).Format("2006-01-02")
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if b.capacity == 0 {
+		return
+	}
+	elapsed := now.Sub(b.updated)
+	if elapsed <= 0 {
+		return
+	}
+	b.level = min(b.capacity, b.level+b.capacity*elapsed.Minutes())
+	b.updated = now
+}
+
+// waitFor возвращает, сколько ждать, пока в корзине наберётся n.
+func (b *tokenBucket) waitFor(n float64) time.Duration {
+	if b.capacity == 0 || b.level >= n {
+		return 0
+	}
+	// Округляем вверх до миллисекунды, чтобы после паузы корзины гарантированно хватило
+	wait := time.Duration((n - b.level) / b.capacity * float64(time.Minute))
+	return wait.Truncate(time.Millisecond) + time.Millisecond
+}
+
+// take списывает n из корзины; уровень может уйти в минус, если фактический расход больше оценки.
+func (b *tokenBucket) take(n float64) {
+	if b.capacity == 0 {
+		return
+	}
+	b.level -= n
+}
+
+// estimateTokens оценивает размер запроса по длине промпта.
+func estimateTokens(prompt string) int {
+	return utf8.RuneCountInString(prompt)/charsPerToken + 1
+}
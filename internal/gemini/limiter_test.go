@@ -0,0 +1,228 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/app"
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/state"
+)
+
+// Пайплайн получает журнал квоты через Client.Limiter().
+var _ app.GeminiBudget = (*Limiter)(nil)
+
+func TestLimiter_Wait(t *testing.T) {
+	start := time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		limits    config.GeminiLimits
+		tokens    []int // Размер каждого запроса по порядку
+		wantSlept []time.Duration
+		wantErr   error
+	}{
+		{
+			name:   "small requests within burst do not wait",
+			limits: config.GeminiLimits{RPM: 5, TPM: 250000},
+			tokens: []int{1000, 1000},
+		},
+		{
+			name:      "third request waits for RPM refill",
+			limits:    config.GeminiLimits{RPM: 2},
+			tokens:    []int{10, 10, 10},
+			wantSlept: []time.Duration{30*time.Second + time.Millisecond},
+		},
+		{
+			name:      "large request waits for TPM refill",
+			limits:    config.GeminiLimits{RPM: 10, TPM: 6000},
+			tokens:    []int{5000, 4000},
+			wantSlept: []time.Duration{30*time.Second + time.Millisecond},
+		},
+		{
+			name:   "request larger than TPM goes with full bucket",
+			limits: config.GeminiLimits{TPM: 1000},
+			tokens: []int{5000},
+		},
+		{
+			name:    "RPD exhausted returns daily quota",
+			limits:  config.GeminiLimits{RPD: 2},
+			tokens:  []int{10, 10, 10},
+			wantErr: ErrDailyQuota,
+		},
+		{
+			name:   "zero limits disable limiter",
+			tokens: []int{1 << 20, 1 << 20, 1 << 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(start)
+			limiter := NewLimiter(tt.limits, clk)
+
+			var err error
+			for _, n := range tt.tokens {
+				if err = limiter.Wait(context.Background(), "models/gemini-2.5-flash", n); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Wait() error = %v, want %v", err, tt.wantErr)
+			}
+			slept := clk.Slept()
+			if len(slept) != len(tt.wantSlept) {
+				t.Fatalf("Slept() = %v, want %v", slept, tt.wantSlept)
+			}
+			for i := range slept {
+				if slept[i] != tt.wantSlept[i] {
+					t.Errorf("Slept()[%d] = %v, want %v", i, slept[i], tt.wantSlept[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_RecordActualUsage(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(config.GeminiLimits{TPM: 6000}, clk)
+
+	// Оценили в 1000 токенов, а модель потратила 4000: следующий запрос на 3000 должен подождать
+	if err := limiter.Wait(context.Background(), "models/gemini-2.5-flash", 1000); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	limiter.Record(1000, 4000)
+	if err := limiter.Wait(context.Background(), "models/gemini-2.5-flash", 3000); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	if slept := clk.Slept(); len(slept) != 1 || slept[0] != 10*time.Second+time.Millisecond {
+		t.Errorf("Slept() = %v, want one 10s pause", slept)
+	}
+	if got := limiter.Used(); got != 2 {
+		t.Errorf("Used() = %d, want 2", got)
+	}
+}
+
+func TestLimiter_WaitCanceled(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(config.GeminiLimits{RPM: 1}, clk)
+	if err := limiter.Wait(context.Background(), "models/gemini-2.5-flash", 1); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, "models/gemini-2.5-flash", 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestLimiter_Ledger(t *testing.T) {
+	// 06:00 UTC 15 января — ещё 14 января по тихоокеанскому времени
+	clk := clock.NewFake(time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(config.GeminiLimits{RPD: 3}, clk)
+	limiter.Restore(news.QuotaLedger{Day: "2026-01-14", Requests: []news.QuotaRequest{{Model: "m", Stage: StageCategorization}}})
+
+	if got := limiter.Remaining(); got != 2 {
+		t.Fatalf("Remaining() = %d, want 2", got)
+	}
+	ctx := WithStage(context.Background(), StageSummary)
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(ctx, "models/gemini-2.5-flash", 100); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	if err := limiter.Wait(ctx, "models/gemini-2.5-flash", 100); !errors.Is(err, ErrDailyQuota) {
+		t.Fatalf("Wait() error = %v, want ErrDailyQuota", err)
+	}
+
+	ledger := limiter.Ledger()
+	if len(ledger.Requests) != 3 {
+		t.Fatalf("Ledger() has %d requests, want 3", len(ledger.Requests))
+	}
+	last := ledger.Requests[2]
+	if last.Model != "models/gemini-2.5-flash" || last.Stage != StageSummary || last.EstimatedTokens != 100 || !last.At.Equal(clk.Now()) {
+		t.Errorf("last request = %+v", last)
+	}
+
+	// В полночь по тихоокеанскому времени (08:00 UTC) квота обнуляется
+	clk.Advance(2 * time.Hour)
+	if got := limiter.Remaining(); got != 3 {
+		t.Errorf("Remaining() after reset = %d, want 3", got)
+	}
+	if ledger := limiter.Ledger(); ledger.Day != "2026-01-15" || len(ledger.Requests) != 0 {
+		t.Errorf("Ledger() after reset = %+v, want empty ledger for 2026-01-15", ledger)
+	}
+}
+
+func TestLimiter_LedgerPersistsAcrossRuns(t *testing.T) {
+	ctx := WithStage(context.Background(), StageCategorization)
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	limits := config.GeminiLimits{RPD: 3, TPM: 6000}
+
+	// run запускает пайплайн в момент at: журнал из state, requests запросов, журнал обратно в state
+	run := func(at time.Time, requests int) (*Limiter, error) {
+		t.Helper()
+		loaded, err := store.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		limiter := NewLimiter(limits, clock.NewFake(at))
+		limiter.Restore(loaded.GeminiQuota)
+
+		var waitErr error
+		for i := 0; i < requests && waitErr == nil; i++ {
+			if waitErr = limiter.Wait(ctx, "models/gemini-2.5-flash", 100); waitErr == nil {
+				limiter.Record(100, 250)
+			}
+		}
+		loaded.GeminiQuota = limiter.Ledger()
+		if err := store.Save(context.Background(), loaded); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		return limiter, waitErr
+	}
+
+	// 06:00 и 07:30 UTC 15 января — ещё 14 января по тихоокеанскому времени
+	first, err := run(time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC), 2)
+	if err != nil {
+		t.Fatalf("first run error = %v", err)
+	}
+	if got := first.Remaining(); got != 1 {
+		t.Fatalf("Remaining() after first run = %d, want 1", got)
+	}
+
+	second, err := run(time.Date(2026, 1, 15, 7, 30, 0, 0, time.UTC), 2)
+	if !errors.Is(err, ErrDailyQuota) {
+		t.Fatalf("second run error = %v, want ErrDailyQuota after the restored ledger", err)
+	}
+	if got := second.Remaining(); got != 0 {
+		t.Errorf("Remaining() after second run = %d, want 0", got)
+	}
+
+	// 08:30 UTC — уже 15 января в Калифорнии: сохранённый журнал за прошлые сутки отбрасывается
+	third, err := run(time.Date(2026, 1, 15, 8, 30, 0, 0, time.UTC), 1)
+	if err != nil {
+		t.Fatalf("third run error = %v", err)
+	}
+	if got := third.Remaining(); got != 2 {
+		t.Errorf("Remaining() after rollover = %d, want 2", got)
+	}
+
+	saved, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if saved.GeminiQuota.Day != "2026-01-15" || len(saved.GeminiQuota.Requests) != 1 {
+		t.Fatalf("saved ledger = %+v, want one request on 2026-01-15", saved.GeminiQuota)
+	}
+	if got := saved.GeminiQuota.Requests[0]; got.Stage != StageCategorization || got.EstimatedTokens != 100 {
+		t.Errorf("saved request = %+v", got)
+	}
+}
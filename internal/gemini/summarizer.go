@@ -3,24 +3,26 @@ package gemini
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
-	"time"
 
 	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/llm"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
 
-// Summarizer реализует app.Summarizer, используя Gemini API для создания кратких резюме новостей.
+// Summarizer реализует app.Summarizer: создаёт краткие резюме новостей через языковую модель (по умолчанию Gemini, см. config.LLM).
 type Summarizer struct {
-	client    GeminiClient
+	client    llm.Client
 	cfg       config.Gemini
 	batchSize int
+	maxBatch  int // Потолок batchSize при планировании под остаток квоты
 }
 
 // NewSummarizer создаёт новый экземпляр суммаризатора.
-func NewSummarizer(client GeminiClient, geminiCfg config.Gemini) *Summarizer {
+func NewSummarizer(client llm.Client, geminiCfg config.Gemini) *Summarizer {
 	batchSize := geminiCfg.BatchSizeSummary
 	if batchSize <= 0 {
 		batchSize = 5 // дефолтное значение
@@ -29,6 +31,19 @@ func NewSummarizer(client GeminiClient, geminiCfg config.Gemini) *Summarizer {
 		client:    client,
 		cfg:       geminiCfg,
 		batchSize: batchSize,
+		maxBatch:  max(batchSize, geminiCfg.MaxBatchSizeSummary),
+	}
+}
+
+// BatchLimits реализует app.BatchResizer: размер батча из конфига и допустимый потолок.
+func (s *Summarizer) BatchLimits() (size, maxSize int) {
+	return s.batchSize, s.maxBatch
+}
+
+// SetBatchSize реализует app.BatchResizer.
+func (s *Summarizer) SetBatchSize(size int) {
+	if size > 0 {
+		s.batchSize = size
 	}
 }
 
@@ -37,6 +52,7 @@ func (s *Summarizer) Summarize(ctx context.Context, articles []news.CategorizedA
 	if len(articles) == 0 {
 		return nil, nil
 	}
+	ctx = WithStage(ctx, StageSummary)
 
 	var results []news.DigestEntry
 	articleMap := make(map[string]news.CategorizedArticle, len(articles))
@@ -54,9 +70,7 @@ func (s *Summarizer) Summarize(ctx context.Context, articles []news.CategorizedA
 		log.Printf("Summarizing %d articles in %d batches (batch size: %d)", len(articles), totalBatches, s.batchSize)
 	}
 	
-	// Минимальная задержка между запросами для соблюдения RPM=5 (12 секунд между запросами)
-	const minDelayBetweenRequests = 12 * time.Second
-	lastRequestTime := time.Now()
+	// Паузы для RPM/TPM выдерживает лимитер клиента
 	requestCount := 0
 	
 	for i := 0; i < len(articles); i += effectiveBatchSize {
@@ -65,18 +79,6 @@ func (s *Summarizer) Summarize(ctx context.Context, articles []news.CategorizedA
 			end = len(articles)
 		}
 
-		// Соблюдаем задержку между запросами
-		elapsed := time.Since(lastRequestTime)
-		if elapsed < minDelayBetweenRequests && requestCount > 0 {
-			waitTime := minDelayBetweenRequests - elapsed
-			log.Printf("Waiting %v before next Gemini API request (RPM limit)...", waitTime)
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(waitTime):
-			}
-		}
-
 		batch := articles[i:end]
 		requestCount++
 		totalBatches := (len(articles) + effectiveBatchSize - 1) / effectiveBatchSize
@@ -88,7 +90,6 @@ func (s *Summarizer) Summarize(ctx context.Context, articles []news.CategorizedA
 		}
 
 		results = append(results, batchResults...)
-		lastRequestTime = time.Now()
 	}
 	
 	log.Printf("Summarization complete: %d articles summarized in %d API requests", len(results), requestCount)
@@ -104,6 +105,7 @@ func (s *Summarizer) summarizeBatch(ctx context.Context, articles []news.Categor
 			ID:      catArticle.Article.ID,
 			Title:   catArticle.Article.Title,
 			Content: catArticle.Article.RawContent,
+			Soften:  catArticle.SafetyAction == config.SafetySoften,
 		})
 	}
 
@@ -115,29 +117,14 @@ func (s *Summarizer) summarizeBatch(ctx context.Context, articles []news.Categor
 	// Формируем промпт согласно docs/prompting.md
 	prompt := s.buildPrompt(string(inputJSON))
 
-	// Вызываем Gemini API
-	responseText, err := s.client.GenerateText(ctx, s.cfg.ModelSummary, prompt)
-	if err != nil {
-		// Проверяем, является ли это ошибкой квоты (RPD)
-		errStr := err.Error()
-		if strings.Contains(strings.ToLower(errStr), "quota") || strings.Contains(strings.ToLower(errStr), "rpd") {
-			log.Printf("CRITICAL: Gemini API quota exceeded during summarization. Stopping batch processing.")
-			return nil, fmt.Errorf("gemini API quota exceeded (RPD limit): %w", err)
-		}
-		return nil, fmt.Errorf("generate text: %w", err)
-	}
-
-	// Парсим JSON ответ
+	// Вызываем Gemini API: ответ приходит JSON-массивом по схеме
 	var summaries []summaryResponse
-	if err := json.Unmarshal([]byte(responseText), &summaries); err != nil {
-		// Пытаемся извлечь JSON из текста, если модель добавила лишнее
-		cleaned := extractJSON(responseText)
-		if cleaned == "" {
-			return nil, fmt.Errorf("unmarshal response: %w (raw: %s)", err, responseText)
-		}
-		if err := json.Unmarshal([]byte(cleaned), &summaries); err != nil {
-			return nil, fmt.Errorf("unmarshal cleaned response: %w (raw: %s)", err, responseText)
+	if err := s.client.GenerateJSON(ctx, s.cfg.ModelSummary, prompt, summarySchema, &summaries); err != nil {
+		if errors.Is(err, ErrDailyQuota) {
+			log.Printf("CRITICAL: Gemini API quota exceeded during summarization. Stopping batch processing.")
+			return nil, err
 		}
+		return nil, fmt.Errorf("generate summaries: %w", err)
 	}
 
 	// Формируем результат - преобразуем CategorizedArticle → DigestEntry
@@ -172,14 +159,20 @@ func (s *Summarizer) summarizeBatch(ctx context.Context, articles []news.Categor
 		}
 
 		results = append(results, news.DigestEntry{
-			ID:          catArticle.Article.ID,
-			Category:    catArticle.Category,
-			Title:       catArticle.Article.Title,
-			TitleRU:     data.TitleRU,
-			URL:         catArticle.Article.URL,
-			SummaryRU:   data.SummaryRU,
-			Source:      catArticle.Article.Source,
-			PublishedAt: catArticle.Article.PublishedAt,
+			ID:            catArticle.Article.ID,
+			Category:      catArticle.Category,
+			Title:         catArticle.Article.Title,
+			TitleRU:       data.TitleRU,
+			URL:           catArticle.Article.URL,
+			CanonicalURL:  catArticle.Article.CanonicalURL,
+			PageCanonical: catArticle.Article.PageCanonicalURL(),
+			SummaryRU:     data.SummaryRU,
+			Source:        catArticle.Article.Source,
+			PublishedAt:   catArticle.Article.PublishedAt,
+			ImageURL:      catArticle.Article.ImageURL(),
+			Author:        catArticle.Article.Author(),
+
+			ContentWarning: catArticle.SafetyAction == config.SafetyWarn,
 		})
 	}
 
@@ -193,14 +186,21 @@ func (s *Summarizer) buildPrompt(inputJSON string) string {
 1. Переведи заголовок на русский язык (title_ru)
 2. Сделай краткое резюме на русском языке длиной 1–2 предложения (summary_ru)
 Используй нейтральный, информативный стиль, без оценочных суждений и кликовбейта. Не придумывай факты, которых нет в тексте.
-Верни результат ТОЛЬКО в виде валидного JSON-массива без markdown блоков, без дополнительных комментариев, без обрамления в code blocks.
-Формат (raw JSON):
+Если у новости есть поле soften: true, пиши особенно сдержанно: сообщи, что произошло, но без натуралистичных подробностей насилия, травм, способов гибели и самоубийства.
+Верни JSON-массив, по одному объекту на каждую новость:
 [{"id": "<id новости>", "title_ru": "<переведенный заголовок на русском>", "summary_ru": "<краткое резюме на русском>"}, ...]
 
 Входные данные:
 %s`, inputJSON)
 }
 
+// summarySchema описывает ответ суммаризации.
+var summarySchema = llm.ArrayOf(map[string]*llm.Schema{
+	"id":         {Type: llm.TypeString},
+	"title_ru":   {Type: llm.TypeString},
+	"summary_ru": {Type: llm.TypeString},
+})
+
 type summaryResponse struct {
 	ID        string `json:"id"`
 	TitleRU   string `json:"title_ru"`   // Переведенный заголовок
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Failover отправляет запрос основному провайдеру, а если тот ответил ошибкой
+// (недоступен, перегружен, исчерпана квота, ответ не по схеме) — запасному со своей моделью.
+// Основной провайдер получает контекст с WithFailFast и не ждёт долгих повторов.
+type Failover struct {
+	primary       Client
+	fallback      Client
+	fallbackModel string
+}
+
+// Убеждаемся, что Failover реализует интерфейс Client.
+var _ Client = (*Failover)(nil)
+
+// NewFailover создаёт провайдера с переключением на fallback. fallbackModel передаётся
+// запасному провайдеру вместо модели основного.
+func NewFailover(primary, fallback Client, fallbackModel string) *Failover {
+	return &Failover{primary: primary, fallback: fallback, fallbackModel: fallbackModel}
+}
+
+// GenerateText реализует Client.
+func (f *Failover) GenerateText(ctx context.Context, model string, prompt string) (string, error) {
+	text, err := f.primary.GenerateText(WithFailFast(ctx), model, prompt)
+	if !f.shouldFailover(ctx, model, err) {
+		return text, err
+	}
+	text, fallbackErr := f.fallback.GenerateText(ctx, f.fallbackModel, prompt)
+	return text, f.join(err, fallbackErr)
+}
+
+// GenerateJSON реализует Client.
+func (f *Failover) GenerateJSON(ctx context.Context, model string, prompt string, schema *Schema, out any) error {
+	err := f.primary.GenerateJSON(WithFailFast(ctx), model, prompt, schema, out)
+	if !f.shouldFailover(ctx, model, err) {
+		return err
+	}
+	return f.join(err, f.fallback.GenerateJSON(ctx, f.fallbackModel, prompt, schema, out))
+}
+
+// join оставляет ошибку основного провайдера видимой для errors.Is, если запасной тоже не ответил:
+// этапы по ней узнают, например, об исчерпанной квоте Gemini.
+func (f *Failover) join(primaryErr, fallbackErr error) error {
+	if fallbackErr == nil {
+		return nil
+	}
+	return fmt.Errorf("%w; fallback %s: %w", primaryErr, f.fallbackModel, fallbackErr)
+}
+
+// shouldFailover решает, переходить ли к запасному провайдеру. Отмена запуска не повод для этого.
+func (f *Failover) shouldFailover(ctx context.Context, model string, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	log.Printf("WARNING: LLM provider failed for %s, falling back to %s: %v", model, f.fallbackModel, err)
+	return true
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubClient отвечает заранее заданным текстом или ошибкой и запоминает модели запросов.
+type stubClient struct {
+	text     string
+	err      error
+	models   []string
+	failFast []bool // FailFast(ctx) каждого запроса
+}
+
+func (c *stubClient) GenerateText(ctx context.Context, model string, prompt string) (string, error) {
+	c.models = append(c.models, model)
+	c.failFast = append(c.failFast, FailFast(ctx))
+	return c.text, c.err
+}
+
+func (c *stubClient) GenerateJSON(ctx context.Context, model string, prompt string, schema *Schema, out any) error {
+	text, err := c.GenerateText(ctx, model, prompt)
+	if err != nil {
+		return err
+	}
+	return Decode(model, text, schema, out)
+}
+
+var errQuota = errors.New("gemini daily quota exhausted")
+
+func TestFailover(t *testing.T) {
+	schema := ArrayOf(map[string]*Schema{"id": {Type: TypeString}})
+
+	tests := []struct {
+		name         string
+		primary      *stubClient
+		fallbackErr  error
+		canceled     bool
+		wantFallback bool
+		wantErr      error
+	}{
+		{
+			name:    "primary succeeds",
+			primary: &stubClient{text: `[{"id": "a1"}]`},
+		},
+		{
+			name:         "primary is down",
+			primary:      &stubClient{err: errors.New("gemini model overloaded")},
+			wantFallback: true,
+		},
+		{
+			name:         "primary breaks schema",
+			primary:      &stubClient{text: `[{"title": "a1"}]`},
+			wantFallback: true,
+		},
+		{
+			name:         "both fail, primary error stays visible",
+			primary:      &stubClient{err: errQuota},
+			fallbackErr:  errors.New("connection refused"),
+			wantFallback: true,
+			wantErr:      errQuota,
+		},
+		{
+			name:     "canceled run does not fail over",
+			primary:  &stubClient{err: context.Canceled},
+			canceled: true,
+			wantErr:  context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fallback := &stubClient{text: `[{"id": "local"}]`, err: tt.fallbackErr}
+			client := NewFailover(tt.primary, fallback, "qwen2.5:7b")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.canceled {
+				cancel()
+			}
+
+			var out []struct {
+				ID string `json:"id"`
+			}
+			err := client.GenerateJSON(ctx, "models/gemini-2.5-flash", "prompt", schema, &out)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenerateJSON() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(tt.primary.models) != 1 || tt.primary.models[0] != "models/gemini-2.5-flash" {
+				t.Errorf("primary models = %v", tt.primary.models)
+			}
+			// Основной провайдер не должен пережидать перегрузку, раз есть запасной; запасному ждать можно
+			if !tt.primary.failFast[0] {
+				t.Error("primary request should be marked FailFast")
+			}
+			if len(fallback.failFast) > 0 && fallback.failFast[0] {
+				t.Error("fallback request should not be marked FailFast")
+			}
+			if gotFallback := len(fallback.models) > 0; gotFallback != tt.wantFallback {
+				t.Fatalf("fallback called = %v, want %v", gotFallback, tt.wantFallback)
+			}
+			if tt.wantFallback && tt.wantErr == nil && (fallback.models[0] != "qwen2.5:7b" || len(out) != 1 || out[0].ID != "local") {
+				t.Errorf("fallback models = %v, out = %+v", fallback.models, out)
+			}
+		})
+	}
+}
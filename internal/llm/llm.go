@@ -0,0 +1,85 @@
+// Package llm описывает провайдера языковой модели, общий для всех этапов пайплайна,
+// и реализации, не привязанные к конкретному SDK: OpenAI-совместимый API и переключение
+// на запасного провайдера. Клиент Gemini находится в пакете gemini.
+package llm
+
+import "context"
+
+// Client — провайдер языковой модели. Этапы пайплайна работают только с этим интерфейсом,
+// поэтому провайдера можно выбрать для каждого этапа отдельно (см. config.LLM).
+type Client interface {
+	GenerateText(ctx context.Context, model string, prompt string) (string, error)
+	// GenerateJSON запрашивает ответ в JSON по схеме schema и декодирует его в out.
+	// Если ответ не соответствует схеме, возвращается *SchemaError (errors.Is(err, ErrSchemaMismatch)).
+	GenerateJSON(ctx context.Context, model string, prompt string, schema *Schema, out any) error
+}
+
+type failFastKey struct{}
+
+// WithFailFast помечает запрос, для которого настроен запасной провайдер: клиент не ждёт долгих
+// повторов (перегрузка модели, ошибки сервера), а сразу возвращает ошибку, чтобы Failover
+// передал запрос запасному провайдеру.
+func WithFailFast(ctx context.Context) context.Context {
+	return context.WithValue(ctx, failFastKey{}, true)
+}
+
+// FailFast сообщает, что повторять запрос после временной ошибки не нужно (см. WithFailFast).
+func FailFast(ctx context.Context) bool {
+	failFast, _ := ctx.Value(failFastKey{}).(bool)
+	return failFast
+}
+
+// Type — тип значения в схеме ответа.
+type Type string
+
+const (
+	TypeObject  Type = "object"
+	TypeArray   Type = "array"
+	TypeString  Type = "string"
+	TypeNumber  Type = "number"
+	TypeInteger Type = "integer"
+	TypeBoolean Type = "boolean"
+)
+
+// Schema — схема ответа модели: подмножество JSON Schema, которое понимают и Gemini API,
+// и OpenAI-совместимые серверы (OpenAI, llama.cpp, Ollama).
+type Schema struct {
+	Type        Type
+	Description string
+	Enum        []string
+	Items       *Schema
+	Nullable    bool
+	Properties  map[string]*Schema
+	Required    []string
+}
+
+// JSONSchema возвращает схему в формате JSON Schema для response_format.
+func (s *Schema) JSONSchema() map[string]any {
+	if s == nil {
+		return nil
+	}
+	result := map[string]any{"type": string(s.Type)}
+	if s.Nullable {
+		result["type"] = []string{string(s.Type), "null"}
+	}
+	if s.Description != "" {
+		result["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		result["enum"] = s.Enum
+	}
+	if s.Items != nil {
+		result["items"] = s.Items.JSONSchema()
+	}
+	if len(s.Properties) > 0 {
+		properties := make(map[string]any, len(s.Properties))
+		for name, property := range s.Properties {
+			properties[name] = property.JSONSchema()
+		}
+		result["properties"] = properties
+	}
+	if len(s.Required) > 0 {
+		result["required"] = s.Required
+	}
+	return result
+}
@@ -0,0 +1,231 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+)
+
+// defaultOpenAITimeout — локальная модель на CPU отвечает на батч из десятков статей минутами.
+const defaultOpenAITimeout = 5 * time.Minute
+
+// maxErrorBody — сколько байт тела ответа с ошибкой попадает в текст ошибки.
+const maxErrorBody = 512
+
+// OpenAIConfig описывает OpenAI-совместимый endpoint chat completions.
+type OpenAIConfig struct {
+	BaseURL string        // Например https://api.openai.com/v1 или http://localhost:11434/v1 (Ollama)
+	APIKey  string        // Пусто — без заголовка Authorization (локальные llama.cpp и Ollama)
+	Timeout time.Duration // 0 — defaultOpenAITimeout
+}
+
+// OpenAI реализует Client для любого сервера с OpenAI-совместимым /chat/completions:
+// OpenAI, llama.cpp server, Ollama и другие.
+type OpenAI struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+	clock   clock.Clock // Паузы между повторами запросов
+}
+
+// Убеждаемся, что OpenAI реализует интерфейс Client.
+var _ Client = (*OpenAI)(nil)
+
+// StatusError — ответ OpenAI-совместимого API с кодом ошибки.
+type StatusError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // Пауза из заголовка Retry-After; 0 — сервер её не указал
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("chat completions status %d: %s", e.StatusCode, e.Message)
+}
+
+// temporary сообщает, что запрос стоит повторить после паузы.
+func (e *StatusError) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// NewOpenAI создаёт клиента OpenAI-совместимого API. Если clk == nil, используются системные часы.
+func NewOpenAI(cfg OpenAIConfig, clk clock.Clock) *OpenAI {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultOpenAITimeout
+	}
+	return &OpenAI{
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:  cfg.APIKey,
+		client:  &http.Client{Timeout: timeout},
+		clock:   clock.OrSystem(clk),
+	}
+}
+
+// GenerateText реализует Client.
+func (c *OpenAI) GenerateText(ctx context.Context, model string, prompt string) (string, error) {
+	return c.complete(ctx, model, prompt, nil)
+}
+
+// GenerateJSON реализует Client через response_format с JSON Schema.
+// Структурированный ответ OpenAI должен быть объектом, поэтому схему-массив
+// оборачиваем в {"items": [...]} и разворачиваем ответ обратно.
+func (c *OpenAI) GenerateJSON(ctx context.Context, model string, prompt string, schema *Schema, out any) error {
+	wrapped := schema
+	if schema != nil && schema.Type == TypeArray {
+		wrapped = &Schema{
+			Type:       TypeObject,
+			Properties: map[string]*Schema{"items": schema},
+			Required:   []string{"items"},
+		}
+	}
+
+	text, err := c.complete(ctx, model, prompt, &responseFormat{
+		Type:       "json_schema",
+		JSONSchema: &jsonSchemaFormat{Name: "response", Schema: wrapped.JSONSchema()},
+	})
+	if err != nil {
+		return err
+	}
+	if wrapped == schema {
+		return Decode(model, text, schema, out)
+	}
+
+	var envelope struct {
+		Items json.RawMessage `json:"items"`
+	}
+	if err := Decode(model, text, wrapped, &envelope); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(envelope.Items, out); err != nil {
+		return &SchemaError{Model: model, Path: "$.items", Reason: err.Error(), Raw: text}
+	}
+	return nil
+}
+
+// complete выполняет запрос с повторами при 429 и ошибках сервера (без повторов при FailFast).
+// Сетевые ошибки не повторяются: недоступный локальный сервер лучше сразу отдать запасному провайдеру.
+func (c *OpenAI) complete(ctx context.Context, model string, prompt string, format *responseFormat) (string, error) {
+	const maxRetries = 3
+	const baseDelay = 10 * time.Second
+
+	body, err := json.Marshal(chatRequest{
+		Model:          model,
+		Messages:       []chatMessage{{Role: "user", Content: prompt}},
+		ResponseFormat: format,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal chat request: %w", err)
+	}
+
+	var lastErr *StatusError
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := lastErr.RetryAfter
+			if delay <= 0 {
+				delay = baseDelay * time.Duration(attempt)
+			}
+			log.Printf("Retrying chat completions request to %s (attempt %d/%d) after %v: %v", c.baseURL, attempt+1, maxRetries, delay, lastErr)
+			if err := c.clock.Sleep(ctx, delay); err != nil {
+				return "", err
+			}
+		}
+
+		text, err := c.post(ctx, body)
+		if err == nil {
+			return text, nil
+		}
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || !statusErr.temporary() || FailFast(ctx) {
+			return "", fmt.Errorf("%s: %w", model, err)
+		}
+		lastErr = statusErr
+	}
+
+	return "", fmt.Errorf("%s: max retries exceeded: %w", model, lastErr)
+}
+
+// post отправляет один запрос и возвращает текст первого варианта ответа.
+func (c *OpenAI) post(ctx context.Context, body []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Message: errorMessage(data)}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			statusErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return "", statusErr
+	}
+
+	var completion chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", fmt.Errorf("decode chat response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("chat response has no choices")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+// errorMessage достаёт error.message из тела ответа, иначе возвращает тело как есть.
+func errorMessage(data []byte) string {
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Error.Message != "" {
+		return body.Error.Message
+	}
+	return strings.TrimSpace(string(data))
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type responseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *jsonSchemaFormat `json:"json_schema,omitempty"`
+}
+
+type jsonSchemaFormat struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
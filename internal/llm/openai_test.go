@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+)
+
+func TestOpenAI_GenerateJSON(t *testing.T) {
+	schema := ArrayOf(map[string]*Schema{
+		"id":    {Type: TypeString},
+		"score": {Type: TypeNumber},
+	})
+
+	tests := []struct {
+		name       string
+		responses  []func(w http.ResponseWriter) // Ответы сервера по порядку запросов
+		wantLen    int
+		wantStatus int // Код StatusError; 0 — ошибки статуса не ждём
+		wantErr    error
+		wantSlept  []time.Duration
+		failFast   bool // Запрос от Failover: без повторов
+	}{
+		{
+			name: "array schema is wrapped into object",
+			responses: []func(w http.ResponseWriter){
+				completion(`{"items": [{"id": "a1", "score": 7}, {"id": "a2", "score": 3}]}`),
+			},
+			wantLen: 2,
+		},
+		{
+			name: "retry after overload uses Retry-After",
+			responses: []func(w http.ResponseWriter){
+				failure(http.StatusServiceUnavailable, "2"),
+				completion(`{"items": [{"id": "a1", "score": 7}]}`),
+			},
+			wantLen:   1,
+			wantSlept: []time.Duration{2 * time.Second},
+		},
+		{
+			name: "overload is not retried when a fallback is configured",
+			responses: []func(w http.ResponseWriter){
+				failure(http.StatusServiceUnavailable, "2"),
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			failFast:   true,
+		},
+		{
+			name: "bad request is not retried",
+			responses: []func(w http.ResponseWriter){
+				failure(http.StatusBadRequest, ""),
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "response outside schema",
+			responses: []func(w http.ResponseWriter){
+				completion(`{"items": [{"id": "a1"}]}`),
+			},
+			wantErr: ErrSchemaMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/chat/completions" {
+					t.Errorf("path = %s, want /v1/chat/completions", r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+					t.Errorf("Authorization = %q", got)
+				}
+				var req chatRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatalf("decode request: %v", err)
+				}
+				if req.Model != "qwen2.5:7b" || req.ResponseFormat == nil || req.ResponseFormat.JSONSchema.Schema["type"] != "object" {
+					t.Errorf("request = %+v, want model qwen2.5:7b with object schema", req)
+				}
+				tt.responses[calls](w)
+				calls++
+			}))
+			defer server.Close()
+
+			clk := clock.NewFake(time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC))
+			client := NewOpenAI(OpenAIConfig{BaseURL: server.URL + "/v1/", APIKey: "secret"}, clk)
+
+			var out []struct {
+				ID    string  `json:"id"`
+				Score float64 `json:"score"`
+			}
+			ctx := context.Background()
+			if tt.failFast {
+				ctx = WithFailFast(ctx)
+			}
+			err := client.GenerateJSON(ctx, "qwen2.5:7b", "prompt", schema, &out)
+			if tt.wantStatus != 0 || tt.wantErr != nil {
+				var statusErr *StatusError
+				if tt.wantStatus != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus) {
+					t.Fatalf("GenerateJSON() error = %v, want status %d", err, tt.wantStatus)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GenerateJSON() error = %v, want %v", err, tt.wantErr)
+				}
+				if calls != len(tt.responses) {
+					t.Errorf("server got %d requests, want %d", calls, len(tt.responses))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateJSON() error = %v", err)
+			}
+			if len(out) != tt.wantLen {
+				t.Errorf("GenerateJSON() len = %d, want %d", len(out), tt.wantLen)
+			}
+			if slept := clk.Slept(); len(slept) != len(tt.wantSlept) || (len(slept) > 0 && slept[0] != tt.wantSlept[0]) {
+				t.Errorf("Slept() = %v, want %v", slept, tt.wantSlept)
+			}
+		})
+	}
+}
+
+func completion(content string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": content}}},
+		})
+	}
+}
+
+func failure(status int, retryAfter string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"error": {"message": "model is loading"}}`))
+	}
+}
@@ -0,0 +1,171 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxRawInError — сколько символов ответа модели попадает в текст ошибки.
+const maxRawInError = 300
+
+// ErrSchemaMismatch — ответ модели не разбирается как JSON или не соответствует схеме ответа.
+// Подробности — в *SchemaError (errors.As).
+var ErrSchemaMismatch = errors.New("response does not match schema")
+
+// SchemaError описывает, где ответ модели разошёлся со схемой.
+type SchemaError struct {
+	Model  string
+	Path   string // Путь к полю в ответе: $[3].category
+	Reason string
+	Raw    string // Ответ модели целиком
+}
+
+func (e *SchemaError) Error() string {
+	raw := e.Raw
+	if utf8.RuneCountInString(raw) > maxRawInError {
+		raw = string([]rune(raw)[:maxRawInError]) + "..."
+	}
+	return fmt.Sprintf("%s: %s at %s: %s (raw: %s)", e.Model, ErrSchemaMismatch, e.Path, e.Reason, raw)
+}
+
+// Is позволяет проверять ошибку через errors.Is(err, ErrSchemaMismatch).
+func (e *SchemaError) Is(target error) bool {
+	return target == ErrSchemaMismatch
+}
+
+// ArrayOf возвращает схему ответа «массив объектов», в которой все поля обязательны.
+func ArrayOf(properties map[string]*Schema) *Schema {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return &Schema{
+		Type: TypeArray,
+		Items: &Schema{
+			Type:       TypeObject,
+			Properties: properties,
+			Required:   required,
+		},
+	}
+}
+
+// Decode проверяет ответ модели по схеме и декодирует его в out.
+// Схема передаётся в API и ограничивает модель, но проверяем её и на нашей стороне:
+// ответ мог быть обрезан по лимиту токенов, локальные модели соблюдают схему не всегда,
+// а моки в тестах не соблюдают её вовсе.
+func Decode(model, text string, schema *Schema, out any) error {
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return &SchemaError{Model: model, Path: "$", Reason: err.Error(), Raw: text}
+	}
+	if path, reason := validateSchema(value, schema, "$"); reason != "" {
+		return &SchemaError{Model: model, Path: path, Reason: reason, Raw: text}
+	}
+	if err := json.Unmarshal([]byte(text), out); err != nil {
+		return &SchemaError{Model: model, Path: "$", Reason: err.Error(), Raw: text}
+	}
+	return nil
+}
+
+// validateSchema проверяет значение, разобранное encoding/json, по подмножеству схемы,
+// которое используют этапы пайплайна: типы, обязательные поля, enum и nullable.
+// Возвращает путь к первому несоответствию и причину; пустая причина — значение подходит.
+func validateSchema(value any, schema *Schema, path string) (string, string) {
+	if schema == nil {
+		return "", ""
+	}
+	if value == nil {
+		if schema.Nullable {
+			return "", ""
+		}
+		return path, "unexpected null"
+	}
+
+	switch schema.Type {
+	case TypeObject:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return path, fmt.Sprintf("want object, got %s", jsonType(value))
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return path, fmt.Sprintf("missing required field %q", name)
+			}
+		}
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if field, ok := object[name]; ok {
+				if p, reason := validateSchema(field, schema.Properties[name], path+"."+name); reason != "" {
+					return p, reason
+				}
+			}
+		}
+	case TypeArray:
+		items, ok := value.([]any)
+		if !ok {
+			return path, fmt.Sprintf("want array, got %s", jsonType(value))
+		}
+		for i, item := range items {
+			if p, reason := validateSchema(item, schema.Items, fmt.Sprintf("%s[%d]", path, i)); reason != "" {
+				return p, reason
+			}
+		}
+	case TypeString:
+		s, ok := value.(string)
+		if !ok {
+			return path, fmt.Sprintf("want string, got %s", jsonType(value))
+		}
+		if len(schema.Enum) > 0 && !containsString(schema.Enum, s) {
+			return path, fmt.Sprintf("%q is not one of %s", s, strings.Join(schema.Enum, ", "))
+		}
+	case TypeNumber, TypeInteger:
+		n, ok := value.(float64)
+		if !ok {
+			return path, fmt.Sprintf("want number, got %s", jsonType(value))
+		}
+		if schema.Type == TypeInteger && n != math.Trunc(n) {
+			return path, fmt.Sprintf("want integer, got %v", n)
+		}
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return path, fmt.Sprintf("want boolean, got %s", jsonType(value))
+		}
+	}
+	return "", ""
+}
+
+// jsonType называет тип значения, разобранного encoding/json, для текста ошибки.
+func jsonType(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	schema := ArrayOf(map[string]*Schema{
+		"id":    {Type: TypeString},
+		"score": {Type: TypeNumber},
+		"kind":  {Type: TypeString, Enum: []string{"news", "opinion"}},
+	})
+
+	tests := []struct {
+		name     string
+		text     string
+		wantPath string // Пусто — ответ подходит
+		wantLen  int
+	}{
+		{
+			name:    "valid response with brackets inside strings",
+			text:    `[{"id": "a]1[", "score": 7.5, "kind": "news"}, {"id": "b", "score": 3, "kind": "opinion"}]`,
+			wantLen: 2,
+		},
+		{
+			name:     "not json",
+			text:     "```json\n[]\n```",
+			wantPath: "$",
+		},
+		{
+			name:     "missing required field",
+			text:     `[{"id": "a", "score": 7, "kind": "news"}, {"id": "b", "kind": "news"}]`,
+			wantPath: "$[1]",
+		},
+		{
+			name:     "wrong type",
+			text:     `[{"id": "a", "score": "high", "kind": "news"}]`,
+			wantPath: "$[0].score",
+		},
+		{
+			name:     "value outside enum",
+			text:     `[{"id": "a", "score": 1, "kind": "ad"}]`,
+			wantPath: "$[0].kind",
+		},
+		{
+			name:     "object instead of array",
+			text:     `{"id": "a"}`,
+			wantPath: "$",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out []struct {
+				ID    string  `json:"id"`
+				Score float64 `json:"score"`
+			}
+			err := Decode("test-model", tt.text, schema, &out)
+			if tt.wantPath == "" {
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if len(out) != tt.wantLen {
+					t.Errorf("Decode() len = %d, want %d", len(out), tt.wantLen)
+				}
+				return
+			}
+
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) || !errors.Is(err, ErrSchemaMismatch) {
+				t.Fatalf("Decode() error = %v, want *SchemaError", err)
+			}
+			if schemaErr.Path != tt.wantPath || schemaErr.Model != "test-model" {
+				t.Errorf("SchemaError = %+v, want path %s", schemaErr, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package news
+
+import "time"
+
+// Причины решений фильтра (FilterDecision.Reason).
+const (
+	FilterKept         = "kept"
+	FilterTooOld       = "too_old"       // Старше recency_max_hours
+	FilterFutureDate   = "future_date"   // Дата публикации в будущем (ошибка ленты)
+	FilterShortContent = "short_content" // Текст короче min_content_length
+	FilterRuleExcluded = "rule_excluded" // Сработало exclude-правило из pipeline.rules
+	FilterDuplicate    = "duplicate"     // Та же статья уже встретилась в этом запуске
+	FilterAlreadySent  = "already_sent"  // Статья уже была в отправленном дайджесте
+)
+
+// FilterDecision объясняет, почему фильтр оставил или отбросил статью.
+type FilterDecision struct {
+	ArticleID     string    `json:"article_id"`
+	Source        string    `json:"source"`
+	Title         string    `json:"title"`
+	URL           string    `json:"url"`
+	PublishedAt   time.Time `json:"published_at"`
+	AgeHours      float64   `json:"age_hours"`      // Возраст статьи на момент фильтрации, для подбора recency_max_hours
+	ContentLength int       `json:"content_length"` // Длина текста в символах, для подбора min_content_length
+	Kept          bool      `json:"kept"`
+	Reason        string    `json:"reason"`
+	Detail        string    `json:"detail,omitempty"` // Уточнение: имя правила, ключ совпадения с историей и т. п.
+}
+
+// FilterAudit — отчёт фильтра за один запуск со сводкой по причинам и источникам.
+type FilterAudit struct {
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Total       int                       `json:"total"`
+	Kept        int                       `json:"kept"`
+	ByReason    map[string]int            `json:"by_reason"`
+	BySource    map[string]map[string]int `json:"by_source"` // Источник -> причина -> число статей
+	Decisions   []FilterDecision          `json:"decisions"`
+}
+
+// NewFilterAudit собирает отчёт из решений фильтра и подсчитывает сводку.
+func NewFilterAudit(decisions []FilterDecision, generatedAt time.Time) FilterAudit {
+	audit := FilterAudit{
+		GeneratedAt: generatedAt,
+		Total:       len(decisions),
+		ByReason:    make(map[string]int),
+		BySource:    make(map[string]map[string]int),
+		Decisions:   decisions,
+	}
+	for _, d := range decisions {
+		if d.Kept {
+			audit.Kept++
+		}
+		audit.ByReason[d.Reason]++
+		if audit.BySource[d.Source] == nil {
+			audit.BySource[d.Source] = make(map[string]int)
+		}
+		audit.BySource[d.Source][d.Reason]++
+	}
+	return audit
+}
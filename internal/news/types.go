@@ -1,17 +1,65 @@
 package news
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // ArticleRaw описывает новость сразу после получения из источника.
 type ArticleRaw struct {
-	ID          string            `json:"id"`
-	Source      string            `json:"source"`
-	Title       string            `json:"title"`
-	URL         string            `json:"url"`
-	PublishedAt time.Time         `json:"published_at"`
-	RawLanguage string            `json:"raw_language"`
-	RawContent  string            `json:"raw_content"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
+	ID           string            `json:"id"`
+	Source       string            `json:"source"`
+	Title        string            `json:"title"`
+	URL          string            `json:"url"`
+	CanonicalURL string            `json:"canonical_url,omitempty"` // Ключ дедупликации (urlnorm.Canonical ссылки из ленты)
+	PublishedAt  time.Time         `json:"published_at"`
+	RawLanguage  string            `json:"raw_language"`
+	RawContent   string            `json:"raw_content"`        // Текст без HTML-разметки, абзацы разделены пустой строкой
+	RawHTML      string            `json:"raw_html,omitempty"` // Исходный HTML из ленты, если RawContent получен из разметки
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Priority     int               `json:"priority,omitempty"` // Приоритет источника (config.Site.Priority), больше — надёжнее
+	Coverage     int               `json:"coverage,omitempty"` // Сколько разных источников написали об этой новости по данным кластеризации (1 — только этот)
+}
+
+// Ключи ArticleRaw.Metadata, которые заполняют коллекторы и читают следующие этапы.
+const (
+	MetaImageURL = "image_url" // URL главной картинки статьи
+	MetaAuthor   = "author"    // Авторы статьи через запятую
+	MetaTags     = "tags"      // Рубрики и теги издателя через TagsSeparator
+
+	// MetaPageCanonical — rel=canonical со страницы статьи (urlnorm.Canonical), если он отличается
+	// от CanonicalURL. Это дополнительный ключ дедупликации: ID от него не зависит, потому что
+	// полный текст загружается не в каждом запуске.
+	MetaPageCanonical = "page_canonical"
+
+	MetaAlsoReportedBy = "also_reported_by" // Другие источники, написавшие о том же (через запятую)
+)
+
+// TagsSeparator разделяет рубрики издателя в Metadata[MetaTags].
+const TagsSeparator = "; "
+
+// ImageURL возвращает URL главной картинки статьи или пустую строку.
+func (a ArticleRaw) ImageURL() string {
+	return a.Metadata[MetaImageURL]
+}
+
+// Author возвращает авторов статьи или пустую строку.
+func (a ArticleRaw) Author() string {
+	return a.Metadata[MetaAuthor]
+}
+
+// PageCanonicalURL возвращает rel=canonical со страницы статьи или пустую строку.
+func (a ArticleRaw) PageCanonicalURL() string {
+	return a.Metadata[MetaPageCanonical]
+}
+
+// Tags возвращает рубрики и теги, которыми статью пометил издатель.
+func (a ArticleRaw) Tags() []string {
+	value := a.Metadata[MetaTags]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, TagsSeparator)
 }
 
 // CategorizedArticle содержит новость и категорию, присвоенную моделью.
@@ -20,32 +68,62 @@ type CategorizedArticle struct {
 	Category           string     `json:"category"`
 	CategoryConfidence float64    `json:"category_confidence,omitempty"`
 	RelevanceScore     float64    `json:"relevance_score,omitempty"` // Оценка актуальности от Gemini (0-10)
+	Sensitive          bool       `json:"sensitive,omitempty"`       // Тяжёлое содержание: помечено словарём или Gemini
+	SafetyAction       string     `json:"safety_action,omitempty"`   // Что делать с помеченной статьей (config.SafetyWarn, config.SafetySoften, ...)
 }
 
 // DigestEntry — итоговое представление новости перед отправкой.
 type DigestEntry struct {
-	ID          string    `json:"id"`
-	Category    string    `json:"category"`
-	Title       string    `json:"title"`        // Оригинальный заголовок
-	TitleRU     string    `json:"title_ru"`     // Переведенный заголовок на русский
-	URL         string    `json:"url"`
-	SummaryRU   string    `json:"summary_ru"`
-	Source      string    `json:"source"`
-	PublishedAt time.Time `json:"published_at"`
+	ID             string    `json:"id"`
+	Category       string    `json:"category"`
+	Title          string    `json:"title"`    // Оригинальный заголовок
+	TitleRU        string    `json:"title_ru"` // Переведенный заголовок на русский
+	URL            string    `json:"url"`
+	CanonicalURL   string    `json:"canonical_url,omitempty"`
+	PageCanonical  string    `json:"page_canonical,omitempty"` // rel=canonical со страницы (ArticleRaw.PageCanonicalURL)
+	SummaryRU      string    `json:"summary_ru"`
+	Source         string    `json:"source"`
+	PublishedAt    time.Time `json:"published_at"`
+	ImageURL       string    `json:"image_url,omitempty"`       // Главная картинка статьи из ленты
+	Author         string    `json:"author,omitempty"`          // Авторы статьи для подписи в дайджесте
+	ContentWarning bool      `json:"content_warning,omitempty"` // Показать в разделе с предупреждением о тяжёлом содержании
 }
 
 // State хранит минимальную информацию об уже отправленных новостях.
 type State struct {
-	LastRun      time.Time          `json:"last_run"`
-	SentArticles []StateArticle     `json:"sent_articles"`
-	Recipients   []RecipientBinding `json:"recipients"`
-	Telegram     TelegramState      `json:"telegram"`
+	LastRun      time.Time            `json:"last_run"`
+	SentArticles []StateArticle       `json:"sent_articles"`
+	Recipients   []RecipientBinding   `json:"recipients"`
+	Telegram     TelegramState        `json:"telegram"`
+	Feeds        map[string]FeedState `json:"feeds,omitempty"` // Служебные данные лент по URL
+	GeminiQuota  QuotaLedger          `json:"gemini_quota"`    // Запросы к Gemini за текущие сутки квоты
+}
+
+// QuotaLedger — журнал запросов к Gemini за одни сутки квоты. Сохраняется между запусками,
+// чтобы пайплайн заранее знал остаток дневного лимита (RPD), а не узнавал о нём по ошибке 429.
+type QuotaLedger struct {
+	Day      string         `json:"day"` // Сутки квоты (YYYY-MM-DD по тихоокеанскому времени, как у Gemini API)
+	Requests []QuotaRequest `json:"requests"`
+}
+
+// QuotaRequest описывает один запрос к Gemini, включая повторы после ошибок.
+type QuotaRequest struct {
+	Model           string    `json:"model"`
+	Stage           string    `json:"stage,omitempty"` // categorization, ranking, summary
+	EstimatedTokens int       `json:"estimated_tokens"`
+	At              time.Time `json:"at"`
 }
 
 // StateArticle описывает запись об отправленной новости.
+// ID содержит время публикации и меняется, если сайт правит дату, поэтому повторы
+// дополнительно узнаются по канонической ссылке и отпечатку заголовка.
 type StateArticle struct {
-	ID     string    `json:"id"`
-	SentAt time.Time `json:"sent_at"`
+	ID            string    `json:"id"`
+	Source        string    `json:"source,omitempty"` // Отпечаток заголовка сравнивается только в пределах источника
+	CanonicalURL  string    `json:"canonical_url,omitempty"`
+	PageCanonical string    `json:"page_canonical,omitempty"` // rel=canonical со страницы, если отличался от ссылки из ленты
+	TitleHash     string    `json:"title_hash,omitempty"`     // textnorm.TitleFingerprint исходного заголовка
+	SentAt        time.Time `json:"sent_at"`
 }
 
 // RecipientBinding хранит известные чаты для рассылки.
@@ -55,6 +133,24 @@ type RecipientBinding struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// FeedState хранит служебные данные ленты между запусками: валидаторы HTTP-кэша
+// для условных запросов (If-None-Match / If-Modified-Since) и историю её здоровья.
+type FeedState struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	SiteID              string    `json:"site_id,omitempty"`
+	LastCheckedAt       time.Time `json:"last_checked_at"`
+	LastSuccessAt       time.Time `json:"last_success_at"`             // Последняя успешная загрузка (включая 304)
+	LastError           string    `json:"last_error,omitempty"`
+	LastItemCount       int       `json:"last_item_count"`             // Статей в последнем полном ответе
+	Successes           int       `json:"successes"`
+	Failures            int       `json:"failures"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	ConsecutiveEmpty    int       `json:"consecutive_empty,omitempty"` // Подряд ответов 200 без единой статьи
+	QuarantinedUntil    time.Time `json:"quarantined_until"`           // До этого времени лента не запрашивается
+}
+
 // TelegramState хранит служебную информацию для взаимодействия с Bot API.
 type TelegramState struct {
 	LastUpdateID int64 `json:"last_update_id"`
@@ -62,7 +158,8 @@ type TelegramState struct {
 
 // Digest хранит готовый дайджест для отправки.
 type Digest struct {
-	Messages   []string  `json:"messages"`   // Готовые сообщения для отправки
-	CreatedAt  time.Time `json:"created_at"`  // Время создания дайджеста
-	ArticleIDs []string  `json:"article_ids"` // ID статей, включенных в дайджест (для отслеживания отправленных)
+	Messages   []string       `json:"messages"`           // Готовые сообщения для отправки
+	CreatedAt  time.Time      `json:"created_at"`         // Время создания дайджеста
+	ArticleIDs []string       `json:"article_ids"`        // ID статей, включенных в дайджест (для отслеживания отправленных)
+	Articles   []StateArticle `json:"articles,omitempty"` // Записи для state после отправки (ID, ссылка, отпечаток заголовка)
 }
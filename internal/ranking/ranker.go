@@ -3,27 +3,29 @@ package ranking
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
-	"strings"
-	"time"
 
 	"github.com/maine/vietnam_bot_news/internal/config"
 	"github.com/maine/vietnam_bot_news/internal/gemini"
+	"github.com/maine/vietnam_bot_news/internal/llm"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
 
-// Ranker реализует app.Ranker для выбора топ-N новостей в каждой категории через Gemini.
+// Ranker реализует app.Ranker для выбора топ-N новостей в каждой категории через языковую модель (по умолчанию Gemini).
 type Ranker struct {
 	maxPerCategory int
-	geminiClient   gemini.GeminiClient
+	priorityBoost  float64 // Прибавка к оценке за единицу приоритета источника (только для сортировки)
+	coverageBoost  float64 // Прибавка к оценке за каждый дополнительный источник той же новости (только для сортировки)
+	geminiClient   llm.Client
 	cfg            config.Gemini
 	batchSize      int
 }
 
 // NewRanker создаёт новый экземпляр ранкера.
-func NewRanker(cfg config.Pipeline, geminiClient gemini.GeminiClient, geminiCfg config.Gemini) *Ranker {
+func NewRanker(cfg config.Pipeline, geminiClient llm.Client, geminiCfg config.Gemini) *Ranker {
 	batchSize := geminiCfg.BatchSizeRanking
 	if batchSize <= 0 {
 		batchSize = 10 // дефолтное значение
@@ -32,18 +34,43 @@ func NewRanker(cfg config.Pipeline, geminiClient gemini.GeminiClient, geminiCfg
 	if maxPerCategory <= 0 {
 		maxPerCategory = 5 // дефолтное значение
 	}
+	priorityBoost := cfg.PriorityRankingBoost
+	if priorityBoost < 0 {
+		priorityBoost = 0
+	}
+	coverageBoost := cfg.CoverageRankingBoost
+	if coverageBoost < 0 {
+		coverageBoost = 0
+	}
 	return &Ranker{
 		maxPerCategory: maxPerCategory,
+		priorityBoost:  priorityBoost,
+		coverageBoost:  coverageBoost,
 		geminiClient:   geminiClient,
 		cfg:            geminiCfg,
 		batchSize:      batchSize,
 	}
 }
 
+// errRankingSkipped — ранжирование через Gemini пропущено планом запросов (см. RankOffline).
+var errRankingSkipped = errors.New("gemini ranking skipped to save daily quota")
+
 // Rank реализует app.Ranker.
 // Группирует новости по категориям, отправляет в Gemini для оценки актуальности,
 // затем сортирует и выбирает топ-N в каждой категории.
 func (r *Ranker) Rank(ctx context.Context, categorized []news.CategorizedArticle) ([]news.CategorizedArticle, error) {
+	return r.rank(gemini.WithStage(ctx, gemini.StageRanking), categorized, nil)
+}
+
+// RankOffline реализует app.OfflineRanker: выбирает топ-N в каждой категории без запросов
+// к Gemini — по приоритету источника, охвату и свежести, без фильтра по релевантности.
+func (r *Ranker) RankOffline(ctx context.Context, categorized []news.CategorizedArticle) ([]news.CategorizedArticle, error) {
+	return r.rank(ctx, categorized, errRankingSkipped)
+}
+
+// rank выполняет ранжирование; skipErr != nil — Gemini не вызывается, все категории
+// обрабатываются как после ошибки ранжирования.
+func (r *Ranker) rank(ctx context.Context, categorized []news.CategorizedArticle, skipErr error) ([]news.CategorizedArticle, error) {
 	if len(categorized) == 0 {
 		return nil, nil
 	}
@@ -60,30 +87,20 @@ func (r *Ranker) Rank(ctx context.Context, categorized []news.CategorizedArticle
 
 	var results []news.CategorizedArticle
 
-	// Минимальная задержка между запросами для соблюдения RPM=5 (12 секунд между запросами)
-	const minDelayBetweenRequests = 12 * time.Second
-	lastRequestTime := time.Now()
-	categoryCount := 0
-	totalCategories := len(byCategory)
+	// Паузы для RPM/TPM выдерживает лимитер клиента.
+	// После ошибки дневной квоты остальные категории не отправляем: запросы всё равно отклонят
 
 	// Обрабатываем каждую категорию отдельно
 	for category, articles := range byCategory {
-		categoryCount++
-
-		// Соблюдаем задержку между запросами для соблюдения RPM лимита
-		elapsed := time.Since(lastRequestTime)
-		if elapsed < minDelayBetweenRequests && categoryCount > 1 {
-			waitTime := minDelayBetweenRequests - elapsed
-			log.Printf("Waiting %v before ranking category '%s' (RPM limit, %d/%d categories)...", waitTime, category, categoryCount, totalCategories)
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(waitTime):
+		// Оцениваем актуальность через Gemini (все статьи категории одним запросом)
+		var scored []news.CategorizedArticle
+		err := skipErr
+		if err == nil {
+			scored, err = r.rankCategory(ctx, category, articles)
+			if errors.Is(err, gemini.ErrDailyQuota) {
+				skipErr = err
 			}
 		}
-
-		// Оцениваем актуальность через Gemini (все статьи категории одним запросом)
-		scored, err := r.rankCategory(ctx, category, articles)
 		rankHadError := err != nil
 		if err != nil {
 			log.Printf("Ranking error for category '%s': %v. Using unscored articles without relevance filter.", category, err)
@@ -131,14 +148,11 @@ func (r *Ranker) Rank(ctx context.Context, categorized []news.CategorizedArticle
 
 		// Если после фильтрации по релевантности ничего не осталось — переходим к следующей категории
 		if len(scored) == 0 {
-			lastRequestTime = time.Now()
 			continue
 		}
 
-		// Сортируем по оценке актуальности (убывание)
-		sort.Slice(scored, func(i, j int) bool {
-			return scored[i].RelevanceScore > scored[j].RelevanceScore
-		})
+		// Сортируем по оценке актуальности (убывание) с поправкой на приоритет источника
+		r.sortByRelevance(scored)
 
 		// Обрезаем до лимита для категории (разные лимиты для разных категорий)
 		maxForCategory := r.getMaxForCategory(category)
@@ -147,7 +161,6 @@ func (r *Ranker) Rank(ctx context.Context, categorized []news.CategorizedArticle
 		}
 
 		results = append(results, scored...)
-		lastRequestTime = time.Now()
 	}
 
 	// Логируем распределение по категориям после ранкинга
@@ -172,6 +185,31 @@ func (r *Ranker) Rank(ctx context.Context, categorized []news.CategorizedArticle
 	return results, nil
 }
 
+// sortByRelevance сортирует статьи по убыванию оценки актуальности.
+// Приоритет источника добавляет к оценке priorityBoost за единицу, а при равенстве служит
+// тай-брейкером: из двух одинаково актуальных новостей выше окажется новость надёжного источника.
+// Новость, о которой написали несколько источников, получает coverageBoost за каждый дополнительный.
+// Порог релевантности (>=5) проверяется по исходной оценке, boost на него не влияет.
+func (r *Ranker) sortByRelevance(articles []news.CategorizedArticle) {
+	effective := func(a news.CategorizedArticle) float64 {
+		score := a.RelevanceScore + r.priorityBoost*float64(a.Article.Priority)
+		if a.Article.Coverage > 1 {
+			score += r.coverageBoost * float64(a.Article.Coverage-1)
+		}
+		return score
+	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		ei, ej := effective(articles[i]), effective(articles[j])
+		if ei != ej {
+			return ei > ej
+		}
+		if articles[i].Article.Priority != articles[j].Article.Priority {
+			return articles[i].Article.Priority > articles[j].Article.Priority
+		}
+		return articles[i].Article.PublishedAt.After(articles[j].Article.PublishedAt)
+	})
+}
+
 // getMaxForCategory возвращает максимальное количество статей для категории.
 // "Общество" и "Экономика и бизнес" - топ 3, остальные - топ 5.
 func (r *Ranker) getMaxForCategory(category string) int {
@@ -214,6 +252,7 @@ func (r *Ranker) rankBatch(ctx context.Context, category string, articles []news
 			Content:     article.Article.RawContent,
 			PublishedAt: article.Article.PublishedAt.Format("2006-01-02T15:04:05-07:00"),
 			Source:      article.Article.Source,
+			Coverage:    article.Article.Coverage,
 		})
 	}
 
@@ -225,29 +264,14 @@ func (r *Ranker) rankBatch(ctx context.Context, category string, articles []news
 	// Формируем промпт согласно docs/prompting.md
 	prompt := r.buildPrompt(string(inputJSON))
 
-	// Вызываем Gemini API
-	responseText, err := r.geminiClient.GenerateText(ctx, r.cfg.ModelRanking, prompt)
-	if err != nil {
-		// Проверяем, является ли это ошибкой квоты (RPD)
-		errStr := err.Error()
-		if strings.Contains(strings.ToLower(errStr), "quota") || strings.Contains(strings.ToLower(errStr), "rpd") {
-			log.Printf("CRITICAL: Gemini API quota exceeded during ranking. Stopping batch processing.")
-			return nil, fmt.Errorf("gemini API quota exceeded (RPD limit): %w", err)
-		}
-		return nil, fmt.Errorf("generate text: %w", err)
-	}
-
-	// Парсим JSON ответ
+	// Вызываем Gemini API: ответ приходит JSON-массивом по схеме
 	var scores []relevanceScoreResponse
-	if err := json.Unmarshal([]byte(responseText), &scores); err != nil {
-		// Пытаемся извлечь JSON из текста, если модель добавила лишнее
-		cleaned := extractJSON(responseText)
-		if cleaned == "" {
-			return nil, fmt.Errorf("unmarshal response: %w (raw: %s)", err, responseText)
-		}
-		if err := json.Unmarshal([]byte(cleaned), &scores); err != nil {
-			return nil, fmt.Errorf("unmarshal cleaned response: %w (cleaned: %s)", err, cleaned)
+	if err := r.geminiClient.GenerateJSON(ctx, r.cfg.ModelRanking, prompt, relevanceSchema, &scores); err != nil {
+		if errors.Is(err, gemini.ErrDailyQuota) {
+			log.Printf("CRITICAL: Gemini API quota exceeded during ranking. Stopping batch processing.")
+			return nil, err
 		}
+		return nil, fmt.Errorf("generate scores: %w", err)
 	}
 
 	// Создаём map для быстрого поиска оценки по ID
@@ -330,74 +354,20 @@ func (r *Ranker) buildPrompt(inputJSON string) string {
 - Удали дубликаты: если несколько новостей имеют очень похожие заголовки или содержание, оставь одну (предпочтительно самую свежую или самую полную).
 - В ответе должен быть ровно один id на каждую группу дубликатов. Дубликаты не должны повторяться.
 
+Поле coverage (если есть) — сколько изданий написали об этой новости. Широкое освещение говорит о значимости события, но не заменяет критерии релевантности для аудитории.
+
 Для каждой новости оцени её релевантность для целевой аудитории по шкале от 0 до 10, где:
 - 10 — очень релевантная новость для экспатов (практическая ценность, помогает понять жизнь во Вьетнаме)
 - 5 — средняя релевантность (может быть интересна, но не критична)
 - 0 — нерелевантная новость (интересна только местным, без практической ценности для экспатов)
 
-Верни результат ТОЛЬКО в виде валидного JSON-массива без markdown блоков, без дополнительных комментариев, без обрамления в code blocks.
-Формат (raw JSON):
+Верни JSON-массив, по одному объекту на каждую оставшуюся новость:
 [{"id": "<id новости>", "relevance_score": <число от 0 до 10>}, ...]
 
 Входные данные:
 %s`, inputJSON)
 }
 
-// extractJSON извлекает JSON-массив из текста (если модель добавила лишний текст).
-func extractJSON(text string) string {
-	// Удаляем markdown code blocks (```json ... ``` или ``` ... ```)
-	// Ищем начало и конец code block
-	codeBlockStart := strings.Index(text, "```json")
-	if codeBlockStart != -1 {
-		// Нашли начало code block, пропускаем ```json и возможные пробелы/переносы строк
-		contentStart := codeBlockStart + 6
-		// Пропускаем пробелы и переносы строк после ```json
-		for contentStart < len(text) && (text[contentStart] == ' ' || text[contentStart] == '\n' || text[contentStart] == '\r') {
-			contentStart++
-		}
-		// Ищем конец code block
-		codeBlockEnd := strings.Index(text[contentStart:], "```")
-		if codeBlockEnd != -1 {
-			// Извлекаем содержимое между ```json и ```
-			text = strings.TrimSpace(text[contentStart : contentStart+codeBlockEnd])
-		}
-	} else {
-		// Пробуем найти обычный code block без указания языка
-		codeBlockStart = strings.Index(text, "```")
-		if codeBlockStart != -1 {
-			contentStart := codeBlockStart + 3
-			// Пропускаем пробелы и переносы строк после ```
-			for contentStart < len(text) && (text[contentStart] == ' ' || text[contentStart] == '\n' || text[contentStart] == '\r') {
-				contentStart++
-			}
-			codeBlockEnd := strings.Index(text[contentStart:], "```")
-			if codeBlockEnd != -1 {
-				text = strings.TrimSpace(text[contentStart : contentStart+codeBlockEnd])
-			}
-		}
-	}
-
-	// Ищем JSON-массив в тексте
-	start := strings.Index(text, "[")
-	if start == -1 {
-		return ""
-	}
-
-	depth := 0
-	for i := start; i < len(text); i++ {
-		if text[i] == '[' {
-			depth++
-		} else if text[i] == ']' {
-			depth--
-			if depth == 0 {
-				return text[start : i+1]
-			}
-		}
-	}
-
-	return ""
-}
-
 type articleRankingInput struct {
 	ID          string `json:"id"`
 	Category    string `json:"category"`
@@ -405,8 +375,15 @@ type articleRankingInput struct {
 	Content     string `json:"content"`
 	PublishedAt string `json:"published_at"`
 	Source      string `json:"source"`
+	Coverage    int    `json:"coverage,omitempty"` // Сколько источников написали об этой новости
 }
 
+// relevanceSchema описывает ответ ранжирования.
+var relevanceSchema = llm.ArrayOf(map[string]*llm.Schema{
+	"id":              {Type: llm.TypeString},
+	"relevance_score": {Type: llm.TypeNumber, Description: "Оценка релевантности от 0 до 10"},
+})
+
 type relevanceScoreResponse struct {
 	ID             string  `json:"id"`
 	RelevanceScore float64 `json:"relevance_score"`
@@ -0,0 +1,151 @@
+// Package safety помечает тяжёлые материалы (натуралистичные подробности преступлений,
+// гибели людей, самоубийств) и применяет к ним политику из pipeline.safety.
+package safety
+
+import (
+	"context"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
+)
+
+// defaultLexicon — встроенный словарь. Слова сравниваются с учётом диакритики: без неё
+// вьетнамские слова совпадают с безобидными («tự tử» — самоубийство, «từ từ» — постепенно).
+var defaultLexicon = []string{
+	// Гибель и тела
+	"thi thể", "xác chết", "phân xác", "chặt xác", "đốt xác", "phi tang", "chết cháy", "tử vong tại chỗ",
+	// Насилие
+	"giết người", "sát hại", "thảm sát", "đâm chết", "chém chết", "đánh chết", "bạo hành", "tra tấn",
+	// Сексуальное насилие
+	"hiếp dâm", "cưỡng hiếp", "dâm ô", "xâm hại tình dục",
+	// Самоубийство
+	"tự tử", "tự sát", "treo cổ", "nhảy lầu",
+	// Англоязычные ленты
+	"murder", "murdered", "corpse", "dismembered", "rape", "raped", "suicide", "beheaded",
+}
+
+// minContentMatches — сколько разных слов словаря должно найтись в тексте статьи, чтобы её пометить.
+// Одно слово в тексте часто встречается в нейтральном контексте (отчёт о ДТП, судебная хроника),
+// поэтому по тексту нужна пара совпадений, а по заголовку достаточно одного.
+const minContentMatches = 2
+
+// Guard реализует app.SafetyGuard.
+type Guard struct {
+	enabled    bool
+	terms      []string // Слова словаря в нижнем регистре, обрамлённые пробелами
+	action     string
+	categories map[string]string
+}
+
+// New создаёт проверку по секции pipeline.safety. При enabled: false статьи пропускаются без изменений.
+func New(cfg config.Safety) *Guard {
+	action := cfg.Action
+	if action == "" {
+		action = config.SafetyWarn
+	}
+	g := &Guard{
+		enabled:    cfg.Enabled,
+		action:     action,
+		categories: cfg.Categories,
+	}
+	seen := make(map[string]bool)
+	for _, term := range append(append([]string(nil), defaultLexicon...), cfg.Keywords...) {
+		padded := joinWords(normalize(term))
+		if strings.TrimSpace(padded) == "" || seen[padded] {
+			continue
+		}
+		seen[padded] = true
+		g.terms = append(g.terms, padded)
+	}
+	return g
+}
+
+// Review реализует app.SafetyGuard.
+// Статья считается тяжёлой, если её пометил Gemini (CategorizedArticle.Sensitive) или словарь.
+// Помеченные статьи с политикой exclude удаляются, остальным записывается SafetyAction.
+func (g *Guard) Review(ctx context.Context, articles []news.CategorizedArticle) ([]news.CategorizedArticle, error) {
+	if !g.enabled {
+		return articles, nil
+	}
+
+	kept := make([]news.CategorizedArticle, 0, len(articles))
+	counts := make(map[string]int)
+	for _, article := range articles {
+		reason := "gemini"
+		if term := g.match(article.Article); term != "" {
+			reason = "lexicon: " + term
+			article.Sensitive = true
+		}
+		if !article.Sensitive {
+			kept = append(kept, article)
+			continue
+		}
+
+		action := g.actionFor(article.Category)
+		counts[action]++
+		log.Printf("Safety: %s [%s] %s (%s)", action, article.Category, article.Article.Title, reason)
+		if action == config.SafetyExclude {
+			continue
+		}
+		article.SafetyAction = action
+		kept = append(kept, article)
+	}
+
+	flagged := 0
+	for _, n := range counts {
+		flagged += n
+	}
+	if flagged > 0 {
+		log.Printf("Safety: flagged %d of %d articles (exclude: %d, warn: %d, soften: %d, allow: %d)",
+			flagged, len(articles), counts[config.SafetyExclude], counts[config.SafetyWarn], counts[config.SafetySoften], counts[config.SafetyAllow])
+	}
+	return kept, nil
+}
+
+// actionFor возвращает политику категории или политику по умолчанию.
+func (g *Guard) actionFor(category string) string {
+	if action, ok := g.categories[category]; ok {
+		return action
+	}
+	return g.action
+}
+
+// match возвращает найденное слово словаря или пустую строку, если статья не тяжёлая.
+func (g *Guard) match(article news.ArticleRaw) string {
+	title := joinWords(normalize(article.Title))
+	for _, term := range g.terms {
+		if strings.Contains(title, term) {
+			return strings.TrimSpace(term)
+		}
+	}
+
+	content := joinWords(normalize(article.RawContent))
+	var found []string
+	for _, term := range g.terms {
+		if strings.Contains(content, term) {
+			found = append(found, strings.TrimSpace(term))
+			if len(found) == minContentMatches {
+				return strings.Join(found, ", ")
+			}
+		}
+	}
+	return ""
+}
+
+// normalize приводит текст к NFC в нижнем регистре: ленты присылают диакритику в разных формах.
+func normalize(s string) string {
+	return strings.ToLower(textnorm.NFC(s))
+}
+
+// joinWords оставляет только слова, разделённые одним пробелом, и обрамляет их пробелами,
+// чтобы поиск подстроки находил слова целиком.
+func joinWords(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
@@ -0,0 +1,110 @@
+package safety
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+func TestGuard_Review(t *testing.T) {
+	article := func(id, category, title, content string) news.CategorizedArticle {
+		return news.CategorizedArticle{
+			Article:  news.ArticleRaw{ID: id, Title: title, RawContent: content},
+			Category: category,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		cfg        config.Safety
+		articles   []news.CategorizedArticle
+		wantIDs    []string
+		wantAction map[string]string // ID -> SafetyAction
+	}{
+		{
+			name: "disabled guard keeps everything",
+			cfg:  config.Safety{Action: config.SafetyExclude},
+			articles: []news.CategorizedArticle{
+				article("crime", "Общество", "Phát hiện thi thể dưới sông", ""),
+			},
+			wantIDs: []string{"crime"},
+		},
+		{
+			name: "title match uses default warn policy",
+			cfg:  config.Safety{Enabled: true},
+			articles: []news.CategorizedArticle{
+				article("crime", "Общество", "Phát hiện THI THỂ dưới sông", ""),
+				article("calm", "Общество", "Giá vàng tăng", ""),
+			},
+			wantIDs:    []string{"crime", "calm"},
+			wantAction: map[string]string{"crime": config.SafetyWarn, "calm": ""},
+		},
+		{
+			name: "per-category policy overrides default",
+			cfg: config.Safety{
+				Enabled:    true,
+				Action:     config.SafetySoften,
+				Categories: map[string]string{"Другое / Разное": config.SafetyExclude},
+			},
+			articles: []news.CategorizedArticle{
+				article("misc", "Другое / Разное", "Nghi phạm sát hại người tình", ""),
+				article("society", "Общество", "Xét xử vụ giết người ở Hà Nội", ""),
+			},
+			wantIDs:    []string{"society"},
+			wantAction: map[string]string{"society": config.SafetySoften},
+		},
+		{
+			name: "content needs two distinct terms",
+			cfg:  config.Safety{Enabled: true},
+			articles: []news.CategorizedArticle{
+				article("one", "Общество", "Tai nạn giao thông", "Một người tử vong tại chỗ."),
+				article("two", "Общество", "Vụ án ở Bình Dương", "Nghi phạm sát hại nạn nhân rồi phi tang thi thể."),
+			},
+			wantIDs:    []string{"one", "two"},
+			wantAction: map[string]string{"one": "", "two": config.SafetyWarn},
+		},
+		{
+			name: "diacritics matter",
+			cfg:  config.Safety{Enabled: true},
+			articles: []news.CategorizedArticle{
+				article("slow", "Общество", "Giá nhà từ từ giảm", ""),
+			},
+			wantIDs:    []string{"slow"},
+			wantAction: map[string]string{"slow": ""},
+		},
+		{
+			name: "gemini flag and custom keywords",
+			cfg:  config.Safety{Enabled: true, Action: config.SafetyExclude, Keywords: []string{"Đánh bom"}},
+			articles: []news.CategorizedArticle{
+				{Article: news.ArticleRaw{ID: "llm", Title: "Chi tiết vụ án"}, Category: "Общество", Sensitive: true},
+				article("custom", "Общество", "Vụ đánh bom ở chợ", ""),
+				article("calm", "Общество", "Lễ hội hoa", ""),
+			},
+			wantIDs: []string{"calm"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.cfg).Review(context.Background(), tt.articles)
+			if err != nil {
+				t.Fatalf("Review() error = %v", err)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("Review() returned %d articles, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if got[i].Article.ID != id {
+					t.Errorf("Review()[%d] = %s, want %s", i, got[i].Article.ID, id)
+				}
+				if want, ok := tt.wantAction[id]; ok {
+					if got[i].SafetyAction != want || got[i].Sensitive != (want != "") {
+						t.Errorf("article %s: SafetyAction = %q, Sensitive = %v, want %q", id, got[i].SafetyAction, got[i].Sensitive, want)
+					}
+				}
+			}
+		})
+	}
+}
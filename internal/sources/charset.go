@@ -0,0 +1,77 @@
+package sources
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// newFeedDecoder создаёт XML-декодер, понимающий не-UTF-8 ленты (windows-1258, ISO-8859-1 и т.п.).
+// Кодировка из HTTP Content-Type имеет приоритет над XML-декларацией (RFC 7303), кроме случая,
+// когда сервер заявляет UTF-8, а тело им не является: тогда верим декларации.
+func newFeedDecoder(data []byte, contentType string) *xml.Decoder {
+	transcoded := false
+	if label := contentTypeCharset(contentType); label != "" && !(isUTF8Label(label) && !utf8.Valid(data)) {
+		if r, err := charset.NewReaderLabel(label, bytes.NewReader(data)); err == nil {
+			if decoded, err := io.ReadAll(r); err == nil {
+				data = decoded
+				transcoded = true
+			}
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if transcoded {
+			// Тело уже перекодировано в UTF-8 по Content-Type, декларацию игнорируем
+			return input, nil
+		}
+		return charset.NewReaderLabel(label, input)
+	}
+	return decoder
+}
+
+// newHTMLReader возвращает HTML-страницу в UTF-8. Кодировка берётся из Content-Type, BOM
+// или <meta charset>; как и для лент, заявленному сервером UTF-8 при невалидном теле не верим.
+// Валидный UTF-8 без объявленной кодировки не перекодируется: угадывание windows-1252
+// по первым байтам страницы испортило бы вьетнамский текст.
+func newHTMLReader(data []byte, contentType string) io.Reader {
+	label := contentTypeCharset(contentType)
+	if label == "" || isUTF8Label(label) {
+		if utf8.Valid(data) {
+			return bytes.NewReader(data)
+		}
+		contentType = ""
+	}
+	_, name, _ := charset.DetermineEncoding(data, contentType)
+	r, err := charset.NewReaderLabel(name, bytes.NewReader(data))
+	if err != nil {
+		return bytes.NewReader(data)
+	}
+	return r
+}
+
+// contentTypeCharset возвращает параметр charset из заголовка Content-Type (или пустую строку).
+func contentTypeCharset(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(params["charset"])
+}
+
+func isUTF8Label(label string) bool {
+	switch strings.ToLower(label) {
+	case "utf-8", "utf8":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,327 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// rssIndexPaths — типичные адреса страниц-каталогов RSS на вьетнамских сайтах.
+var rssIndexPaths = []string{"/rss", "/rss.html", "/rss.htm", "/tin-rss", "/rss-feed"}
+
+// feedMIMETypes — типы <link rel="alternate">, которые считаем лентами.
+var feedMIMETypes = map[string]bool{
+	"application/rss+xml":  true,
+	"application/atom+xml": true,
+	"application/rdf+xml":  true,
+	"application/xml":      true,
+	"text/xml":             true,
+}
+
+// categoryHint — ключевые слова одной категории из pipeline.category_keywords.
+type categoryHint struct {
+	category string
+	keywords []string
+}
+
+// DiscoveredFeed — лента, найденная на сайте.
+type DiscoveredFeed struct {
+	URL      string
+	Title    string
+	Category string // Предложенная категория из pipeline.categories; пусто — отдать Gemini
+}
+
+// FeedDiscoverer ищет RSS/Atom-ленты сайта по адресу главной страницы.
+type FeedDiscoverer struct {
+	client *http.Client
+	hints  []categoryHint
+	verify bool
+}
+
+// NewFeedDiscoverer создаёт новый экземпляр. Категории подсказываются по pipeline.category_keywords
+// и только из pipeline.categories, в порядке этого списка.
+// При verify каждая найденная ссылка загружается и разбирается, чтобы отсеять не-ленты.
+func NewFeedDiscoverer(client *http.Client, cfg config.Pipeline, verify bool) *FeedDiscoverer {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	var hints []categoryHint
+	for _, category := range cfg.Categories {
+		var keywords []string
+		for _, keyword := range cfg.CategoryKeywords[category] {
+			if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+				keywords = append(keywords, keyword)
+			}
+		}
+		if len(keywords) > 0 {
+			hints = append(hints, categoryHint{category: category, keywords: keywords})
+		}
+	}
+	return &FeedDiscoverer{client: client, hints: hints, verify: verify}
+}
+
+// Discover загружает главную страницу и страницы-каталоги RSS (/rss и ссылки с «rss» на главной),
+// собирает ленты из <link rel="alternate"> и ссылок каталогов и возвращает их без дублей
+// в порядке обнаружения. Второе значение — <title> главной страницы (подсказка для name).
+func (d *FeedDiscoverer) Discover(ctx context.Context, siteURL string) ([]DiscoveredFeed, string, error) {
+	base, err := url.Parse(strings.TrimSpace(siteURL))
+	if err != nil || base.Host == "" {
+		return nil, "", fmt.Errorf("invalid site URL %q", siteURL)
+	}
+
+	home, err := d.fetchHTML(ctx, base.String())
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch homepage: %w", err)
+	}
+	siteTitle := ""
+	if titleNode := firstElement(home, "title"); titleNode != nil {
+		siteTitle = nodeText(titleNode)
+	}
+
+	var feeds []DiscoveredFeed
+	seen := make(map[string]bool)
+	add := func(feedURL, title string) {
+		if feedURL == "" || seen[feedURL] {
+			return
+		}
+		seen[feedURL] = true
+		feeds = append(feeds, DiscoveredFeed{URL: feedURL, Title: title})
+	}
+
+	for _, feed := range alternateFeeds(home, base) {
+		add(feed.URL, feed.Title)
+	}
+
+	// Каталоги RSS: стандартные адреса плюс ссылки с главной, похожие на каталог
+	indexes := make([]string, 0, len(rssIndexPaths))
+	indexSeen := make(map[string]bool)
+	for _, path := range rssIndexPaths {
+		u := base.ResolveReference(&url.URL{Path: path}).String()
+		indexes = append(indexes, u)
+		indexSeen[u] = true
+	}
+	for _, link := range pageLinks(home, base) {
+		switch {
+		case looksLikeFeed(link.URL) && sameSite(link.URL, base):
+			add(link.URL, link.Title)
+		case looksLikeRSSIndex(link.URL) && sameSite(link.URL, base) && !indexSeen[link.URL]:
+			indexes = append(indexes, link.URL)
+			indexSeen[link.URL] = true
+		}
+	}
+
+	for _, indexURL := range indexes {
+		page, err := d.fetchHTML(ctx, indexURL)
+		if err != nil {
+			continue // Большинства стандартных адресов на конкретном сайте нет
+		}
+		for _, feed := range alternateFeeds(page, base) {
+			add(feed.URL, feed.Title)
+		}
+		for _, link := range pageLinks(page, base) {
+			if looksLikeFeed(link.URL) && sameSite(link.URL, base) {
+				add(link.URL, link.Title)
+			}
+		}
+	}
+
+	if d.verify {
+		feeds = d.verifyFeeds(ctx, feeds)
+	}
+	for i := range feeds {
+		feeds[i].Category = d.suggestCategory(feeds[i])
+	}
+	return feeds, siteTitle, nil
+}
+
+// verifyFeeds оставляет только ссылки, которые действительно разбираются как лента.
+func (d *FeedDiscoverer) verifyFeeds(ctx context.Context, feeds []DiscoveredFeed) []DiscoveredFeed {
+	limiter := newHostLimiter(defaultPerHostConcurrency, defaultPolitenessDelay, nil)
+	ok := make([]bool, len(feeds))
+	runParallel(len(feeds), defaultMaxConcurrency, func(i int) {
+		release, err := limiter.acquire(ctx, feeds[i].URL)
+		if err != nil {
+			return
+		}
+		defer release()
+		resp, err := conditionalGet(ctx, d.client, feeds[i].URL, news.FeedState{})
+		if err != nil {
+			return
+		}
+		_, err = parseRSSFeed(resp.body, resp.contentType)
+		ok[i] = err == nil
+	})
+
+	verified := make([]DiscoveredFeed, 0, len(feeds))
+	for i, feed := range feeds {
+		if ok[i] {
+			verified = append(verified, feed)
+		}
+	}
+	return verified
+}
+
+// suggestCategory подбирает категорию по URL и названию ленты.
+// Главные ленты («home», «tin-moi-nhat» и т.п.) и неизвестные рубрики остаются пустыми — их размечает Gemini.
+func (d *FeedDiscoverer) suggestCategory(feed DiscoveredFeed) string {
+	haystack := strings.ToLower(feed.URL + " " + feed.Title)
+	for _, hint := range d.hints {
+		for _, keyword := range hint.keywords {
+			if strings.Contains(haystack, keyword) {
+				return hint.category
+			}
+		}
+	}
+	return ""
+}
+
+func (d *FeedDiscoverer) fetchHTML(ctx context.Context, pageURL string) (*html.Node, error) {
+	body, err := httpGet(ctx, d.client, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	return html.Parse(bytes.NewReader(body))
+}
+
+type pageLink struct {
+	URL   string
+	Title string
+}
+
+// alternateFeeds возвращает ленты из <link rel="alternate" type="application/rss+xml">.
+func alternateFeeds(doc *html.Node, base *url.URL) []pageLink {
+	var links []pageLink
+	walkElements(doc, "link", func(n *html.Node) {
+		rel := strings.Fields(strings.ToLower(nodeAttr(n, "rel")))
+		isAlternate := false
+		for _, r := range rel {
+			if r == "alternate" {
+				isAlternate = true
+			}
+		}
+		if !isAlternate || !feedMIMETypes[strings.ToLower(strings.TrimSpace(nodeAttr(n, "type")))] {
+			return
+		}
+		if u := resolveLink(base, nodeAttr(n, "href")); u != "" {
+			links = append(links, pageLink{URL: u, Title: strings.TrimSpace(nodeAttr(n, "title"))})
+		}
+	})
+	return links
+}
+
+// pageLinks возвращает все ссылки <a href> страницы с их текстом.
+func pageLinks(doc *html.Node, base *url.URL) []pageLink {
+	var links []pageLink
+	walkElements(doc, "a", func(n *html.Node) {
+		if u := resolveLink(base, nodeAttr(n, "href")); u != "" {
+			title := nodeText(n)
+			if title == "" {
+				title = strings.TrimSpace(nodeAttr(n, "title"))
+			}
+			links = append(links, pageLink{URL: u, Title: title})
+		}
+	})
+	return links
+}
+
+func walkElements(n *html.Node, tag string, fn func(*html.Node)) {
+	if n.Type == html.ElementNode && n.Data == tag {
+		fn(n)
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walkElements(child, tag, fn)
+	}
+}
+
+func resolveLink(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	u := base.ResolveReference(ref)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
+
+// looksLikeFeed сообщает, похожа ли ссылка на адрес ленты (…/rss/kinh-te.rss, …/feed, …/rss.xml).
+func looksLikeFeed(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	path := strings.ToLower(strings.TrimSuffix(u.Path, "/"))
+	switch {
+	case strings.HasSuffix(path, ".rss"), strings.HasSuffix(path, ".atom"):
+		return true
+	case strings.HasSuffix(path, ".xml"):
+		return strings.Contains(path, "rss") || strings.Contains(path, "feed") || strings.Contains(path, "atom")
+	case strings.HasSuffix(path, "/feed"), strings.HasSuffix(path, "/atom"):
+		return true
+	case strings.Contains(path, "/rss/"):
+		// vietbao.vn/rss/xa-hoi — ленты без расширения внутри каталога /rss/
+		return !strings.HasSuffix(path, ".html") && !strings.HasSuffix(path, ".htm")
+	}
+	return false
+}
+
+// looksLikeRSSIndex сообщает, похожа ли ссылка на страницу-каталог лент (vnexpress.net/rss).
+func looksLikeRSSIndex(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	path := strings.ToLower(strings.TrimSuffix(u.Path, "/"))
+	last := path[strings.LastIndex(path, "/")+1:]
+	last = strings.TrimSuffix(strings.TrimSuffix(last, ".html"), ".htm")
+	return last == "rss" || last == "tin-rss" || last == "rss-feed" || last == "feeds"
+}
+
+// sameSite сообщает, ведёт ли ссылка на тот же сайт (с учётом поддоменов: www, rss и т.п.).
+func sameSite(link string, base *url.URL) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	root := strings.TrimPrefix(strings.ToLower(base.Hostname()), "www.")
+	return host == root || strings.HasSuffix(host, "."+root)
+}
+
+// WriteSiteYAML печатает блок для sites.yaml в формате существующих записей.
+func WriteSiteYAML(w io.Writer, site config.Site, feeds []DiscoveredFeed) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "  - id: %q\n", site.ID)
+	fmt.Fprintf(&sb, "    name: %q\n", site.Name)
+	fmt.Fprintf(&sb, "    url: %q\n", site.URL)
+	sb.WriteString("    rss_feeds:\n")
+	for _, feed := range feeds {
+		if feed.Title != "" {
+			fmt.Fprintf(&sb, "      # %s\n", strings.Join(strings.Fields(feed.Title), " "))
+		}
+		fmt.Fprintf(&sb, "      - url: %q\n", feed.URL)
+		if feed.Category == "" {
+			sb.WriteString("        category: \"\"  # Используем Gemini\n")
+		} else {
+			fmt.Fprintf(&sb, "        category: %q\n", feed.Category)
+		}
+	}
+	fmt.Fprintf(&sb, "    priority: %d\n", site.Priority)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
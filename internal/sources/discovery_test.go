@@ -0,0 +1,131 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+)
+
+func TestFeedDiscoverer_Discover(t *testing.T) {
+	const feedXML = `<rss><channel><item><title>Tin</title><link>https://example.com/1</link></item></channel></rss>`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>Báo Mẫu</title>
+			<link rel="alternate" type="application/rss+xml" title="Tin mới nhất" href="/rss/tin-moi-nhat.rss">
+		</head><body><a href="/rss">RSS</a><a href="/kinh-doanh">Kinh doanh</a></body></html>`)
+	})
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><ul>
+			<li><a href="/rss/kinh-doanh.rss">Kinh doanh</a></li>
+			<li><a href="/rss/so-hoa.rss">Số hóa</a></li>
+			<li><a href="/rss/the-thao.rss">Thể thao</a></li>
+			<li><a href="/rss/broken.rss">Hỏng</a></li>
+			<li><a href="/rss/tin-moi-nhat.rss">Tin mới nhất</a></li>
+			<li><a href="https://other.com/rss/x.rss">Чужая лента</a></li>
+		</ul></body></html>`)
+	})
+	for _, name := range []string{"tin-moi-nhat", "kinh-doanh", "so-hoa", "the-thao"} {
+		mux.HandleFunc("/rss/"+name+".rss", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, feedXML)
+		})
+	}
+	mux.HandleFunc("/rss/broken.rss", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>Cloudflare</body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cfg := config.Pipeline{
+		Categories: []string{"Экономика и бизнес", "Технология", "Общество"},
+		CategoryKeywords: map[string][]string{
+			"Экономика и бизнес": {"kinh-doanh"},
+			"Технология":         {"SO-HOA"},
+			"Спорт":              {"the-thao"}, // Нет в categories — не предлагается
+		},
+	}
+	discoverer := NewFeedDiscoverer(server.Client(), cfg, true)
+	feeds, title, err := discoverer.Discover(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if title != "Báo Mẫu" {
+		t.Errorf("site title = %q, want %q", title, "Báo Mẫu")
+	}
+
+	want := []DiscoveredFeed{
+		{URL: server.URL + "/rss/tin-moi-nhat.rss", Title: "Tin mới nhất", Category: ""},
+		{URL: server.URL + "/rss/kinh-doanh.rss", Title: "Kinh doanh", Category: "Экономика и бизнес"},
+		{URL: server.URL + "/rss/so-hoa.rss", Title: "Số hóa", Category: "Технология"},
+		{URL: server.URL + "/rss/the-thao.rss", Title: "Thể thao", Category: ""},
+	}
+	if len(feeds) != len(want) {
+		t.Fatalf("Discover() = %+v, want %d feeds", feeds, len(want))
+	}
+	for i := range want {
+		if feeds[i] != want[i] {
+			t.Errorf("feeds[%d] = %+v, want %+v", i, feeds[i], want[i])
+		}
+	}
+}
+
+func TestLooksLikeFeed(t *testing.T) {
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://vnexpress.net/rss/kinh-doanh.rss", true},
+		{"https://vietbao.vn/rss/xa-hoi", true},
+		{"https://example.com/feed", true},
+		{"https://example.com/rss.xml", true},
+		{"https://example.com/sitemap.xml", false},
+		{"https://example.com/rss", false},
+		{"https://example.com/rss/huong-dan.html", false},
+		{"https://example.com/kinh-doanh", false},
+	}
+	for _, tt := range tests {
+		if got := looksLikeFeed(tt.link); got != tt.want {
+			t.Errorf("looksLikeFeed(%q) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSiteYAML(t *testing.T) {
+	site := config.Site{ID: "mau", Name: "Báo Mẫu", URL: "https://mau.vn", Priority: 1}
+	feeds := []DiscoveredFeed{
+		{URL: "https://mau.vn/rss/home.rss", Title: "Trang chủ"},
+		{URL: "https://mau.vn/rss/kinh-te.rss", Title: "Kinh tế", Category: "Экономика и бизнес"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSiteYAML(&buf, site, feeds); err != nil {
+		t.Fatalf("WriteSiteYAML() error = %v", err)
+	}
+
+	// Блок должен разбираться тем же кодом, что и sites.yaml
+	path := filepath.Join(t.TempDir(), "sites.yaml")
+	if err := os.WriteFile(path, []byte("sites:\n"+buf.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := config.LoadSites(path)
+	if err != nil {
+		t.Fatalf("output is not valid sites.yaml:\n%s\nerror: %v", buf.String(), err)
+	}
+	if len(root.Sites) != 1 || len(root.Sites[0].RSSFeeds) != 2 || root.Sites[0].RSSFeeds[1].Category != "Экономика и бизнес" {
+		t.Errorf("parsed site = %+v", root.Sites)
+	}
+	if !strings.Contains(buf.String(), "# Kinh tế") {
+		t.Errorf("output should keep feed titles as comments:\n%s", buf.String())
+	}
+}
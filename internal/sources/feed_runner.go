@@ -0,0 +1,101 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// feedJob — одна лента или страница-листинг для загрузки.
+// fetch получает сохранённое состояние ленты и возвращает статьи и новые валидаторы HTTP-кэша.
+type feedJob struct {
+	site  config.Site
+	url   string
+	fetch func(ctx context.Context, prev news.FeedState) ([]news.ArticleRaw, news.FeedState, error)
+}
+
+// feedRunner загружает ленты параллельно с учётом лимитов на хост и ведёт их здоровье в state.Feeds.
+// Общий для RSSCollector и ScrapeCollector.
+type feedRunner struct {
+	kind    string // Для логов: "RSS feed", "scrape page"
+	workers int
+	limiter *hostLimiter
+	health  healthPolicy
+	clock   func() time.Time
+}
+
+func newFeedRunner(kind string, cfg config.Sources, limiter *hostLimiter, clock func() time.Time) *feedRunner {
+	return &feedRunner{
+		kind:    kind,
+		workers: maxConcurrency(cfg),
+		limiter: limiter,
+		health:  newHealthPolicy(cfg),
+		clock:   clock,
+	}
+}
+
+// run загружает все ленты и возвращает обновлённое состояние, статьи в порядке jobs
+// и ошибки отдельных лент (*FeedError). Ленты в карантине пропускаются.
+func (r *feedRunner) run(ctx context.Context, state news.State, jobs []feedJob) (news.State, []news.ArticleRaw, []error) {
+	now := r.clock()
+	items := make([][]news.ArticleRaw, len(jobs))
+	feeds := make([]*news.FeedState, len(jobs))
+	errs := make([]error, len(jobs))
+	runParallel(len(jobs), r.workers, func(i int) {
+		job := jobs[i]
+		prev := state.Feeds[job.url]
+		if r.health.quarantined(prev, now) {
+			log.Printf("Skipping quarantined %s %s for site %s until %s (%d failures in a row, last error: %s)",
+				r.kind, job.url, job.site.ID, prev.QuarantinedUntil.Format(time.RFC3339), prev.ConsecutiveFailures, prev.LastError)
+			return
+		}
+
+		release, err := r.limiter.acquire(ctx, job.url)
+		var validators news.FeedState
+		if err == nil {
+			items[i], validators, err = job.fetch(ctx, prev)
+			release()
+		}
+
+		var next news.FeedState
+		switch {
+		case errors.Is(err, errNotModified):
+			log.Printf("%s %s for site %s not modified since last run", r.kind, job.url, job.site.ID)
+			next = r.health.recordSuccess(prev, job.site.ID, prev.LastItemCount, now)
+		case err == nil:
+			next = r.health.recordSuccess(prev, job.site.ID, len(items[i]), now)
+		default:
+			// При ошибке одной ленты продолжаем обработку других
+			// Это позволяет частично обработать сайт, даже если одна из лент недоступна
+			log.Printf("Error fetching %s %s for site %s (%s): %v", r.kind, job.url, job.site.ID, job.site.Name, err)
+			errs[i] = &FeedError{SiteID: job.site.ID, URL: job.url, Err: err}
+			if ctx.Err() != nil {
+				// Отмена запуска — не вина ленты, историю не портим
+				return
+			}
+			next = r.health.recordFailure(prev, job.site.ID, err, now)
+			if r.health.quarantined(next, now) {
+				log.Printf("WARNING: %s %s for site %s quarantined until %s after %d failures in a row",
+					r.kind, job.url, job.site.ID, next.QuarantinedUntil.Format(time.RFC3339), next.ConsecutiveFailures)
+			}
+			feeds[i] = &next
+			return
+		}
+		next.ETag, next.LastModified = validators.ETag, validators.LastModified
+		feeds[i] = &next
+	})
+
+	var results []news.ArticleRaw
+	updates := make(map[string]news.FeedState, len(jobs))
+	for i, jobItems := range items {
+		results = append(results, jobItems...)
+		if feeds[i] != nil {
+			updates[jobs[i].url] = *feeds[i]
+		}
+	}
+	return withFeedStates(state, updates), results, errs
+}
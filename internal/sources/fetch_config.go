@@ -0,0 +1,84 @@
+package sources
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// Значения по умолчанию для config.Sources.
+const (
+	defaultMaxConcurrency     = 8
+	defaultPerHostConcurrency = 2
+	defaultPolitenessDelay    = 300 * time.Millisecond
+)
+
+// maxConcurrency возвращает размер общего пула загрузчиков.
+func maxConcurrency(cfg config.Sources) int {
+	if cfg.MaxConcurrency > 0 {
+		return cfg.MaxConcurrency
+	}
+	return defaultMaxConcurrency
+}
+
+// perHostConcurrency возвращает лимит одновременных запросов к хосту.
+func perHostConcurrency(cfg config.Sources) int {
+	if cfg.PerHostConcurrency > 0 {
+		return cfg.PerHostConcurrency
+	}
+	return defaultPerHostConcurrency
+}
+
+// politenessDelay возвращает паузу между запросами к одному хосту.
+// Отрицательное значение в конфиге отключает паузу.
+func politenessDelay(cfg config.Sources) time.Duration {
+	switch {
+	case cfg.PolitenessDelayMs > 0:
+		return time.Duration(cfg.PolitenessDelayMs) * time.Millisecond
+	case cfg.PolitenessDelayMs < 0:
+		return 0
+	default:
+		return defaultPolitenessDelay
+	}
+}
+
+// newSourceHostLimiter создаёт ограничитель запросов по хостам из настроек источников.
+func newSourceHostLimiter(cfg config.Sources, clk clock.Clock) *hostLimiter {
+	return newHostLimiter(perHostConcurrency(cfg), politenessDelay(cfg), clk)
+}
+
+// FeedError описывает ошибку загрузки одной ленты или страницы-листинга.
+// Коллекторы возвращают их объединёнными через errors.Join вместе с успешно собранными статьями.
+type FeedError struct {
+	SiteID string
+	URL    string
+	Err    error
+}
+
+func (e *FeedError) Error() string {
+	return fmt.Sprintf("site %s: %s: %v", e.SiteID, e.URL, e.Err)
+}
+
+func (e *FeedError) Unwrap() error {
+	return e.Err
+}
+
+// withFeedStates возвращает состояние с обновлёнными записями state.Feeds.
+// Исходная карта не изменяется: её могут читать другие коллекторы.
+func withFeedStates(state news.State, updates map[string]news.FeedState) news.State {
+	if len(updates) == 0 {
+		return state
+	}
+	feeds := make(map[string]news.FeedState, len(state.Feeds)+len(updates))
+	for url, feed := range state.Feeds {
+		feeds[url] = feed
+	}
+	for url, feed := range updates {
+		feeds[url] = feed
+	}
+	state.Feeds = feeds
+	return state
+}
@@ -0,0 +1,232 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
+	"github.com/maine/vietnam_bot_news/internal/urlnorm"
+)
+
+// defaultFullTextMaxAge — статьи старше этого возраста всё равно отсеет фильтр, их не догружаем.
+const defaultFullTextMaxAge = 24 * time.Hour
+
+// fullTextFetcher догружает полный текст статей для сайтов с full_text: true.
+// Анонс из ленты сохраняется в Metadata["teaser"], а RawContent заменяется текстом статьи.
+// Если лента не дала картинку или автора, они берутся из мета-тегов страницы.
+type fullTextFetcher struct {
+	client  *http.Client
+	limiter *hostLimiter
+	workers int
+	maxAge  time.Duration
+	clock   func() time.Time
+}
+
+func newFullTextFetcher(cfg config.Sources, client *http.Client, limiter *hostLimiter, clock func() time.Time) *fullTextFetcher {
+	maxAge := time.Duration(cfg.FullTextMaxAgeHours) * time.Hour
+	if maxAge <= 0 {
+		maxAge = defaultFullTextMaxAge
+	}
+	return &fullTextFetcher{
+		client:  client,
+		limiter: limiter,
+		workers: maxConcurrency(cfg),
+		maxAge:  maxAge,
+		clock:   clock,
+	}
+}
+
+// enrich догружает полный текст для подходящих статей и возвращает обновлённый срез.
+// Ошибки отдельных статей не прерывают сбор: у статьи остаётся анонс из ленты.
+func (f *fullTextFetcher) enrich(ctx context.Context, sites []config.Site, articles []news.ArticleRaw) []news.ArticleRaw {
+	fullTextSites := make(map[string]bool, len(sites))
+	for _, site := range sites {
+		if site.FullText {
+			fullTextSites[site.ID] = true
+		}
+	}
+	if len(fullTextSites) == 0 {
+		return articles
+	}
+
+	// Одна статья часто приходит из нескольких лент сайта (главная + рубрика), иногда с разными
+	// метками отслеживания — качаем её один раз по первой встреченной ссылке
+	cutoff := f.clock().Add(-f.maxAge)
+	byKey := make(map[string][]int)
+	var keys, urls []string
+	for i, article := range articles {
+		if !fullTextSites[article.Source] || article.URL == "" || article.PublishedAt.Before(cutoff) {
+			continue
+		}
+		key := article.CanonicalURL
+		if key == "" {
+			key = article.URL
+		}
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+			urls = append(urls, article.URL)
+		}
+		byKey[key] = append(byKey[key], i)
+	}
+	if len(urls) == 0 {
+		return articles
+	}
+
+	pages := make([]articlePage, len(urls))
+	runParallel(len(urls), f.workers, func(i int) {
+		page, err := f.fetch(ctx, urls[i])
+		if err != nil {
+			log.Printf("Full text: failed to fetch %s: %v", urls[i], err)
+			return
+		}
+		pages[i] = page
+	})
+
+	fetched := 0
+	for i, key := range keys {
+		if pages[i].text == "" {
+			continue
+		}
+		fetched++
+		for _, idx := range byKey[key] {
+			articles[idx] = withFullText(articles[idx], pages[i])
+		}
+	}
+	log.Printf("Full text: fetched %d/%d articles", fetched, len(urls))
+
+	return articles
+}
+
+// articlePage — то, что удалось извлечь со страницы статьи.
+type articlePage struct {
+	text      string
+	image     string // og:image
+	author    string // <meta name="author"> или article:author
+	canonical string // <link rel="canonical">, если он ведёт на статью того же сайта
+}
+
+// fetch скачивает страницу статьи с учётом лимита на хост и извлекает основной текст.
+func (f *fullTextFetcher) fetch(ctx context.Context, articleURL string) (articlePage, error) {
+	release, err := f.limiter.acquire(ctx, articleURL)
+	if err != nil {
+		return articlePage{}, err
+	}
+	resp, err := conditionalGet(ctx, f.client, articleURL, news.FeedState{})
+	release()
+	if err != nil {
+		return articlePage{}, err
+	}
+
+	// Страницы вьетнамских сайтов бывают в windows-1258: перекодируем до разбора
+	doc, err := html.Parse(newHTMLReader(resp.body, resp.contentType))
+	if err != nil {
+		return articlePage{}, fmt.Errorf("parse HTML: %w", err)
+	}
+
+	// Мета-теги читаем до extractArticleText: она вырезает служебные узлы из дерева
+	page := articlePage{
+		image:     absoluteHTTPURL(articleURL, pageMeta(doc, "og:image")),
+		author:    authorName(pageMeta(doc, "author", "article:author")),
+		canonical: pageCanonical(doc, articleURL),
+	}
+	page.text = extractArticleText(doc)
+	if page.text == "" {
+		return articlePage{}, fmt.Errorf("article body not found")
+	}
+	return page, nil
+}
+
+// pageMeta возвращает content первого <meta> с property или name из keys (в порядке keys).
+func pageMeta(doc *html.Node, keys ...string) string {
+	found := make(map[string]string)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			for _, attr := range []string{"property", "name"} {
+				key := strings.ToLower(strings.TrimSpace(nodeAttr(n, attr)))
+				if _, ok := found[key]; key != "" && !ok {
+					found[key] = strings.TrimSpace(nodeAttr(n, "content"))
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	for _, key := range keys {
+		if value := found[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// pageCanonical возвращает абсолютную ссылку из <link rel="canonical">.
+// Canonical на другой сайт или на главную страницу (частая ошибка шаблонов) игнорируется.
+func pageCanonical(doc *html.Node, articleURL string) string {
+	// Сравниваем канонические хосты: страница m.site.vn обычно указывает canonical на www.site.vn
+	base, err := url.Parse(urlnorm.Canonical(articleURL))
+	if err != nil {
+		return ""
+	}
+	var href string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if href != "" {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "link" && strings.EqualFold(strings.TrimSpace(nodeAttr(n, "rel")), "canonical") {
+			href = absoluteHTTPURL(articleURL, nodeAttr(n, "href"))
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	if href == "" || !sameSite(urlnorm.Canonical(href), base) {
+		return ""
+	}
+	if u, err := url.Parse(href); err != nil || strings.Trim(u.Path, "/") == "" {
+		return ""
+	}
+	return href
+}
+
+// withFullText подменяет RawContent полным текстом, сохраняя анонс в метаданных.
+// Картинка и автор со страницы не перезаписывают данные из ленты. rel=canonical страницы
+// сохраняется в метаданных как дополнительный ключ дедупликации: ID и CanonicalURL остаются
+// от ссылки из ленты, чтобы не зависеть от того, удалось ли загрузить страницу в этом запуске.
+func withFullText(article news.ArticleRaw, page articlePage) news.ArticleRaw {
+	metadata := make(map[string]string, len(article.Metadata)+4)
+	for k, v := range article.Metadata {
+		metadata[k] = v
+	}
+	metadata["teaser"] = article.RawContent
+	metadata["full_text"] = "1"
+	if metadata[news.MetaImageURL] == "" && page.image != "" {
+		metadata[news.MetaImageURL] = page.image
+	}
+	if metadata[news.MetaAuthor] == "" && page.author != "" {
+		metadata[news.MetaAuthor] = page.author
+	}
+
+	article.Metadata = metadata
+	article.RawContent = textnorm.NFC(page.text)
+	if canonical := urlnorm.Canonical(page.canonical); canonical != "" && canonical != article.CanonicalURL {
+		metadata[news.MetaPageCanonical] = canonical
+	}
+	return article
+}
@@ -0,0 +1,320 @@
+package sources
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
+)
+
+var articlePageHTML = `<!DOCTYPE html>
+<html><head><title>Tin</title><script>var x = 1;</script>
+<meta property="og:image" content="/og.jpg"><meta name="author" content="Trần Thị B"></head>
+<body>
+<header class="site-header"><a href="/">Trang chủ</a><a href="/thoi-su">Thời sự</a></header>
+<nav class="menu"><a href="/kinh-te">Kinh tế</a></nav>
+<div class="container">
+  <div class="detail-content">
+    <p>` + strings.Repeat("Đoạn văn đầu tiên của bài báo, có nội dung đầy đủ và dài. ", 4) + `</p>
+    <figure><img src="1.jpg"><figcaption>Chú thích ảnh</figcaption></figure>
+    <p>` + strings.Repeat("Đoạn văn thứ hai, tiếp tục câu chuyện với nhiều chi tiết. ", 4) + `</p>
+    <div class="box-tinlienquan"><p>` + strings.Repeat("Tin liên quan rất dài nhưng không thuộc bài. ", 4) + `</p></div>
+  </div>
+  <aside class="sidebar"><p>` + strings.Repeat("Tin đọc nhiều trong cột bên. ", 5) + `</p></aside>
+</div>
+<footer><p>Bản quyền thuộc về tòa soạn, mọi hình thức sao chép phải ghi rõ nguồn.</p></footer>
+</body></html>`
+
+func TestExtractArticleText(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(articlePageHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := extractArticleText(doc)
+	if text == "" {
+		t.Fatal("extractArticleText() returned empty text")
+	}
+
+	paragraphs := strings.Split(text, "\n\n")
+	if len(paragraphs) != 2 {
+		t.Errorf("extractArticleText() paragraphs = %d, want 2:\n%s", len(paragraphs), text)
+	}
+	for _, unwanted := range []string{"Trang chủ", "Kinh tế", "Chú thích ảnh", "Tin liên quan", "cột bên", "Bản quyền", "var x"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("extractArticleText() should drop boilerplate %q", unwanted)
+		}
+	}
+}
+
+func TestExtractArticleText_NoBody(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body><nav><p>Menu</p></nav><p>Ngắn.</p></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := extractArticleText(doc); text != "" {
+		t.Errorf("extractArticleText() = %q, want empty for page without article body", text)
+	}
+}
+
+func TestFullTextFetcher_enrich(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			_, _ = w.Write([]byte(articlePageHTML))
+		}
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	sites := []config.Site{
+		{ID: "full", FullText: true},
+		{ID: "teaser-only"},
+	}
+	articles := []news.ArticleRaw{
+		{Source: "full", URL: server.URL + "/a", PublishedAt: now, RawContent: "Teaser A"},
+		{Source: "full", URL: server.URL + "/b", PublishedAt: now, RawContent: "Teaser B", Metadata: map[string]string{news.MetaImageURL: "https://cdn.example/feed.jpg"}},
+		{Source: "full", URL: server.URL + "/a", PublishedAt: now, RawContent: "Teaser A (other feed)"},
+		{Source: "full", URL: server.URL + "/broken", PublishedAt: now, RawContent: "Teaser broken"},
+		{Source: "full", URL: server.URL + "/old", PublishedAt: now.Add(-48 * time.Hour), RawContent: "Teaser old"},
+		{Source: "teaser-only", URL: server.URL + "/c", PublishedAt: now, RawContent: "Teaser C"},
+	}
+
+	fetcher := newFullTextFetcher(config.Sources{}, server.Client(), newHostLimiter(1, 0, nil), func() time.Time { return now })
+	got := fetcher.enrich(context.Background(), sites, articles)
+
+	for _, idx := range []int{0, 1, 2} {
+		if !strings.HasPrefix(got[idx].RawContent, "Đoạn văn đầu tiên") {
+			t.Errorf("article %d RawContent = %q, want full text", idx, got[idx].RawContent)
+		}
+		if got[idx].Metadata["full_text"] != "1" {
+			t.Errorf("article %d should be marked as full_text", idx)
+		}
+	}
+	if img := got[0].ImageURL(); img != server.URL+"/og.jpg" {
+		t.Errorf("article 0 image = %q, want og:image from page", img)
+	}
+	if author := got[0].Author(); author != "Trần Thị B" {
+		t.Errorf("article 0 author = %q, want meta author from page", author)
+	}
+	if img := got[1].ImageURL(); img != "https://cdn.example/feed.jpg" {
+		t.Errorf("article 1 image = %q, feed image must not be overwritten", img)
+	}
+	if got[2].Metadata["teaser"] != "Teaser A (other feed)" {
+		t.Errorf("teaser = %q, want original RawContent kept in metadata", got[2].Metadata["teaser"])
+	}
+	for _, idx := range []int{3, 4, 5} {
+		if got[idx].RawContent != articles[idx].RawContent || got[idx].Metadata["full_text"] != "" {
+			t.Errorf("article %d should keep teaser, got %q", idx, got[idx].RawContent)
+		}
+	}
+	if maxInFlight > 1 {
+		t.Errorf("max concurrent requests to host = %d, want <= 1", maxInFlight)
+	}
+}
+
+func TestHostLimiter_acquire_RespectsContext(t *testing.T) {
+	limiter := newHostLimiter(1, 0, nil)
+	release, err := limiter.acquire(context.Background(), "https://example.com/a")
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	defer release()
+
+	// Другой хост не блокируется
+	otherRelease, err := limiter.acquire(context.Background(), "https://other.com/a")
+	if err != nil {
+		t.Fatalf("acquire() for other host error = %v", err)
+	}
+	otherRelease()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := limiter.acquire(ctx, "https://EXAMPLE.com/b"); err == nil {
+		t.Error("acquire() should fail when host slot is busy and context is cancelled")
+	}
+}
+
+func TestHostLimiter_acquire_PolitenessDelay(t *testing.T) {
+	const delay = 300 * time.Millisecond
+	clk := clock.NewFake(time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC))
+	limiter := newHostLimiter(2, delay, clk)
+
+	for i := 0; i < 3; i++ {
+		release, err := limiter.acquire(context.Background(), "https://example.com/a")
+		if err != nil {
+			t.Fatalf("acquire() error = %v", err)
+		}
+		release()
+	}
+	if err := limiter.pace(context.Background(), "https://other.com/a"); err != nil {
+		t.Fatalf("pace() error = %v", err)
+	}
+
+	// Три запроса к одному хосту: между ними две паузы, даже при свободных слотах; другой хост не ждёт
+	if slept := clk.Slept(); len(slept) != 2 || slept[0] != delay || slept[1] != delay {
+		t.Errorf("Slept() = %v, want two pauses of %v", slept, delay)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	got := make([]int, 20)
+	runParallel(len(got), 3, func(i int) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+		got[i] = i * i
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	})
+
+	for i, v := range got {
+		if v != i*i {
+			t.Errorf("got[%d] = %d, want %d", i, v, i*i)
+		}
+	}
+	if maxInFlight > 3 {
+		t.Errorf("max workers = %d, want <= 3", maxInFlight)
+	}
+}
+
+func TestPageCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"relative canonical", `<link rel="canonical" href="/thoi-su/tin-1.html">`, "https://m.vnexpress.net/thoi-su/tin-1.html"},
+		{"canonical on desktop host", `<link rel="Canonical" href="https://www.vnexpress.net/tin-1.html">`, "https://www.vnexpress.net/tin-1.html"},
+		{"other site ignored", `<link rel="canonical" href="https://other.vn/tin-1.html">`, ""},
+		{"home page ignored", `<link rel="canonical" href="https://vnexpress.net/">`, ""},
+		{"no canonical", `<link rel="stylesheet" href="/a.css">`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader("<html><head>" + tt.head + "</head><body></body></html>"))
+			if err != nil {
+				t.Fatalf("html.Parse() error = %v", err)
+			}
+			if got := pageCanonical(doc, "https://m.vnexpress.net/tin-1.html?utm_source=rss"); got != tt.want {
+				t.Errorf("pageCanonical() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFullText_Canonical(t *testing.T) {
+	published := time.Date(2024, 12, 3, 10, 0, 0, 0, time.UTC)
+	article := news.ArticleRaw{
+		ID:           buildArticleID("vnexpress", "https://vnexpress.net/amp/tin-1.html", published),
+		Source:       "vnexpress",
+		URL:          "https://vnexpress.net/amp/tin-1.html",
+		CanonicalURL: "https://vnexpress.net/tin-1.html",
+		PublishedAt:  published,
+	}
+
+	got := withFullText(article, articlePage{text: "Nội dung", canonical: "https://vnexpress.net/thoi-su/tin-1.html?utm_source=x"})
+	if got.PageCanonicalURL() != "https://vnexpress.net/thoi-su/tin-1.html" {
+		t.Errorf("PageCanonicalURL() = %q, want rel=canonical normalized", got.PageCanonicalURL())
+	}
+	// ID и ключ дедупликации не должны зависеть от того, загрузилась ли страница
+	if got.ID != article.ID || got.CanonicalURL != article.CanonicalURL || got.URL != article.URL {
+		t.Errorf("ID/CanonicalURL/URL = %q %q %q, the feed link must be kept", got.ID, got.CanonicalURL, got.URL)
+	}
+
+	same := withFullText(article, articlePage{text: "Nội dung", canonical: "https://vnexpress.net/tin-1.html"})
+	if same.PageCanonicalURL() != "" {
+		t.Errorf("PageCanonicalURL() = %q, want empty when it matches the feed link", same.PageCanonicalURL())
+	}
+}
+
+func TestFullTextFetcher_fetch_Charset(t *testing.T) {
+	// «Việt Nam» в windows-1258: ê (0xEA) и комбинирующая точка снизу (0xF2)
+	page := func(head, word string) string {
+		return `<html><head>` + head + `</head><body><div class="detail-content"><p>` +
+			strings.Repeat("Bai bao ve "+word+" co noi dung day du va rat dai. ", 6) + `</p></div></body></html>`
+	}
+	const cp1258 = "Vi\xea\xf2t Nam"
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+	}{
+		{
+			name: "meta charset",
+			body: page(`<meta charset="windows-1258">`, cp1258),
+		},
+		{
+			name:        "charset from Content-Type",
+			body:        page("", cp1258),
+			contentType: "text/html; charset=windows-1258",
+		},
+		{
+			name:        "meta wins when Content-Type wrongly claims UTF-8",
+			body:        page(`<meta http-equiv="Content-Type" content="text/html; charset=windows-1258">`, cp1258),
+			contentType: "text/html; charset=utf-8",
+		},
+		{
+			// Длинный ASCII-заголовок: по первым 1024 байтам кодировку не угадать
+			name: "undeclared UTF-8 stays UTF-8",
+			body: page(`<script>`+strings.Repeat("var a = 1;\n", 120)+`</script>`, "Việt Nam"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			fetcher := newFullTextFetcher(config.Sources{}, server.Client(), newHostLimiter(1, 0, nil), time.Now)
+			got, err := fetcher.fetch(context.Background(), server.URL+"/a")
+			if err != nil {
+				t.Fatalf("fetch() error = %v", err)
+			}
+			if text := textnorm.NFC(got.text); !strings.Contains(text, "Bai bao ve Việt Nam co") {
+				t.Errorf("text = %q, want decoded Vietnamese", text)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// errNoItems возвращается, если лента ответила 200, но в ней нет ни одной разбираемой статьи.
+// Обычно это сменившаяся вёрстка или заглушка вместо ленты, поэтому считается ошибкой.
+var errNoItems = errors.New("response has no parsable items")
+
+// Значения по умолчанию для карантина лент.
+const (
+	defaultQuarantineAfterFailures = 3
+	defaultQuarantineBase          = 24 * time.Hour
+	defaultQuarantineMax           = 7 * 24 * time.Hour
+)
+
+// healthPolicy ведёт историю здоровья лент и решает, когда отправлять их в карантин.
+// После quarantineAfter ошибок подряд лента пропускается на base, затем срок удваивается
+// с каждой неудачной повторной пробой (но не больше max).
+type healthPolicy struct {
+	quarantineAfter int
+	base            time.Duration
+	max             time.Duration
+}
+
+func newHealthPolicy(cfg config.Sources) healthPolicy {
+	p := healthPolicy{
+		quarantineAfter: cfg.QuarantineAfterFailures,
+		base:            time.Duration(cfg.QuarantineBaseHours) * time.Hour,
+		max:             time.Duration(cfg.QuarantineMaxHours) * time.Hour,
+	}
+	if p.quarantineAfter <= 0 {
+		p.quarantineAfter = defaultQuarantineAfterFailures
+	}
+	if p.base <= 0 {
+		p.base = defaultQuarantineBase
+	}
+	if p.max <= 0 {
+		p.max = defaultQuarantineMax
+	}
+	if p.max < p.base {
+		p.max = p.base
+	}
+	return p
+}
+
+// quarantined сообщает, нужно ли пропустить ленту в этом запуске.
+func (p healthPolicy) quarantined(feed news.FeedState, now time.Time) bool {
+	return now.Before(feed.QuarantinedUntil)
+}
+
+// recordSuccess отмечает успешную загрузку ленты с items статьями и снимает карантин.
+func (p healthPolicy) recordSuccess(feed news.FeedState, siteID string, items int, now time.Time) news.FeedState {
+	feed.SiteID = siteID
+	feed.LastCheckedAt = now
+	feed.LastSuccessAt = now
+	feed.LastError = ""
+	feed.LastItemCount = items
+	feed.Successes++
+	feed.ConsecutiveFailures = 0
+	feed.ConsecutiveEmpty = 0
+	feed.QuarantinedUntil = time.Time{}
+	return feed
+}
+
+// recordFailure отмечает ошибку загрузки и при необходимости отправляет ленту в карантин.
+// Валидаторы HTTP-кэша сохраняются прежними.
+func (p healthPolicy) recordFailure(feed news.FeedState, siteID string, err error, now time.Time) news.FeedState {
+	feed.SiteID = siteID
+	feed.LastCheckedAt = now
+	feed.LastError = err.Error()
+	feed.Failures++
+	feed.ConsecutiveFailures++
+	if errors.Is(err, errNoItems) {
+		feed.ConsecutiveEmpty++
+	} else {
+		feed.ConsecutiveEmpty = 0
+	}
+
+	if over := feed.ConsecutiveFailures - p.quarantineAfter; over >= 0 {
+		delay := p.base
+		for i := 0; i < over && delay < p.max; i++ {
+			delay *= 2
+		}
+		if delay > p.max {
+			delay = p.max
+		}
+		feed.QuarantinedUntil = now.Add(delay)
+	}
+	return feed
+}
+
+// Статусы лент в отчёте о здоровье.
+const (
+	FeedStatusOK          = "ok"
+	FeedStatusEmpty       = "empty" // 200 OK, но ни одной статьи
+	FeedStatusFailing     = "failing"
+	FeedStatusQuarantined = "quarantined"
+	FeedStatusUnchecked   = "unchecked" // Лента ещё ни разу не загружалась
+)
+
+// FeedHealth — строка отчёта о здоровье одной ленты или страницы-листинга.
+type FeedHealth struct {
+	SiteID string
+	URL    string
+	Status string
+	State  news.FeedState
+}
+
+// HealthReport строит отчёт о здоровье всех лент, sitemap и страниц-листингов из конфига
+// по данным state.Feeds. Порядок строк совпадает с порядком в sites.yaml.
+func HealthReport(sites []config.Site, state news.State, now time.Time) []FeedHealth {
+	var report []FeedHealth
+	add := func(siteID, url string) {
+		feed, ok := state.Feeds[url]
+		report = append(report, FeedHealth{
+			SiteID: siteID,
+			URL:    url,
+			Status: feedStatus(feed, ok, now),
+			State:  feed,
+		})
+	}
+	for _, site := range sites {
+		for _, feed := range siteRSSFeeds(site) {
+			add(site.ID, feed.URL)
+		}
+		for _, sitemap := range site.Sitemaps {
+			add(site.ID, sitemap.URL)
+		}
+		if site.Scrape != nil {
+			for _, page := range site.Scrape.Pages {
+				add(site.ID, page.URL)
+			}
+		}
+	}
+	return report
+}
+
+func feedStatus(feed news.FeedState, known bool, now time.Time) string {
+	switch {
+	case !known || feed.LastCheckedAt.IsZero():
+		return FeedStatusUnchecked
+	case now.Before(feed.QuarantinedUntil):
+		return FeedStatusQuarantined
+	case feed.ConsecutiveEmpty > 0:
+		return FeedStatusEmpty
+	case feed.ConsecutiveFailures > 0:
+		return FeedStatusFailing
+	default:
+		return FeedStatusOK
+	}
+}
+
+// WriteHealthReport выводит отчёт таблицей в порядке sites.yaml, чтобы его было удобно сверять с конфигом.
+func WriteHealthReport(w io.Writer, report []FeedHealth) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SITE\tSTATUS\tITEMS\tLAST OK\tFAILS\tQUARANTINED UNTIL\tURL\tLAST ERROR")
+	for _, row := range report {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%d/%d\t%s\t%s\t%s\n",
+			row.SiteID,
+			row.Status,
+			row.State.LastItemCount,
+			formatReportTime(row.State.LastSuccessAt),
+			row.State.ConsecutiveFailures,
+			row.State.Failures,
+			formatReportTime(row.State.QuarantinedUntil),
+			row.URL,
+			row.State.LastError,
+		)
+	}
+	return tw.Flush()
+}
+
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,146 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+func TestHealthPolicy_recordFailure(t *testing.T) {
+	now := time.Date(2024, 12, 3, 8, 0, 0, 0, time.UTC)
+	policy := newHealthPolicy(config.Sources{QuarantineAfterFailures: 3, QuarantineBaseHours: 24, QuarantineMaxHours: 72})
+
+	tests := []struct {
+		name          string
+		failuresInRow int
+		want          time.Duration // 0 — без карантина
+	}{
+		{name: "below threshold", failuresInRow: 2, want: 0},
+		{name: "threshold reached", failuresInRow: 3, want: 24 * time.Hour},
+		{name: "failed re-probe doubles", failuresInRow: 4, want: 48 * time.Hour},
+		{name: "capped at max", failuresInRow: 6, want: 72 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var feed news.FeedState
+			for i := 0; i < tt.failuresInRow; i++ {
+				feed = policy.recordFailure(feed, "site", errors.New("boom"), now)
+			}
+			var got time.Duration
+			if !feed.QuarantinedUntil.IsZero() {
+				got = feed.QuarantinedUntil.Sub(now)
+			}
+			if got != tt.want {
+				t.Errorf("quarantine = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthPolicy_recordSuccess_ClearsQuarantine(t *testing.T) {
+	now := time.Date(2024, 12, 3, 8, 0, 0, 0, time.UTC)
+	policy := newHealthPolicy(config.Sources{QuarantineAfterFailures: 1})
+
+	feed := policy.recordFailure(news.FeedState{}, "site", errNoItems, now)
+	if !policy.quarantined(feed, now) || feed.ConsecutiveEmpty != 1 {
+		t.Fatalf("feed should be quarantined and flagged empty, got %+v", feed)
+	}
+
+	feed = policy.recordSuccess(feed, "site", 5, now.Add(48*time.Hour))
+	if policy.quarantined(feed, now) || feed.ConsecutiveFailures != 0 || feed.ConsecutiveEmpty != 0 || feed.LastError != "" {
+		t.Errorf("success should reset failure history, got %+v", feed)
+	}
+	if feed.Successes != 1 || feed.Failures != 1 || feed.LastItemCount != 5 {
+		t.Errorf("totals = %d/%d items %d, want 1/1 items 5", feed.Successes, feed.Failures, feed.LastItemCount)
+	}
+}
+
+func TestRSSCollector_Collect_Health(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/broken":
+			http.Error(w, "boom", http.StatusBadGateway)
+		case "/empty":
+			fmt.Fprint(w, `<rss><channel><title>Empty</title></channel></rss>`)
+		default:
+			fmt.Fprint(w, `<rss><channel><item><title>Tin</title><link>https://example.com/1</link></item></channel></rss>`)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 12, 3, 8, 0, 0, 0, time.UTC)
+	sites := []config.Site{{ID: "a", RSSFeeds: []config.RSSFeed{
+		{URL: server.URL + "/ok"},
+		{URL: server.URL + "/broken"},
+		{URL: server.URL + "/empty"},
+	}}}
+	cfg := config.Sources{PolitenessDelayMs: -1, QuarantineAfterFailures: 2, QuarantineBaseHours: 24}
+	collector := NewRSSCollector(sites, cfg, server.Client(), clock.NewFake(now))
+
+	// Два запуска подряд: после второй ошибки ленты уходят в карантин, третий запуск их не запрашивает
+	state := news.State{}
+	for run := 0; run < 3; run++ {
+		var err error
+		state, _, err = collector.Collect(context.Background(), state)
+		if run < 2 && !errors.Is(err, errNoItems) {
+			t.Fatalf("run %d: error = %v, want errNoItems among feed errors", run, err)
+		}
+	}
+
+	if requests["/ok"] != 3 || requests["/broken"] != 2 || requests["/empty"] != 2 {
+		t.Errorf("requests = %v, want ok:3 broken:2 empty:2", requests)
+	}
+
+	report := HealthReport(sites, state, now)
+	wantStatuses := []string{FeedStatusOK, FeedStatusQuarantined, FeedStatusQuarantined}
+	for i, want := range wantStatuses {
+		if report[i].Status != want {
+			t.Errorf("report[%d] (%s) status = %q, want %q", i, report[i].URL, report[i].Status, want)
+		}
+	}
+	if report[2].State.ConsecutiveEmpty != 2 {
+		t.Errorf("empty feed ConsecutiveEmpty = %d, want 2", report[2].State.ConsecutiveEmpty)
+	}
+
+	// После окончания карантина лента снова проверяется и статус показывает, что она всё ещё пустая
+	later := HealthReport(sites, state, now.Add(25*time.Hour))
+	if later[2].Status != FeedStatusEmpty {
+		t.Errorf("status after quarantine = %q, want %q", later[2].Status, FeedStatusEmpty)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHealthReport(&buf, report); err != nil {
+		t.Fatalf("WriteHealthReport() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "unexpected status 502") {
+		t.Errorf("report should include last error, got:\n%s", buf.String())
+	}
+}
+
+func TestHealthReport_Unchecked(t *testing.T) {
+	sites := []config.Site{{
+		ID:     "scraped",
+		Scrape: &config.Scrape{Pages: []config.ScrapePage{{URL: "https://example.com/list"}}},
+	}}
+	report := HealthReport(sites, news.State{}, time.Now())
+	if len(report) != 1 || report[0].Status != FeedStatusUnchecked {
+		t.Errorf("HealthReport() = %+v, want one unchecked scrape page", report)
+	}
+}
@@ -0,0 +1,134 @@
+package sources
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+)
+
+// hostLimiter ограничивает число одновременных запросов к одному хосту
+// и выдерживает паузу вежливости между последовательными запросами к нему.
+type hostLimiter struct {
+	limit int
+	delay time.Duration
+	clock clock.Clock
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+	next  map[string]time.Time // раньше этого времени следующий запрос к хосту не отправляем
+}
+
+// newHostLimiter создаёт ограничитель. Если clk == nil, используются системные часы.
+func newHostLimiter(limit int, delay time.Duration, clk clock.Clock) *hostLimiter {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &hostLimiter{
+		limit: limit,
+		delay: delay,
+		clock: clock.OrSystem(clk),
+		slots: make(map[string]chan struct{}),
+		next:  make(map[string]time.Time),
+	}
+}
+
+// acquire занимает слот для хоста из rawURL и возвращает функцию освобождения.
+// Ожидание прерывается при отмене контекста.
+func (l *hostLimiter) acquire(ctx context.Context, rawURL string) (func(), error) {
+	host := hostOf(rawURL)
+	slot := l.slot(host)
+	select {
+	case slot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-slot }
+
+	if wait := l.reserve(host); wait > 0 {
+		if err := l.clock.Sleep(ctx, wait); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// pace выдерживает паузу вежливости перед запросом к хосту, не занимая слот.
+// Нужен для последовательных запросов внутри уже занятого слота (например, дочерние sitemap).
+func (l *hostLimiter) pace(ctx context.Context, rawURL string) error {
+	wait := l.reserve(hostOf(rawURL))
+	if wait <= 0 {
+		return nil
+	}
+	return l.clock.Sleep(ctx, wait)
+}
+
+func (l *hostLimiter) slot(host string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = make(chan struct{}, l.limit)
+		l.slots[host] = slot
+	}
+	return slot
+}
+
+// reserve бронирует ближайшее время запроса к хосту с учётом паузы вежливости
+// и возвращает, сколько нужно подождать до него.
+func (l *hostLimiter) reserve(host string) time.Duration {
+	if l.delay <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	start := l.next[host]
+	if start.Before(now) {
+		start = now
+	}
+	l.next[host] = start.Add(l.delay)
+	return start.Sub(now)
+}
+
+// hostOf возвращает хост URL в нижнем регистре (пустую строку для некорректных URL).
+func hostOf(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// runParallel вызывает fn для индексов [0, n) не более чем в workers горутинах.
+// Результаты fn должен складывать по индексу, чтобы порядок не зависел от порядка завершения.
+func runParallel(n, workers int, fn func(i int)) {
+	if n == 0 {
+		return
+	}
+	if workers <= 0 || workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
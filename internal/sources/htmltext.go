@@ -0,0 +1,41 @@
+package sources
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlToText превращает HTML-анонс или content:encoded из ленты в обычный текст:
+// сущности декодируются, абзацы разделяются пустой строкой, скрипты, картинки с подписями
+// и блоки «читайте также» выбрасываются. Строка без разметки возвращается без изменений,
+// кроме схлопывания пробелов.
+func htmlToText(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return collapseParagraphs(s)
+	}
+
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(s), body)
+	if err != nil {
+		return collapseParagraphs(s)
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	pruneBoilerplate(body)
+	return renderText(body)
+}
+
+// collapseParagraphs схлопывает пробелы внутри строк и оставляет между абзацами одну пустую строку,
+// как renderText.
+func collapseParagraphs(s string) string {
+	var paragraphs []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
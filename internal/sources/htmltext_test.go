@@ -0,0 +1,82 @@
+package sources
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+)
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "plain text",
+			in:   "  Giá   vàng tăng\n\n\nmạnh  ",
+			want: "Giá vàng tăng\n\nmạnh",
+		},
+		{
+			name: "entities without markup",
+			in:   "Lãi suất &amp; tỷ giá",
+			want: "Lãi suất & tỷ giá",
+		},
+		{
+			name: "paragraphs and inline tags",
+			in:   `<p>Hà Nội <b>mưa</b> lớn.</p><p>Nhiều tuyến <a href="/x">phố</a> ngập.<br>Giao thông ùn tắc.</p>`,
+			want: "Hà Nội mưa lớn.\n\nNhiều tuyến phố ngập.\n\nGiao thông ùn tắc.",
+		},
+		{
+			name: "teaser with image link",
+			in:   `<a href="https://vnexpress.net/tin-1.html"><img src="https://i.vnecdn.net/1.jpg" /></a></br>Chính phủ thông qua đề án mới.`,
+			want: "Chính phủ thông qua đề án mới.",
+		},
+		{
+			name: "scripts, figures and captions dropped",
+			in: `<figure><img src="a.jpg"><figcaption>Ảnh: TTXVN</figcaption></figure>` +
+				`<script>var x = 1;</script>` +
+				`<table class="tplCaption"><tr><td><img src="b.jpg"></td></tr><tr><td><p class="Image">Hiện trường vụ việc</p></td></tr></table>` +
+				`<p>Nội dung chính.</p>` +
+				`<div class="related-news"><a href="/y">Tin liên quan</a></div>`,
+			want: "Nội dung chính.",
+		},
+		{
+			name: "comparison sign is not markup",
+			in:   "Lạm phát < 4% trong năm",
+			want: "Lạm phát < 4% trong năm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(tt.in); got != tt.want {
+				t.Errorf("htmlToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewArticle_StripsHTML(t *testing.T) {
+	site := config.Site{ID: "vnexpress", Name: "VnExpress"}
+	published := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+
+	htmlItem := rssItem{
+		Title:       "Tin 1",
+		Link:        "https://vnexpress.net/tin-1.html",
+		Description: `<img src="https://i.vnecdn.net/1.jpg"/>Giá xăng giảm &amp; ổn định.`,
+	}
+	article := newArticle(site, "", 1, htmlItem, published)
+	if article.RawContent != "Giá xăng giảm & ổn định." {
+		t.Errorf("RawContent = %q", article.RawContent)
+	}
+	if article.RawHTML != htmlItem.Description {
+		t.Errorf("RawHTML = %q, want original description", article.RawHTML)
+	}
+
+	plainItem := rssItem{Title: "Tin 2", Link: "https://vnexpress.net/tin-2.html", Description: "Giá xăng giảm."}
+	if article := newArticle(site, "", 1, plainItem, published); article.RawHTML != "" || article.RawContent != "Giá xăng giảm." {
+		t.Errorf("plain article = %q / %q, want no RawHTML", article.RawContent, article.RawHTML)
+	}
+}
@@ -0,0 +1,83 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// userAgent добавляется ко всем запросам, чтобы избежать блокировки (403 Forbidden).
+const userAgent = "Mozilla/5.0 (compatible; RSSBot/1.0; +https://github.com/maine/vietnam_bot_news)"
+
+// errNotModified возвращается conditionalGet, если сервер ответил 304 Not Modified.
+var errNotModified = errors.New("not modified")
+
+// httpResponse — результат GET-запроса.
+type httpResponse struct {
+	body        []byte
+	contentType string         // Заголовок Content-Type (нужен для определения кодировки)
+	validators  news.FeedState // Валидаторы HTTP-кэша для следующего условного запроса
+}
+
+// httpGet выполняет GET-запрос с общим User-Agent и возвращает тело ответа.
+func httpGet(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	resp, err := conditionalGet(ctx, client, url, news.FeedState{})
+	return resp.body, err
+}
+
+// conditionalGet выполняет GET-запрос с валидаторами из prev (If-None-Match / If-Modified-Since).
+// Если ресурс не изменился, возвращает errNotModified и валидаторы prev
+// (обновлённые, если сервер прислал новые).
+func conditionalGet(ctx context.Context, client *http.Client, url string, prev news.FeedState) (httpResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return httpResponse{validators: prev}, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if prev.ETag != "" {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return httpResponse{validators: prev}, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		next := prev
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			next.ETag = etag
+		}
+		if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+			next.LastModified = lastModified
+		}
+		return httpResponse{validators: next}, errNotModified
+	}
+
+	if resp.StatusCode >= 400 {
+		return httpResponse{validators: prev}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return httpResponse{validators: prev}, fmt.Errorf("read body: %w", err)
+	}
+
+	// Валидаторы берём только из полного ответа: если сервер их не прислал, кэш ленты сбрасывается
+	return httpResponse{
+		body:        body,
+		contentType: resp.Header.Get("Content-Type"),
+		validators: news.FeedState{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
@@ -0,0 +1,161 @@
+package sources
+
+import (
+	"html"
+	"net/mail"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
+)
+
+// maxArticleTags ограничивает число рубрик издателя, сохраняемых у статьи.
+const maxArticleTags = 10
+
+// rssEnclosure — вложение элемента ленты: <enclosure>, <media:content>, <media:thumbnail>
+// или Atom-ссылка rel="enclosure".
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+// isImage сообщает, что вложение — картинка: по MIME-типу, атрибуту medium или расширению файла.
+func (e rssEnclosure) isImage() bool {
+	if e.Type != "" || e.Medium != "" {
+		return strings.HasPrefix(strings.ToLower(e.Type), "image/") || strings.EqualFold(e.Medium, "image")
+	}
+	u, err := url.Parse(strings.TrimSpace(e.URL))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(path.Ext(u.Path)) {
+	case ".jpg", ".jpeg", ".png", ".webp", ".gif":
+		return true
+	}
+	return false
+}
+
+// itemMetadata добавляет в metadata картинку, авторов и рубрики издателя из элемента ленты.
+func itemMetadata(metadata map[string]string, item rssItem) {
+	if image := itemImage(item); image != "" {
+		metadata[news.MetaImageURL] = image
+	}
+	if authors := itemAuthors(item); len(authors) > 0 {
+		metadata[news.MetaAuthor] = strings.Join(authors, ", ")
+	}
+	if tags := itemCategories(item); len(tags) > 0 {
+		metadata[news.MetaTags] = strings.Join(tags, news.TagsSeparator)
+	}
+}
+
+// imgSrcRe находит первую картинку в HTML-анонсе (многие вьетнамские ленты кладут её в description).
+var imgSrcRe = regexp.MustCompile(`(?i)<img[^>]+?src\s*=\s*["']([^"']+)["']`)
+
+// itemImage выбирает главную картинку статьи: media:content, затем enclosure, затем media:thumbnail
+// и в последнюю очередь первый <img> из текста. Относительные ссылки разрешаются от ссылки на статью.
+func itemImage(item rssItem) string {
+	var candidates []string
+	for _, group := range [][]rssEnclosure{item.Media, item.Enclosures} {
+		for _, enc := range group {
+			if enc.isImage() {
+				candidates = append(candidates, enc.URL)
+			}
+		}
+	}
+	for _, thumb := range item.Thumbnails {
+		candidates = append(candidates, thumb.URL)
+	}
+	for _, text := range []string{item.Description, item.ContentEncoded} {
+		if m := imgSrcRe.FindStringSubmatch(text); m != nil {
+			candidates = append(candidates, html.UnescapeString(m[1]))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if image := absoluteHTTPURL(item.Link, candidate); image != "" {
+			return image
+		}
+	}
+	return ""
+}
+
+// absoluteHTTPURL разрешает ref относительно base и возвращает его, только если получился http(s)-адрес.
+func absoluteHTTPURL(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "data:") {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if b, err := url.Parse(strings.TrimSpace(base)); err == nil {
+		u = b.ResolveReference(u)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
+
+// itemAuthors возвращает авторов из dc:creator и <author> без повторов.
+// В RSS 2.0 <author> — это e-mail ("editor@site.vn (Nguyễn Văn A)"): берём имя в скобках,
+// голые адреса не сохраняем.
+func itemAuthors(item rssItem) []string {
+	var authors []string
+	seen := make(map[string]bool)
+	for _, raw := range append(append([]string(nil), item.Creators...), item.Authors...) {
+		name := authorName(raw)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		authors = append(authors, name)
+	}
+	return authors
+}
+
+func authorName(raw string) string {
+	raw = strings.Join(strings.Fields(html.UnescapeString(raw)), " ")
+	if raw == "" {
+		return ""
+	}
+	if addr, err := mail.ParseAddress(raw); err == nil {
+		// "Имя <email>" даёт имя, голый адрес — пустое имя
+		return textnorm.NFC(addr.Name)
+	}
+	if open := strings.Index(raw, "("); open != -1 && strings.HasSuffix(raw, ")") {
+		if strings.Contains(raw[:open], "@") {
+			return textnorm.NFC(strings.TrimSpace(raw[open+1 : len(raw)-1]))
+		}
+	}
+	if strings.Contains(raw, "@") && !strings.Contains(raw, " ") {
+		return ""
+	}
+	return textnorm.NFC(raw)
+}
+
+// itemCategories возвращает рубрики и теги издателя без повторов (без учёта регистра).
+func itemCategories(item rssItem) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, raw := range item.Categories {
+		tag := textnorm.NFC(strings.Join(strings.Fields(html.UnescapeString(raw)), " "))
+		// Разделитель внутри рубрики сломал бы разбор news.ArticleRaw.Tags
+		tag = strings.ReplaceAll(tag, strings.TrimSpace(news.TagsSeparator), ",")
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+		if len(tags) == maxArticleTags {
+			break
+		}
+	}
+	return tags
+}
@@ -0,0 +1,102 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+func TestParseRSSFeed_ItemMetadata(t *testing.T) {
+	tests := []struct {
+		name       string
+		feed       string
+		wantImage  string
+		wantAuthor string
+		wantTags   []string
+	}{
+		{
+			name: "media content, dc creator and categories",
+			feed: `<?xml version="1.0"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><item>
+  <title>Tin</title><link>https://vnexpress.net/tin-1.html</link>
+  <media:content url="https://cdn.vnexpress.net/video.mp4" type="video/mp4"/>
+  <media:content url="https://cdn.vnexpress.net/anh.jpg" medium="image"/>
+  <dc:creator>Nguyễn Văn A</dc:creator>
+  <category>Kinh doanh</category>
+  <category><![CDATA[Bất động sản]]></category>
+  <category>kinh doanh</category>
+</item></channel></rss>`,
+			wantImage:  "https://cdn.vnexpress.net/anh.jpg",
+			wantAuthor: "Nguyễn Văn A",
+			wantTags:   []string{"Kinh doanh", "Bất động sản"},
+		},
+		{
+			name: "enclosure and e-mail author",
+			feed: `<?xml version="1.0"?>
+<rss version="2.0"><channel><item>
+  <title>Tin</title><link>https://tuoitre.vn/tin-2.htm</link>
+  <enclosure url="https://cdn.tuoitre.vn/2.png" length="0" type="image/png"/>
+  <author>toasoan@tuoitre.vn (Trần Thị B)</author>
+</item></channel></rss>`,
+			wantImage:  "https://cdn.tuoitre.vn/2.png",
+			wantAuthor: "Trần Thị B",
+		},
+		{
+			name: "image from description and bare e-mail author",
+			feed: `<?xml version="1.0"?>
+<rss version="2.0"><channel><item>
+  <title>Tin</title><link>https://thanhnien.vn/tin-3.htm</link>
+  <description><![CDATA[<a href="/tin-3.htm"><img src="/images/3.jpg" /></a>Mô tả]]></description>
+  <author>toasoan@thanhnien.vn</author>
+</item></channel></rss>`,
+			wantImage: "https://thanhnien.vn/images/3.jpg",
+		},
+		{
+			name: "atom author, category label and enclosure link",
+			feed: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><entry>
+  <title>Tin</title>
+  <link rel="alternate" href="https://example.vn/tin-4"/>
+  <link rel="enclosure" type="image/jpeg" href="https://example.vn/4.jpg"/>
+  <author><name>Lê Văn C</name></author>
+  <author><name>Phạm D</name></author>
+  <category term="the-thao" label="Thể thao"/>
+  <category term="bong-da"/>
+  <updated>2024-12-03T10:00:00Z</updated>
+</entry></feed>`,
+			wantImage:  "https://example.vn/4.jpg",
+			wantAuthor: "Lê Văn C, Phạm D",
+			wantTags:   []string{"Thể thao", "bong-da"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := parseRSSFeed([]byte(tt.feed), "")
+			if err != nil {
+				t.Fatalf("parseRSSFeed() error = %v", err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("parseRSSFeed() returned %d items, want 1", len(items))
+			}
+
+			article := newArticle(config.Site{ID: "site"}, "", 0, items[0], time.Time{})
+			if got := article.ImageURL(); got != tt.wantImage {
+				t.Errorf("ImageURL() = %q, want %q", got, tt.wantImage)
+			}
+			if got := article.Author(); got != tt.wantAuthor {
+				t.Errorf("Author() = %q, want %q", got, tt.wantAuthor)
+			}
+			if got := article.Tags(); !reflect.DeepEqual(got, tt.wantTags) {
+				t.Errorf("Tags() = %q, want %q", got, tt.wantTags)
+			}
+			if _, ok := article.Metadata[news.MetaImageURL]; ok != (tt.wantImage != "") {
+				t.Errorf("Metadata[%q] presence = %v", news.MetaImageURL, ok)
+			}
+		})
+	}
+}
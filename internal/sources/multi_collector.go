@@ -0,0 +1,67 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// Collector — общий интерфейс коллекторов пакета (совпадает с app.SourceCollector).
+type Collector interface {
+	Collect(ctx context.Context, state news.State) (news.State, []news.ArticleRaw, error)
+}
+
+// MultiCollector объединяет несколько коллекторов (RSS, скрапинг) в один прогон.
+type MultiCollector struct {
+	collectors []Collector
+}
+
+// hostLimited реализуют коллекторы пакета, которые ходят в сеть через hostLimiter.
+type hostLimited interface {
+	hostLimiter() *hostLimiter
+	setHostLimiter(limiter *hostLimiter)
+}
+
+// NewMultiCollector создаёт композитный коллектор. nil-коллекторы пропускаются.
+// Коллекторы пакета получают общий ограничитель запросов по хостам (первого из них):
+// сайт с RSS, скрапингом и sitemap не должен получать втрое больше запросов, чем разрешено.
+func NewMultiCollector(collectors ...Collector) *MultiCollector {
+	active := make([]Collector, 0, len(collectors))
+	var shared *hostLimiter
+	for _, c := range collectors {
+		if c == nil {
+			continue
+		}
+		if limited, ok := c.(hostLimited); ok {
+			if shared == nil {
+				shared = limited.hostLimiter()
+			} else {
+				limited.setHostLimiter(shared)
+			}
+		}
+		active = append(active, c)
+	}
+	return &MultiCollector{collectors: active}
+}
+
+// Collect реализует app.SourceCollector.
+// Результаты возвращаются в порядке коллекторов. Ошибка одного коллектора не прерывает остальные:
+// ошибки всех коллекторов возвращаются объединёнными вместе с успешно собранными статьями.
+// Состояние передаётся по цепочке: каждый коллектор получает результат предыдущего.
+func (m *MultiCollector) Collect(ctx context.Context, state news.State) (news.State, []news.ArticleRaw, error) {
+	var results []news.ArticleRaw
+	var errs []error
+	for _, c := range m.collectors {
+		var items []news.ArticleRaw
+		var err error
+		state, items, err = c.Collect(ctx, state)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%T: %w", c, err))
+		}
+		// Коллекторы пакета возвращают частичные результаты вместе с ошибками отдельных лент
+		results = append(results, items...)
+	}
+	return state, results, errors.Join(errs...)
+}
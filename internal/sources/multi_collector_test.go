@@ -0,0 +1,125 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// stubCollector - заглушка коллектора для тестов MultiCollector
+type stubCollector struct {
+	articles []news.ArticleRaw
+	err      error
+}
+
+func (s *stubCollector) Collect(ctx context.Context, state news.State) (news.State, []news.ArticleRaw, error) {
+	return state, s.articles, s.err
+}
+
+func TestMultiCollector_Collect(t *testing.T) {
+	rss := &stubCollector{articles: []news.ArticleRaw{{ID: "rss-1"}, {ID: "rss-2"}}}
+	scrape := &stubCollector{articles: []news.ArticleRaw{{ID: "scrape-1"}}}
+	broken := &stubCollector{err: errors.New("boom")}
+
+	tests := []struct {
+		name       string
+		collectors []Collector
+		wantIDs    []string
+		wantErr    bool
+	}{
+		{
+			name:       "results in collector order",
+			collectors: []Collector{rss, scrape},
+			wantIDs:    []string{"rss-1", "rss-2", "scrape-1"},
+		},
+		{
+			name:       "one failing collector does not stop others",
+			collectors: []Collector{broken, scrape},
+			wantIDs:    []string{"scrape-1"},
+			wantErr:    true,
+		},
+		{
+			name:       "partial results are kept along with the error",
+			collectors: []Collector{&stubCollector{articles: []news.ArticleRaw{{ID: "partial-1"}}, err: errors.New("one feed down")}, scrape},
+			wantIDs:    []string{"partial-1", "scrape-1"},
+			wantErr:    true,
+		},
+		{
+			name:       "all collectors failed",
+			collectors: []Collector{broken},
+			wantErr:    true,
+		},
+		{
+			name:       "nil collectors are skipped",
+			collectors: []Collector{nil, rss},
+			wantIDs:    []string{"rss-1", "rss-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := NewMultiCollector(tt.collectors...).Collect(context.Background(), news.State{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Collect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("Collect() len = %d, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if got[i].ID != id {
+					t.Errorf("Collect()[%d].ID = %q, want %q", i, got[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiCollector_SharesHostLimiter(t *testing.T) {
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<rss version="2.0"><channel><item><title>Tin RSS</title><link>https://example.com/tin-rss.html</link><pubDate>Tue, 03 Dec 2024 10:00:00 +0000</pubDate><description>Nội dung</description></item></channel></rss>`)
+	})
+	var serverURL string
+	mux.HandleFunc("/sitemap-news.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:news="http://www.google.com/schemas/sitemap-news/0.9">
+  <url><loc>%s/tin-sitemap.html</loc><news:news><news:title>Tin sitemap</news:title><news:publication_date>2024-12-03T10:00:00Z</news:publication_date></news:news></url>
+</urlset>`, serverURL)
+	})
+	mux.HandleFunc("/tin-sitemap.html", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><article><p>Nội dung đầy đủ của bài viết.</p></article></body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL = server.URL
+
+	site := config.Site{
+		ID:       "site",
+		RSSFeeds: []config.RSSFeed{{URL: server.URL + "/rss"}},
+		Sitemaps: []config.RSSFeed{{URL: server.URL + "/sitemap-news.xml"}},
+	}
+	cfg := config.Sources{PolitenessDelayMs: 300}
+	clk := clock.NewFake(now)
+	multi := NewMultiCollector(
+		NewRSSCollector([]config.Site{site}, cfg, server.Client(), clk),
+		NewSitemapCollector([]config.Site{site}, cfg, 24*time.Hour, server.Client(), clk),
+	)
+
+	if _, _, err := multi.Collect(context.Background(), news.State{}); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	// Sitemap того же сайта ждёт паузу вежливости после ленты RSS, а статья из sitemap — после него:
+	// ограничитель у коллекторов общий. С отдельными была бы только пауза перед статьёй.
+	want := []time.Duration{300 * time.Millisecond, 300 * time.Millisecond}
+	if slept := clk.Slept(); fmt.Sprint(slept) != fmt.Sprint(want) {
+		t.Errorf("Slept() = %v, want %v", slept, want)
+	}
+}
@@ -0,0 +1,183 @@
+package sources
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// minArticleTextLength — минимальная длина извлечённого текста (в рунах),
+// при которой считаем, что нашли тело статьи, а не обрывок вёрстки.
+const minArticleTextLength = 200
+
+// boilerplateTags удаляются со страницы до поиска тела статьи.
+var boilerplateTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "iframe": true, "template": true,
+	"form": true, "button": true, "select": true, "svg": true, "canvas": true,
+	"nav": true, "header": true, "footer": true, "aside": true,
+	"figure": true, "figcaption": true,
+}
+
+// boilerplateMarkers — части class/id, по которым узнаются меню, блоки «читайте также», реклама и т.п.
+var boilerplateMarkers = map[string]bool{
+	"nav": true, "navigation": true, "menu": true, "breadcrumb": true, "breadcrumbs": true,
+	"header": true, "footer": true, "sidebar": true, "banner": true,
+	"ad": true, "ads": true, "advert": true, "advertisement": true, "qc": true,
+	"share": true, "social": true, "comment": true, "comments": true,
+	"related": true, "relate": true, "tinlienquan": true, "lienquan": true,
+	"tags": true, "popup": true, "newsletter": true, "subscribe": true,
+	"caption": true, "tplcaption": true, "figcaption": true, // Подписи к фото (VnExpress вёрстает их таблицей)
+}
+
+// structuralTags никогда не удаляются, даже если их class похож на служебный.
+var structuralTags = map[string]bool{
+	"html": true, "body": true, "main": true, "article": true,
+}
+
+// blockTags разделяют текст на абзацы при преобразовании HTML в текст.
+var blockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "ul": true, "ol": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "blockquote": true, "pre": true,
+	"table": true, "tr": true, "dd": true, "dt": true, "hr": true,
+}
+
+// extractArticleText выделяет основной текст статьи из HTML-страницы (упрощённый readability):
+// удаляет служебные блоки, находит контейнер с наибольшим объёмом абзацев и возвращает его текст
+// с сохранением разбиения на абзацы. Возвращает пустую строку, если тело статьи не найдено.
+func extractArticleText(doc *html.Node) string {
+	pruneBoilerplate(doc)
+
+	scores := make(map[*html.Node]float64)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "p" {
+			text := nodeText(n)
+			length := utf8.RuneCountInString(text)
+			if length >= 25 && n.Parent != nil {
+				// Длинные абзацы с запятыми — признак связного текста, а не подписей и ссылок
+				score := float64(length) + float64(strings.Count(text, ","))*10
+				scores[n.Parent] += score
+				if n.Parent.Parent != nil {
+					scores[n.Parent.Parent] += score / 2
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	var bestScore float64
+	for node, score := range scores {
+		if score > bestScore || (score == bestScore && best != nil && isBefore(node, best)) {
+			best, bestScore = node, score
+		}
+	}
+	if best == nil {
+		return ""
+	}
+
+	text := renderText(best)
+	if utf8.RuneCountInString(text) < minArticleTextLength {
+		return ""
+	}
+	return text
+}
+
+// pruneBoilerplate удаляет из дерева служебные элементы.
+func pruneBoilerplate(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode || (child.Type == html.ElementNode && isBoilerplate(child)) {
+			n.RemoveChild(child)
+		} else {
+			pruneBoilerplate(child)
+		}
+		child = next
+	}
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if structuralTags[n.Data] {
+		return false
+	}
+	if boilerplateTags[n.Data] {
+		return true
+	}
+	for _, value := range []string{nodeAttr(n, "class"), nodeAttr(n, "id")} {
+		parts := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, part := range parts {
+			if boilerplateMarkers[part] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renderText преобразует поддерево HTML в текст: блочные элементы разделяют абзацы,
+// пробелы внутри абзаца схлопываются, абзацы разделяются пустой строкой.
+func renderText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if boilerplateTags[n.Data] && !structuralTags[n.Data] {
+				return
+			}
+		}
+
+		block := n.Type == html.ElementNode && blockTags[n.Data]
+		if block {
+			sb.WriteString("\n")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if block {
+			sb.WriteString("\n")
+		}
+	}
+	walk(n)
+
+	return collapseParagraphs(sb.String())
+}
+
+// isBefore сообщает, встречается ли узел a раньше узла b в порядке обхода документа.
+// Нужен для детерминированного выбора между контейнерами с одинаковой оценкой.
+func isBefore(a, b *html.Node) bool {
+	found := false
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n == a {
+			found = true
+			return true
+		}
+		if n == b {
+			return true
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if walk(child) {
+				return true
+			}
+		}
+		return false
+	}
+	root := a
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	walk(root)
+	return found
+}
@@ -6,9 +6,9 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
-	"io"
 	"log"
 	"net/http"
 	"regexp"
@@ -16,61 +16,83 @@ import (
 	"strings"
 	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/config"
 	"github.com/maine/vietnam_bot_news/internal/news"
+	"github.com/maine/vietnam_bot_news/internal/textnorm"
+	"github.com/maine/vietnam_bot_news/internal/urlnorm"
 )
 
-// RSSCollector загружает новости из RSS-лент.
+// RSSCollector загружает новости из RSS-лент (RSS 2.0, RSS 1.0/RDF и Atom).
 type RSSCollector struct {
-	sites  []config.Site
-	client *http.Client
-	clock  func() time.Time
+	sites    []config.Site
+	client   *http.Client
+	clock    func() time.Time
+	runner   *feedRunner
+	fullText *fullTextFetcher
 }
 
-// NewRSSCollector создаёт новый экземпляр.
-func NewRSSCollector(sites []config.Site, client *http.Client, clock func() time.Time) *RSSCollector {
+// NewRSSCollector создаёт новый экземпляр. Если clk == nil, используются системные часы.
+func NewRSSCollector(sites []config.Site, cfg config.Sources, client *http.Client, clk clock.Clock) *RSSCollector {
 	if client == nil {
 		client = &http.Client{Timeout: 15 * time.Second}
 	}
-	if clock == nil {
-		clock = time.Now
-	}
+	clk = clock.OrSystem(clk)
+	// Один ограничитель на ленты и статьи: к одному хосту ходим не чаще лимита, что бы ни качали
+	limiter := newSourceHostLimiter(cfg, clk)
 	return &RSSCollector{
-		sites:  sites,
-		client: client,
-		clock:  clock,
+		sites:    sites,
+		client:   client,
+		clock:    clk.Now,
+		runner:   newFeedRunner("RSS feed", cfg, limiter, clk.Now),
+		fullText: newFullTextFetcher(cfg, client, limiter, clk.Now),
 	}
 }
 
+// hostLimiter и setHostLimiter реализуют hostLimited.
+func (c *RSSCollector) hostLimiter() *hostLimiter {
+	return c.runner.limiter
+}
+
+func (c *RSSCollector) setHostLimiter(limiter *hostLimiter) {
+	c.runner.limiter = limiter
+	c.fullText.limiter = limiter
+}
+
 // Collect реализует app.SourceCollector.
-func (c *RSSCollector) Collect(ctx context.Context) ([]news.ArticleRaw, error) {
-	var results []news.ArticleRaw
+// Ленты загружаются параллельно (общий пул + лимит на хост), но порядок статей детерминирован:
+// по порядку сайтов и лент в конфиге. Ошибки отдельных лент не прерывают сбор и возвращаются
+// объединёнными (*FeedError через errors.Join) вместе с успешно собранными статьями.
+// В state.Feeds ведутся валидаторы HTTP-кэша и здоровье лент; ленты в карантине пропускаются.
+func (c *RSSCollector) Collect(ctx context.Context, state news.State) (news.State, []news.ArticleRaw, error) {
+	var jobs []feedJob
 	for _, site := range c.sites {
-		// Получаем список RSS-лент для обработки
-		rssFeeds := c.getRSSFeeds(site)
-		if len(rssFeeds) == 0 {
-			continue
+		for _, rssFeed := range c.getRSSFeeds(site) {
+			jobs = append(jobs, feedJob{
+				site: site,
+				url:  rssFeed.URL,
+				fetch: func(ctx context.Context, prev news.FeedState) ([]news.ArticleRaw, news.FeedState, error) {
+					return c.fetchFeed(ctx, site, rssFeed, prev)
+				},
+			})
 		}
+	}
 
-		// Обрабатываем каждую RSS-ленту
-		for _, rssFeed := range rssFeeds {
-			items, err := c.fetchFeed(ctx, site, rssFeed)
-			if err != nil {
-				// При ошибке одной RSS-ленты продолжаем обработку других
-				// Это позволяет частично обработать сайт, даже если одна из RSS-лент недоступна
-				log.Printf("Error fetching RSS feed %s for site %s (%s): %v", rssFeed.URL, site.ID, site.Name, err)
-				continue
-			}
+	state, results, errs := c.runner.run(ctx, state, jobs)
 
-			results = append(results, items...)
-		}
-	}
-	return results, nil
+	// Для сайтов с full_text: true заменяем анонсы из ленты полным текстом статей
+	results = c.fullText.enrich(ctx, c.sites, results)
+	return state, results, errors.Join(errs...)
 }
 
 // getRSSFeeds возвращает список RSS-лент для сайта.
-// Поддерживает оба формата: старый (одна RSS) и новый (массив RSS с категориями).
 func (c *RSSCollector) getRSSFeeds(site config.Site) []config.RSSFeed {
+	return siteRSSFeeds(site)
+}
+
+// siteRSSFeeds возвращает список RSS-лент сайта.
+// Поддерживает оба формата: старый (одна RSS) и новый (массив RSS с категориями).
+func siteRSSFeeds(site config.Site) []config.RSSFeed {
 	// Приоритет: новый формат (rss_feeds)
 	if len(site.RSSFeeds) > 0 {
 		return site.RSSFeeds
@@ -84,40 +106,23 @@ func (c *RSSCollector) getRSSFeeds(site config.Site) []config.RSSFeed {
 	return nil
 }
 
-func (c *RSSCollector) fetchFeed(ctx context.Context, site config.Site, rssFeed config.RSSFeed) ([]news.ArticleRaw, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rssFeed.URL, nil)
+// fetchFeed загружает ленту условным запросом с валидаторами prev и возвращает статьи и новые валидаторы.
+// Если лента не изменилась (304), возвращает errNotModified; если в ней нет ни одной статьи — errNoItems.
+func (c *RSSCollector) fetchFeed(ctx context.Context, site config.Site, rssFeed config.RSSFeed, prev news.FeedState) ([]news.ArticleRaw, news.FeedState, error) {
+	resp, err := conditionalGet(ctx, c.client, rssFeed.URL, prev)
 	if err != nil {
-		return nil, fmt.Errorf("build request: %w", err)
+		return nil, resp.validators, err
 	}
 
-	// Добавляем User-Agent, чтобы избежать блокировки (403 Forbidden)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; RSSBot/1.0; +https://github.com/maine/vietnam_bot_news)")
-
-	resp, err := c.client.Do(req)
+	items, err := parseRSSFeed(resp.body, resp.contentType)
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read body: %w", err)
-	}
-
-	items, err := parseRSSFeed(body)
-	if err != nil {
-		return nil, fmt.Errorf("parse RSS: %w", err)
+		return nil, prev, fmt.Errorf("parse feed: %w", err)
 	}
 
 	articles := make([]news.ArticleRaw, 0, len(items))
 
 	// Лимит: обрабатываем только первые 100 статей из RSS (обычно самые свежие)
 	// Это защищает от обработки тысяч старых статей, которые могут быть в RSS
-	const maxArticlesPerFeed = 100
 	itemsToProcess := items
 	if len(items) > maxArticlesPerFeed {
 		itemsToProcess = items[:maxArticlesPerFeed]
@@ -129,34 +134,61 @@ func (c *RSSCollector) fetchFeed(ctx context.Context, site config.Site, rssFeed
 		}
 
 		timestamp := parseTime(item.PubDate, c.clock())
-		content := strings.TrimSpace(selectContent(item))
+		articles = append(articles, newArticle(site, rssFeed.Category, i, item, timestamp))
+	}
+	if len(articles) == 0 {
+		// Не запоминаем валидаторы пустой ленты, иначе следующий запуск получит 304 и не перечитает её
+		return nil, prev, errNoItems
+	}
 
-		metadata := map[string]string{
-			"rss_rank": strconv.Itoa(i),
-			"siteName": site.Name,
-		}
-		// Сохраняем категорию из RSS-ленты, если она указана
-		// Если категория пустая, categorizer будет использовать Gemini
-		if rssFeed.Category != "" {
-			metadata["rss_category"] = rssFeed.Category
-		}
+	return articles, resp.validators, nil
+}
 
-		// Декодируем HTML-сущности в заголовке (например, &agrave; -> à, &ecirc; -> ê)
-		title := html.UnescapeString(strings.TrimSpace(item.Title))
-
-		articles = append(articles, news.ArticleRaw{
-			ID:          buildArticleID(site.ID, item.Link, timestamp),
-			Source:      site.ID,
-			Title:       title,
-			URL:         strings.TrimSpace(item.Link),
-			PublishedAt: timestamp,
-			RawLanguage: detectLanguage(site),
-			RawContent:  content,
-			Metadata:    metadata,
-		})
+// maxArticlesPerFeed ограничивает число элементов, обрабатываемых из одной ленты или листинга.
+const maxArticlesPerFeed = 100
+
+// newArticle собирает news.ArticleRaw из элемента ленты.
+// Используется всеми коллекторами пакета, чтобы статьи из RSS и со скрапинга были неотличимы.
+func newArticle(site config.Site, category string, rank int, item rssItem, published time.Time) news.ArticleRaw {
+	// NFC: одна и та же вьетнамская буква может прийти составной или предсоставленной.
+	// Разметка не нужна ни для min_content_length, ни в промптах Gemini, исходник сохраняем отдельно
+	rawHTML := textnorm.NFC(strings.TrimSpace(selectContent(item)))
+	content := htmlToText(rawHTML)
+	if content == rawHTML {
+		rawHTML = ""
 	}
 
-	return articles, nil
+	metadata := map[string]string{
+		"rss_rank": strconv.Itoa(rank),
+		"siteName": site.Name,
+	}
+	// Сохраняем категорию из RSS-ленты, если она указана
+	// Если категория пустая, categorizer будет использовать Gemini
+	if category != "" {
+		metadata["rss_category"] = category
+	}
+	// Картинка, авторы и рубрики издателя нужны форматтеру и категоризатору
+	itemMetadata(metadata, item)
+
+	// Декодируем HTML-сущности в заголовке (например, &agrave; -> à, &ecirc; -> ê)
+	title := textnorm.NFC(html.UnescapeString(strings.TrimSpace(item.Title)))
+
+	// ID строится от канонической ссылки: метки utm_*, AMP и мобильные версии не плодят новые ID
+	canonical := urlnorm.Canonical(item.Link)
+
+	return news.ArticleRaw{
+		ID:           buildArticleID(site.ID, canonical, published),
+		Source:       site.ID,
+		Title:        title,
+		URL:          strings.TrimSpace(item.Link),
+		CanonicalURL: canonical,
+		PublishedAt:  published,
+		RawLanguage:  detectLanguage(site),
+		RawContent:   content,
+		RawHTML:      rawHTML,
+		Metadata:     metadata,
+		Priority:     site.Priority,
+	}
 }
 
 func detectLanguage(site config.Site) string {
@@ -201,6 +233,9 @@ func parseTime(value string, fallback time.Time) time.Time {
 		"2006-01-02T15:04:05Z",
 		"2006-01-02T15:04:05+07:00",
 		"2006-01-02T15:04:05-07:00",
+		// W3C Datetime из sitemap: без секунд и только дата
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02",
 	}
 
 	for _, f := range formats {
@@ -226,8 +261,14 @@ func selectContent(item rssItem) string {
 
 // --- RSS parsing ---
 
-type rssFeed struct {
-	Channel rssChannel `xml:"channel"`
+// feedDocument описывает корневой элемент ленты любого поддерживаемого диалекта:
+// RSS 2.0 (<rss><channel><item>), RSS 1.0 / RDF (<rdf:RDF><item>) и Atom (<feed><entry>).
+// Диалект определяется по имени корневого элемента.
+type feedDocument struct {
+	XMLName xml.Name
+	Channel rssChannel  `xml:"channel"`
+	Items   []rssItem   `xml:"item"`  // RSS 1.0: item лежат прямо в корне rdf:RDF
+	Entries []atomEntry `xml:"entry"` // Atom
 }
 
 type rssChannel struct {
@@ -235,29 +276,171 @@ type rssChannel struct {
 }
 
 type rssItem struct {
-	Title          string `xml:"title"`
-	Link           string `xml:"link"`
-	Description    string `xml:"description"`
-	ContentEncoded string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
-	PubDate        string `xml:"pubDate"`
+	Title          string         `xml:"title"`
+	Link           string         `xml:"link"`
+	Description    string         `xml:"description"`
+	ContentEncoded string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate        string         `xml:"pubDate"`
+	DCDate         string         `xml:"http://purl.org/dc/elements/1.1/ date"` // RSS 1.0 и некоторые RSS 2.0 ленты
+	Authors        []string       `xml:"author"`                                // RSS 2.0: e-mail, часто с именем в скобках
+	Creators       []string       `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories     []string       `xml:"category"`
+	Enclosures     []rssEnclosure `xml:"enclosure"`
+	Media          []rssEnclosure `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails     []rssEnclosure `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type atomEntry struct {
+	Title     atomText   `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	ID        string     `xml:"id"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Media      []rssEnclosure `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []rssEnclosure `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
 }
 
-func parseRSSFeed(data []byte) ([]rssItem, error) {
+// atomCategory — рубрика Atom: term обязателен, label — человекочитаемое название.
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// atomText — текстовая конструкция Atom (type="text" | "html" | "xhtml").
+type atomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (t atomText) value() string {
+	// Для xhtml содержимое лежит вложенной разметкой (<div xmlns="...">), а не текстом
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// parseRSSFeed разбирает ленту RSS 2.0, RSS 1.0 (RDF) или Atom.
+// contentType — заголовок HTTP-ответа, из него берётся кодировка ленты.
+func parseRSSFeed(data []byte, contentType string) ([]rssItem, error) {
 	// Исправляем некорректные XML-сущности (например, & без ;)
 	data = fixXMLEntities(data)
 
-	var feed rssFeed
+	var doc feedDocument
 	// Сначала пытаемся стандартный парсер
-	if err := xml.Unmarshal(data, &feed); err != nil {
+	if err := newFeedDecoder(data, contentType).Decode(&doc); err != nil {
 		// Если не получилось, используем более толерантный декодер
 		// decoder.Strict = false позволяет обрабатывать некоторые синтаксические ошибки
-		decoder := xml.NewDecoder(bytes.NewReader(data))
+		doc = feedDocument{}
+		decoder := newFeedDecoder(data, contentType)
 		decoder.Strict = false
-		if err := decoder.Decode(&feed); err != nil {
+		if err := decoder.Decode(&doc); err != nil {
 			return nil, fmt.Errorf("parse RSS XML: %w", err)
 		}
 	}
-	return feed.Channel.Items, nil
+
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss":
+		return normalizeRSSItems(doc.Channel.Items), nil
+	case "rdf":
+		// В RSS 1.0 channel содержит только оглавление (rdf:Seq), сами item — в корне
+		return normalizeRSSItems(append(doc.Channel.Items, doc.Items...)), nil
+	case "feed":
+		return atomEntriesToItems(doc.Entries), nil
+	default:
+		// Например, HTML-страница Cloudflare вместо ленты: раньше это молча давало 0 статей
+		return nil, fmt.Errorf("unsupported feed format: root element <%s>", doc.XMLName.Local)
+	}
+}
+
+// normalizeRSSItems подставляет dc:date, если в item нет pubDate.
+func normalizeRSSItems(items []rssItem) []rssItem {
+	for i := range items {
+		if strings.TrimSpace(items[i].PubDate) == "" {
+			items[i].PubDate = items[i].DCDate
+		}
+	}
+	return items
+}
+
+// atomEntriesToItems приводит записи Atom к общему представлению rssItem,
+// чтобы дальнейшая обработка не зависела от диалекта ленты.
+func atomEntriesToItems(entries []atomEntry) []rssItem {
+	items := make([]rssItem, 0, len(entries))
+	for _, entry := range entries {
+		pubDate := entry.Published
+		if strings.TrimSpace(pubDate) == "" {
+			pubDate = entry.Updated
+		}
+		item := rssItem{
+			Title:          entry.Title.value(),
+			Link:           atomEntryLink(entry),
+			Description:    entry.Summary.value(),
+			ContentEncoded: entry.Content.value(),
+			PubDate:        pubDate,
+			Media:          entry.Media,
+			Thumbnails:     entry.Thumbnails,
+		}
+		for _, author := range entry.Authors {
+			item.Authors = append(item.Authors, author.Name)
+		}
+		for _, category := range entry.Categories {
+			label := category.Label
+			if strings.TrimSpace(label) == "" {
+				label = category.Term
+			}
+			item.Categories = append(item.Categories, label)
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				item.Enclosures = append(item.Enclosures, rssEnclosure{URL: link.Href, Type: link.Type})
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// atomEntryLink выбирает ссылку на статью: rel="alternate" (или без rel) с приоритетом text/html.
+// Если подходящей ссылки нет, используем id записи, когда он является URL.
+func atomEntryLink(entry atomEntry) string {
+	var fallback string
+	for _, link := range entry.Links {
+		href := strings.TrimSpace(link.Href)
+		if href == "" || (link.Rel != "" && link.Rel != "alternate") {
+			continue
+		}
+		if link.Type == "" || strings.Contains(link.Type, "html") {
+			return href
+		}
+		if fallback == "" {
+			fallback = href
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+	id := strings.TrimSpace(entry.ID)
+	if strings.HasPrefix(id, "http://") || strings.HasPrefix(id, "https://") {
+		return id
+	}
+	return ""
 }
 
 // fixXMLEntities исправляет распространённые проблемы с XML-сущностями в RSS-лентах.
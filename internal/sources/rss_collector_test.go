@@ -2,10 +2,15 @@ package sources
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
 )
 
 func TestRSSCollector_getRSSFeeds(t *testing.T) {
@@ -50,7 +55,7 @@ func TestRSSCollector_getRSSFeeds(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			collector := NewRSSCollector([]config.Site{tt.site}, nil, nil)
+			collector := NewRSSCollector([]config.Site{tt.site}, config.Sources{}, nil, nil)
 			feeds := collector.getRSSFeeds(tt.site)
 			if len(feeds) != tt.want {
 				t.Errorf("getRSSFeeds() len = %v, want %v", len(feeds), tt.want)
@@ -207,6 +212,39 @@ func TestRSSCollector_parseRSSFeed(t *testing.T) {
   </channel>
 </rss>`
 
+	validAtom := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Government Portal</title>
+  <entry>
+    <title>Atom Title</title>
+    <link rel="alternate" type="text/html" href="https://example.com/atom/1"/>
+    <id>urn:uuid:1</id>
+    <updated>2024-12-03T10:00:00+07:00</updated>
+    <summary>Atom summary</summary>
+  </entry>
+  <entry>
+    <title type="html">Atom Title 2</title>
+    <link href="https://example.com/atom/2"/>
+    <id>https://example.com/atom/2</id>
+    <published>2024-12-03T09:00:00Z</published>
+    <content type="html">&lt;p&gt;Atom content&lt;/p&gt;</content>
+  </entry>
+</feed>`
+
+	validRDF := `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://example.com/">
+    <title>RDF Feed</title>
+    <items><rdf:Seq><rdf:li rdf:resource="https://example.com/rdf/1"/></rdf:Seq></items>
+  </channel>
+  <item rdf:about="https://example.com/rdf/1">
+    <title>RDF Title</title>
+    <link>https://example.com/rdf/1</link>
+    <description>RDF description</description>
+    <dc:date>2024-12-03T10:00:00+07:00</dc:date>
+  </item>
+</rdf:RDF>`
+
 	tests := []struct {
 		name    string
 		data    []byte
@@ -231,11 +269,29 @@ func TestRSSCollector_parseRSSFeed(t *testing.T) {
 			wantErr: false,
 			wantLen: 0,
 		},
+		{
+			name:    "valid Atom",
+			data:    []byte(validAtom),
+			wantErr: false,
+			wantLen: 2,
+		},
+		{
+			name:    "valid RSS 1.0 (RDF)",
+			data:    []byte(validRDF),
+			wantErr: false,
+			wantLen: 1,
+		},
+		{
+			name:    "HTML page instead of feed",
+			data:    []byte(`<html><head><title>Just a moment...</title></head><body></body></html>`),
+			wantErr: true,
+			wantLen: 0,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			items, err := parseRSSFeed(tt.data)
+			items, err := parseRSSFeed(tt.data, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseRSSFeed() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -247,11 +303,137 @@ func TestRSSCollector_parseRSSFeed(t *testing.T) {
 	}
 }
 
+func TestRSSCollector_parseRSSFeed_Atom(t *testing.T) {
+	data := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <title>Only updated</title>
+    <link rel="self" href="https://example.com/feed/entry/1"/>
+    <link rel="alternate" href="https://example.com/news/1"/>
+    <updated>2024-12-03T10:00:00+07:00</updated>
+    <summary>Summary text</summary>
+    <content type="html">&lt;p&gt;Full content&lt;/p&gt;</content>
+  </entry>
+  <entry>
+    <title>No link, URL id</title>
+    <id>https://example.com/news/2</id>
+    <published>2024-12-02T10:00:00Z</published>
+    <updated>2024-12-03T10:00:00Z</updated>
+  </entry>
+</feed>`
+
+	items, err := parseRSSFeed([]byte(data), "")
+	if err != nil {
+		t.Fatalf("parseRSSFeed() error = %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("parseRSSFeed() len = %d, want 2", len(items))
+	}
+
+	first := items[0]
+	if first.Link != "https://example.com/news/1" {
+		t.Errorf("Link = %q, want alternate link", first.Link)
+	}
+	if first.PubDate != "2024-12-03T10:00:00+07:00" {
+		t.Errorf("PubDate = %q, want <updated> when <published> is missing", first.PubDate)
+	}
+	if first.ContentEncoded != "<p>Full content</p>" {
+		t.Errorf("ContentEncoded = %q, want decoded <content>", first.ContentEncoded)
+	}
+	if first.Description != "Summary text" {
+		t.Errorf("Description = %q, want <summary>", first.Description)
+	}
+
+	second := items[1]
+	if second.Link != "https://example.com/news/2" {
+		t.Errorf("Link = %q, want id as fallback", second.Link)
+	}
+	if second.PubDate != "2024-12-02T10:00:00Z" {
+		t.Errorf("PubDate = %q, want <published>", second.PubDate)
+	}
+}
+
+func TestRSSCollector_parseRSSFeed_DCDate(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <item>
+      <title>Title</title>
+      <link>https://example.com/1</link>
+      <dc:date>2024-12-03T10:00:00+07:00</dc:date>
+    </item>
+  </channel>
+</rss>`
+
+	items, err := parseRSSFeed([]byte(data), "")
+	if err != nil {
+		t.Fatalf("parseRSSFeed() error = %v", err)
+	}
+	if len(items) != 1 || items[0].PubDate != "2024-12-03T10:00:00+07:00" {
+		t.Errorf("parseRSSFeed() should use dc:date when pubDate is missing, got %+v", items)
+	}
+}
+
+func TestRSSCollector_parseRSSFeed_Charset(t *testing.T) {
+	// "Vi\xea\xf2t Nam" в windows-1258: ê + комбинируемая точка снизу, после NFC — "Việt Nam"
+	const cp1258Title = "Vi\xea\xf2t Nam"
+	feed := func(decl, title string) []byte {
+		return []byte(decl + `<rss><channel><item><title>` + title + `</title><link>https://example.com/1</link></item></channel></rss>`)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		want        string
+	}{
+		{
+			name: "encoding from XML declaration",
+			data: feed(`<?xml version="1.0" encoding="windows-1258"?>`, cp1258Title),
+			want: "Vi\u1ec7t Nam",
+		},
+		{
+			name:        "charset from Content-Type",
+			data:        feed("", "Caf\xe9"),
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			want:        "Caf\u00e9",
+		},
+		{
+			name:        "Content-Type overrides XML declaration",
+			data:        feed(`<?xml version="1.0" encoding="utf-8"?>`, "Caf\xe9"),
+			contentType: "text/xml; charset=windows-1252",
+			want:        "Caf\u00e9",
+		},
+		{
+			name:        "declaration wins when Content-Type wrongly claims UTF-8",
+			data:        feed(`<?xml version="1.0" encoding="windows-1258"?>`, cp1258Title),
+			contentType: "text/xml; charset=utf-8",
+			want:        "Vi\u1ec7t Nam",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := parseRSSFeed(tt.data, tt.contentType)
+			if err != nil {
+				t.Fatalf("parseRSSFeed() error = %v", err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("parseRSSFeed() len = %d, want 1", len(items))
+			}
+			article := newArticle(config.Site{ID: "test"}, "", 0, items[0], time.Now())
+			if article.Title != tt.want {
+				t.Errorf("Title = %q, want %q", article.Title, tt.want)
+			}
+		})
+	}
+}
+
 func TestRSSCollector_Collect_EmptySites(t *testing.T) {
-	collector := NewRSSCollector([]config.Site{}, nil, nil)
+	collector := NewRSSCollector([]config.Site{}, config.Sources{}, nil, nil)
 	ctx := context.Background()
 
-	articles, err := collector.Collect(ctx)
+	_, articles, err := collector.Collect(ctx, news.State{})
 	if err != nil {
 		t.Fatalf("Collect() error = %v", err)
 	}
@@ -260,6 +442,81 @@ func TestRSSCollector_Collect_EmptySites(t *testing.T) {
 	}
 }
 
+func TestRSSCollector_Collect_ConcurrentOrderAndErrors(t *testing.T) {
+	// Первая лента отвечает медленнее второй: порядок результатов всё равно должен следовать конфигу
+	delays := map[string]time.Duration{"/slow": 50 * time.Millisecond, "/fast": 0}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(delays[r.URL.Path])
+		fmt.Fprintf(w, `<rss><channel><item><title>%s</title><link>https://example.com%s/1</link><pubDate>Mon, 02 Dec 2024 10:00:00 +0700</pubDate></item></channel></rss>`, r.URL.Path, r.URL.Path)
+	}))
+	defer server.Close()
+
+	sites := []config.Site{
+		{ID: "a", Name: "A", RSSFeeds: []config.RSSFeed{{URL: server.URL + "/slow"}, {URL: server.URL + "/broken"}}},
+		{ID: "b", Name: "B", RSSFeeds: []config.RSSFeed{{URL: server.URL + "/fast"}}},
+	}
+	cfg := config.Sources{MaxConcurrency: 4, PerHostConcurrency: 4, PolitenessDelayMs: -1}
+	collector := NewRSSCollector(sites, cfg, server.Client(), nil)
+
+	_, articles, err := collector.Collect(context.Background(), news.State{})
+	if len(articles) != 2 || articles[0].Title != "/slow" || articles[1].Title != "/fast" {
+		t.Fatalf("Collect() articles = %+v, want /slow then /fast", articles)
+	}
+
+	var feedErr *FeedError
+	if !errors.As(err, &feedErr) {
+		t.Fatalf("Collect() error = %v, want *FeedError", err)
+	}
+	if feedErr.SiteID != "a" || feedErr.URL != server.URL+"/broken" {
+		t.Errorf("FeedError = %+v, want site a and /broken feed", feedErr)
+	}
+}
+
+func TestRSSCollector_Collect_ConditionalGet(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Dec 2024 03:00:00 GMT"
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		fmt.Fprint(w, `<rss><channel><item><title>Tin</title><link>https://example.com/1</link></item></channel></rss>`)
+	}))
+	defer server.Close()
+
+	feedURL := server.URL + "/rss"
+	sites := []config.Site{{ID: "a", RSSFeeds: []config.RSSFeed{{URL: feedURL}}}}
+	collector := NewRSSCollector(sites, config.Sources{PolitenessDelayMs: -1}, server.Client(), nil)
+
+	state, articles, err := collector.Collect(context.Background(), news.State{})
+	if err != nil || len(articles) != 1 {
+		t.Fatalf("first Collect() = %d articles, err %v; want 1 article", len(articles), err)
+	}
+	if got := state.Feeds[feedURL]; got.ETag != etag || got.LastModified != lastModified {
+		t.Fatalf("state.Feeds[%q] validators = %q / %q, want %q / %q", feedURL, got.ETag, got.LastModified, etag, lastModified)
+	}
+
+	// Повторный запуск с сохранённым состоянием: 304 означает «новых статей нет», а не ошибку
+	state, articles, err = collector.Collect(context.Background(), state)
+	if err != nil || len(articles) != 0 {
+		t.Fatalf("second Collect() = %d articles, err %v; want 0 articles", len(articles), err)
+	}
+	if got := state.Feeds[feedURL]; got.ETag != etag || got.LastModified != lastModified || got.LastItemCount != 1 {
+		t.Errorf("validators and item count should survive 304, got %+v", got)
+	}
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+}
+
 func TestRSSCollector_detectLanguage(t *testing.T) {
 	site := config.Site{ID: "test"}
 	lang := detectLanguage(site)
@@ -0,0 +1,272 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// vietnamTZ используется для дат без часового пояса на вьетнамских сайтах (UTC+7).
+var vietnamTZ = time.FixedZone("ICT", 7*60*60)
+
+// ScrapeCollector загружает новости со страниц-листингов сайтов без RSS.
+// Правила извлечения задаются селекторами в config.Site.Scrape.
+type ScrapeCollector struct {
+	sites    []config.Site
+	client   *http.Client
+	clock    func() time.Time
+	runner   *feedRunner
+	fullText *fullTextFetcher
+}
+
+// NewScrapeCollector создаёт новый экземпляр. Если clk == nil, используются системные часы.
+func NewScrapeCollector(sites []config.Site, cfg config.Sources, client *http.Client, clk clock.Clock) *ScrapeCollector {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	clk = clock.OrSystem(clk)
+	limiter := newSourceHostLimiter(cfg, clk)
+	return &ScrapeCollector{
+		sites:    sites,
+		client:   client,
+		clock:    clk.Now,
+		runner:   newFeedRunner("scrape page", cfg, limiter, clk.Now),
+		fullText: newFullTextFetcher(cfg, client, limiter, clk.Now),
+	}
+}
+
+// hostLimiter и setHostLimiter реализуют hostLimited.
+func (c *ScrapeCollector) hostLimiter() *hostLimiter {
+	return c.runner.limiter
+}
+
+func (c *ScrapeCollector) setHostLimiter(limiter *hostLimiter) {
+	c.runner.limiter = limiter
+	c.fullText.limiter = limiter
+}
+
+// Collect реализует app.SourceCollector.
+// Как и RSSCollector, загружает страницы параллельно, сохраняет порядок из конфига,
+// ведёт здоровье страниц в state.Feeds и возвращает ошибки отдельных страниц
+// объединёнными вместе с собранными статьями.
+func (c *ScrapeCollector) Collect(ctx context.Context, state news.State) (news.State, []news.ArticleRaw, error) {
+	var jobs []feedJob
+	var errs []error
+	for _, site := range c.sites {
+		if site.Scrape == nil || len(site.Scrape.Pages) == 0 {
+			continue
+		}
+
+		rules, err := compileScrapeRules(*site.Scrape)
+		if err != nil {
+			// Ошибка в конфиге одного сайта не должна останавливать сбор остальных
+			log.Printf("Invalid scrape config for site %s (%s): %v", site.ID, site.Name, err)
+			errs = append(errs, &FeedError{SiteID: site.ID, URL: site.URL, Err: fmt.Errorf("invalid scrape config: %w", err)})
+			continue
+		}
+
+		for _, page := range site.Scrape.Pages {
+			jobs = append(jobs, feedJob{
+				site: site,
+				url:  page.URL,
+				fetch: func(ctx context.Context, prev news.FeedState) ([]news.ArticleRaw, news.FeedState, error) {
+					// Листинги меняются при каждом запросе, поэтому загружаются без условных заголовков
+					articles, err := c.fetchPage(ctx, site, page, rules)
+					return articles, news.FeedState{}, err
+				},
+			})
+		}
+	}
+
+	state, results, pageErrs := c.runner.run(ctx, state, jobs)
+
+	// В листинге обычно нет текста статьи, поэтому full_text особенно полезен для скрапинга
+	results = c.fullText.enrich(ctx, c.sites, results)
+	return state, results, errors.Join(append(errs, pageErrs...)...)
+}
+
+// scrapeRules — скомпилированные селекторы из config.Scrape.
+type scrapeRules struct {
+	item       *selector
+	link       *selector
+	title      *selector
+	date       *selector
+	summary    *selector
+	dateAttr   string
+	dateFormat string
+}
+
+func compileScrapeRules(cfg config.Scrape) (scrapeRules, error) {
+	rules := scrapeRules{
+		dateAttr:   cfg.DateAttr,
+		dateFormat: cfg.DateFormat,
+	}
+
+	var err error
+	if rules.item, err = compileSelector(cfg.Item); err != nil {
+		return rules, fmt.Errorf("item: %w", err)
+	}
+
+	optional := []struct {
+		name  string
+		value string
+		dst   **selector
+	}{
+		{"link", cfg.Link, &rules.link},
+		{"title", cfg.Title, &rules.title},
+		{"date", cfg.Date, &rules.date},
+		{"summary", cfg.Summary, &rules.summary},
+	}
+	for _, opt := range optional {
+		if strings.TrimSpace(opt.value) == "" {
+			continue
+		}
+		if *opt.dst, err = compileSelector(opt.value); err != nil {
+			return rules, fmt.Errorf("%s: %w", opt.name, err)
+		}
+	}
+	return rules, nil
+}
+
+func (c *ScrapeCollector) fetchPage(ctx context.Context, site config.Site, page config.ScrapePage, rules scrapeRules) ([]news.ArticleRaw, error) {
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse page URL: %w", err)
+	}
+
+	body, err := httpGet(ctx, c.client, page.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse HTML: %w", err)
+	}
+
+	blocks := rules.item.matchAll(doc)
+	if len(blocks) > maxArticlesPerFeed {
+		blocks = blocks[:maxArticlesPerFeed]
+	}
+
+	now := c.clock()
+	articles := make([]news.ArticleRaw, 0, len(blocks))
+	seen := make(map[string]struct{}, len(blocks))
+	for i, block := range blocks {
+		item, published, ok := rules.extract(block, base, now)
+		if !ok {
+			continue
+		}
+		// Одна и та же статья часто встречается в листинге дважды (картинка + заголовок)
+		if _, dup := seen[item.Link]; dup {
+			continue
+		}
+		seen[item.Link] = struct{}{}
+
+		articles = append(articles, newArticle(site, page.Category, i, item, published))
+	}
+	if len(articles) == 0 {
+		// Селекторы ничего не нашли — скорее всего, сайт сменил вёрстку
+		return nil, errNoItems
+	}
+
+	return articles, nil
+}
+
+// extract извлекает из блока листинга ссылку, заголовок, анонс и дату.
+func (r scrapeRules) extract(block *html.Node, base *url.URL, now time.Time) (rssItem, time.Time, bool) {
+	linkNode := block
+	if r.link != nil {
+		linkNode = r.link.matchFirst(block)
+	} else if block.Data != "a" {
+		linkNode = firstElement(block, "a")
+	}
+	if linkNode == nil {
+		return rssItem{}, time.Time{}, false
+	}
+
+	href := strings.TrimSpace(nodeAttr(linkNode, "href"))
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return rssItem{}, time.Time{}, false
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return rssItem{}, time.Time{}, false
+	}
+
+	title := nodeText(linkNode)
+	if r.title != nil {
+		if titleNode := r.title.matchFirst(block); titleNode != nil {
+			title = nodeText(titleNode)
+		}
+	}
+	if title == "" {
+		// Ссылки-картинки часто хранят заголовок только в title
+		title = strings.TrimSpace(nodeAttr(linkNode, "title"))
+	}
+
+	var summary string
+	if r.summary != nil {
+		if summaryNode := r.summary.matchFirst(block); summaryNode != nil {
+			summary = nodeText(summaryNode)
+		}
+	}
+
+	item := rssItem{
+		Title:       title,
+		Link:        base.ResolveReference(ref).String(),
+		Description: summary,
+	}
+	if item.Title == "" {
+		return rssItem{}, time.Time{}, false
+	}
+
+	return item, r.parseDate(block, now), true
+}
+
+func (r scrapeRules) parseDate(block *html.Node, now time.Time) time.Time {
+	if r.date == nil {
+		return now
+	}
+	dateNode := r.date.matchFirst(block)
+	if dateNode == nil {
+		return now
+	}
+
+	value := nodeText(dateNode)
+	if r.dateAttr != "" {
+		value = strings.TrimSpace(nodeAttr(dateNode, r.dateAttr))
+	}
+
+	if r.dateFormat != "" {
+		if t, err := time.ParseInLocation(r.dateFormat, value, vietnamTZ); err == nil {
+			return t
+		}
+	}
+	return parseTime(value, now)
+}
+
+// firstElement возвращает первый элемент с указанным тегом внутри n.
+func firstElement(n *html.Node, tag string) *html.Node {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == tag {
+			return child
+		}
+		if found := firstElement(child, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
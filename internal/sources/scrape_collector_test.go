@@ -0,0 +1,174 @@
+package sources
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+const listingHTML = `<!DOCTYPE html>
+<html><body>
+<nav><a href="/about">About</a></nav>
+<div class="list-news">
+  <article class="item-news">
+    <a class="thumb" href="/tin-1.html" title="Tin một"><img src="1.jpg"></a>
+    <h3 class="title-news"><a href="/tin-1.html">Tin một</a></h3>
+    <p class="description">Mô tả tin một</p>
+    <time datetime="2024-12-03T10:00:00+07:00">3/12/2024</time>
+  </article>
+  <article class="item-news">
+    <h3 class="title-news"><a href="https://example.org/tin-2.html">Tin hai</a></h3>
+    <span class="date">03/12/2024 09:30</span>
+  </article>
+  <article class="item-news ads">
+    <h3 class="title-news"><a href="javascript:void(0)">Quảng cáo</a></h3>
+  </article>
+</div>
+</body></html>`
+
+func TestScrapeCollector_Collect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(listingHTML))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	site := config.Site{
+		ID:   "scraped",
+		Name: "Scraped Site",
+		URL:  server.URL,
+		Scrape: &config.Scrape{
+			Pages: []config.ScrapePage{
+				{URL: server.URL + "/thoi-su", Category: "Общество"},
+			},
+			Item:     "div.list-news > article.item-news",
+			Link:     "h3.title-news a",
+			Summary:  ".description",
+			Date:     "time[datetime]",
+			DateAttr: "datetime",
+		},
+	}
+
+	collector := NewScrapeCollector([]config.Site{site}, config.Sources{}, server.Client(), clock.NewFake(now))
+	_, articles, err := collector.Collect(context.Background(), news.State{})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(articles) != 2 {
+		t.Fatalf("Collect() len = %d, want 2 (javascript link skipped)", len(articles))
+	}
+
+	first := articles[0]
+	if first.URL != server.URL+"/tin-1.html" {
+		t.Errorf("URL = %q, want resolved relative link", first.URL)
+	}
+	if first.Title != "Tin một" {
+		t.Errorf("Title = %q, want %q", first.Title, "Tin một")
+	}
+	if first.RawContent != "Mô tả tin một" {
+		t.Errorf("RawContent = %q, want summary", first.RawContent)
+	}
+	if want := time.Date(2024, 12, 3, 3, 0, 0, 0, time.UTC); !first.PublishedAt.Equal(want) {
+		t.Errorf("PublishedAt = %v, want %v", first.PublishedAt, want)
+	}
+	if first.Metadata["rss_category"] != "Общество" {
+		t.Errorf("rss_category = %q, want page category", first.Metadata["rss_category"])
+	}
+	if first.ID != buildArticleID(site.ID, first.CanonicalURL, first.PublishedAt) {
+		t.Errorf("ID = %q, want the same scheme as RSSCollector", first.ID)
+	}
+
+	// Дата не найдена селектором — используется время сбора
+	if !articles[1].PublishedAt.Equal(now) {
+		t.Errorf("PublishedAt = %v, want clock fallback %v", articles[1].PublishedAt, now)
+	}
+}
+
+func TestScrapeCollector_Collect_SkipsSitesWithoutScrape(t *testing.T) {
+	site := config.Site{
+		ID:       "rss-only",
+		RSSFeeds: []config.RSSFeed{{URL: "https://example.com/rss"}},
+	}
+	collector := NewScrapeCollector([]config.Site{site}, config.Sources{}, nil, nil)
+	_, articles, err := collector.Collect(context.Background(), news.State{})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(articles) != 0 {
+		t.Errorf("Collect() len = %d, want 0", len(articles))
+	}
+}
+
+func TestScrapeRules_parseDate(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<div><span class="date">03/12/2024 09:30</span></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := compileScrapeRules(config.Scrape{
+		Item:       "div",
+		Date:       "span.date",
+		DateFormat: "02/01/2006 15:04",
+	})
+	if err != nil {
+		t.Fatalf("compileScrapeRules() error = %v", err)
+	}
+
+	got := rules.parseDate(doc, time.Time{})
+	want := time.Date(2024, 12, 3, 2, 30, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("parseDate() = %v, want %v (date_format in UTC+7)", got, want)
+	}
+}
+
+func TestCompileSelector(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<div id="main" class="a b">
+		<ul><li class="x"><a href="1" data-id="7">one</a></li><li><span><a href="2">two</a></span></li></ul>
+	</div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		selector string
+		wantErr  bool
+		want     int
+	}{
+		{name: "tag", selector: "a", want: 2},
+		{name: "id and classes", selector: "div#main.a.b", want: 1},
+		{name: "descendant", selector: "ul a", want: 2},
+		{name: "child", selector: "li > a", want: 1},
+		{name: "child without spaces", selector: "li>a", want: 1},
+		{name: "attribute presence", selector: "a[data-id]", want: 1},
+		{name: "attribute value", selector: `a[href="2"]`, want: 1},
+		{name: "class mismatch", selector: "div.c", want: 0},
+		{name: "empty", selector: "", wantErr: true},
+		{name: "dangling child", selector: "li >", wantErr: true},
+		{name: "unclosed attribute", selector: "a[href", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := compileSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compileSelector(%q) error = %v, wantErr %v", tt.selector, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := len(sel.matchAll(doc)); got != tt.want {
+				t.Errorf("matchAll(%q) = %d nodes, want %d", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
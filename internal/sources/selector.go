@@ -0,0 +1,239 @@
+package sources
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector — упрощённый CSS-селектор для скрапинга листингов.
+// Поддерживаются составные селекторы (tag, .class, #id, [attr], [attr=value])
+// и комбинаторы потомка (пробел) и прямого потомка (>).
+// Этого хватает для типичных листингов новостных сайтов без подключения полноценного CSS-движка.
+type selector struct {
+	steps []selectorStep
+}
+
+type selectorStep struct {
+	compound compoundSelector
+	child    bool // true, если перед шагом стоит ">" (прямой потомок предыдущего шага)
+}
+
+type compoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrSelector
+}
+
+type attrSelector struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// compileSelector разбирает строку селектора.
+func compileSelector(s string) (*selector, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+
+	// Отделяем ">" пробелами, чтобы "ul>li" и "ul > li" разбирались одинаково
+	s = strings.ReplaceAll(s, ">", " > ")
+
+	sel := &selector{}
+	child := false
+	for _, token := range strings.Fields(s) {
+		if token == ">" {
+			if len(sel.steps) == 0 || child {
+				return nil, fmt.Errorf("selector %q: unexpected '>'", s)
+			}
+			child = true
+			continue
+		}
+		compound, err := parseCompound(token)
+		if err != nil {
+			return nil, fmt.Errorf("selector %q: %w", s, err)
+		}
+		sel.steps = append(sel.steps, selectorStep{compound: compound, child: child})
+		child = false
+	}
+	if child || len(sel.steps) == 0 {
+		return nil, fmt.Errorf("selector %q: dangling '>'", s)
+	}
+	return sel, nil
+}
+
+func parseCompound(token string) (compoundSelector, error) {
+	var c compoundSelector
+	i := 0
+	readName := func() string {
+		start := i
+		for i < len(token) && !strings.ContainsRune(".#[", rune(token[i])) {
+			i++
+		}
+		return token[start:i]
+	}
+
+	c.tag = strings.ToLower(readName())
+	if c.tag == "*" {
+		c.tag = ""
+	}
+	for i < len(token) {
+		switch token[i] {
+		case '.':
+			i++
+			name := readName()
+			if name == "" {
+				return c, fmt.Errorf("empty class in %q", token)
+			}
+			c.classes = append(c.classes, name)
+		case '#':
+			i++
+			name := readName()
+			if name == "" {
+				return c, fmt.Errorf("empty id in %q", token)
+			}
+			c.id = name
+		case '[':
+			end := strings.IndexByte(token[i:], ']')
+			if end == -1 {
+				return c, fmt.Errorf("unclosed '[' in %q", token)
+			}
+			body := token[i+1 : i+end]
+			i += end + 1
+			attr := attrSelector{name: strings.ToLower(body)}
+			if eq := strings.IndexByte(body, '='); eq != -1 {
+				attr.name = strings.ToLower(strings.TrimSpace(body[:eq]))
+				attr.value = strings.Trim(strings.TrimSpace(body[eq+1:]), `"'`)
+				attr.hasValue = true
+			}
+			if attr.name == "" {
+				return c, fmt.Errorf("empty attribute in %q", token)
+			}
+			c.attrs = append(c.attrs, attr)
+		default:
+			return c, fmt.Errorf("unexpected %q in %q", token[i], token)
+		}
+	}
+	return c, nil
+}
+
+func (c compoundSelector) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if c.tag != "" && n.Data != c.tag {
+		return false
+	}
+	if c.id != "" && nodeAttr(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(nodeAttr(n, "class"))
+		for _, want := range c.classes {
+			found := false
+			for _, have := range classes {
+				if have == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, attr := range c.attrs {
+		value, ok := lookupAttr(n, attr.name)
+		if !ok || (attr.hasValue && value != attr.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAll возвращает все узлы внутри root (не включая сам root), подходящие под селектор,
+// в порядке обхода документа.
+func (s *selector) matchAll(root *html.Node) []*html.Node {
+	var result []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if s.matchesAt(child, len(s.steps)-1, root) {
+				result = append(result, child)
+			}
+			walk(child)
+		}
+	}
+	walk(root)
+	return result
+}
+
+// matchFirst возвращает первый подходящий узел или nil.
+func (s *selector) matchFirst(root *html.Node) *html.Node {
+	if nodes := s.matchAll(root); len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
+
+// matchesAt проверяет, что узел n подходит под шаг idx, а его предки — под предыдущие шаги.
+// Предки ищутся только внутри root.
+func (s *selector) matchesAt(n *html.Node, idx int, root *html.Node) bool {
+	step := s.steps[idx]
+	if !step.compound.matches(n) {
+		return false
+	}
+	if idx == 0 {
+		return true
+	}
+	for parent := n.Parent; parent != nil && parent != root; parent = parent.Parent {
+		if s.matchesAt(parent, idx-1, root) {
+			return true
+		}
+		if step.child {
+			return false
+		}
+	}
+	return false
+}
+
+// nodeAttr возвращает значение атрибута или пустую строку.
+func nodeAttr(n *html.Node, name string) string {
+	value, _ := lookupAttr(n, name)
+	return value
+}
+
+func lookupAttr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, name) {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// nodeText возвращает текстовое содержимое узла с нормализованными пробелами.
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		case html.ElementNode:
+			if n.Data == "script" || n.Data == "style" {
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
@@ -0,0 +1,324 @@
+package sources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+// Ограничения обхода индексов sitemap: индекс крупного сайта ссылается на сотни архивных sitemap.
+const (
+	maxSitemapChildren = 10
+	maxSitemapDepth    = 2
+)
+
+// SitemapCollector загружает новости из news sitemap (формат Google News: news:title,
+// news:publication_date) и индексов sitemap. На многих вьетнамских сайтах sitemap-news.xml
+// свежее и полнее RSS. В sitemap нет текста статьи, поэтому статьи всегда догружаются полным текстом.
+type SitemapCollector struct {
+	sites    []config.Site
+	client   *http.Client
+	clock    func() time.Time
+	maxAge   time.Duration
+	limiter  *hostLimiter
+	runner   *feedRunner
+	fullText *fullTextFetcher
+}
+
+// NewSitemapCollector создаёт новый экземпляр. Если clk == nil, используются системные часы.
+// maxAge — окно актуальности (pipeline.recency_max_hours): более старые записи sitemap не берутся.
+func NewSitemapCollector(sites []config.Site, cfg config.Sources, maxAge time.Duration, client *http.Client, clk clock.Clock) *SitemapCollector {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	clk = clock.OrSystem(clk)
+	if maxAge <= 0 {
+		maxAge = defaultFullTextMaxAge
+	}
+	limiter := newSourceHostLimiter(cfg, clk)
+	return &SitemapCollector{
+		sites:    sites,
+		client:   client,
+		clock:    clk.Now,
+		maxAge:   maxAge,
+		limiter:  limiter,
+		runner:   newFeedRunner("sitemap", cfg, limiter, clk.Now),
+		fullText: newFullTextFetcher(cfg, client, limiter, clk.Now),
+	}
+}
+
+// hostLimiter и setHostLimiter реализуют hostLimited.
+func (c *SitemapCollector) hostLimiter() *hostLimiter {
+	return c.runner.limiter
+}
+
+func (c *SitemapCollector) setHostLimiter(limiter *hostLimiter) {
+	c.limiter = limiter
+	c.runner.limiter = limiter
+	c.fullText.limiter = limiter
+}
+
+// Collect реализует app.SourceCollector.
+// Как и остальные коллекторы, загружает sitemap параллельно, сохраняет порядок из конфига,
+// ведёт здоровье sitemap в state.Feeds и возвращает ошибки объединёнными вместе с собранными статьями.
+func (c *SitemapCollector) Collect(ctx context.Context, state news.State) (news.State, []news.ArticleRaw, error) {
+	var jobs []feedJob
+	var fullTextSites []config.Site
+	for _, site := range c.sites {
+		if len(site.Sitemaps) == 0 {
+			continue
+		}
+		for _, sitemap := range site.Sitemaps {
+			jobs = append(jobs, feedJob{
+				site: site,
+				url:  sitemap.URL,
+				fetch: func(ctx context.Context, prev news.FeedState) ([]news.ArticleRaw, news.FeedState, error) {
+					// Индекс может не меняться, пока меняются дочерние sitemap, поэтому без условных заголовков
+					articles, err := c.fetchSitemap(ctx, site, sitemap)
+					return articles, news.FeedState{}, err
+				},
+			})
+		}
+		fullTextSite := site
+		fullTextSite.FullText = true
+		fullTextSites = append(fullTextSites, fullTextSite)
+	}
+
+	state, results, errs := c.runner.run(ctx, state, jobs)
+
+	results = c.fullText.enrich(ctx, fullTextSites, results)
+	return state, results, errors.Join(errs...)
+}
+
+// sitemapDocument покрывает оба вида sitemap: индекс (<sitemapindex>) и список URL (<urlset>).
+type sitemapDocument struct {
+	XMLName  xml.Name
+	Sitemaps []sitemapRef `xml:"sitemap"`
+	URLs     []sitemapURL `xml:"url"`
+}
+
+type sitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+type sitemapURL struct {
+	Loc     string         `xml:"loc"`
+	LastMod string         `xml:"lastmod"`
+	News    sitemapNews    `xml:"news"`
+	Images  []sitemapImage `xml:"image"`
+}
+
+// sitemapImage — расширение Google Image (xmlns:image="http://www.google.com/schemas/sitemap-image/1.1").
+type sitemapImage struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemapNews — расширение Google News (xmlns:news="http://www.google.com/schemas/sitemap-news/0.9").
+type sitemapNews struct {
+	Title           string `xml:"title"`
+	PublicationDate string `xml:"publication_date"`
+	Keywords        string `xml:"keywords"`
+}
+
+// sitemapEntry — запись sitemap, прошедшая фильтр по дате.
+type sitemapEntry struct {
+	item      rssItem
+	published time.Time
+}
+
+func (c *SitemapCollector) fetchSitemap(ctx context.Context, site config.Site, sitemap config.RSSFeed) ([]news.ArticleRaw, error) {
+	now := c.clock()
+	cutoff := now.Add(-c.maxAge)
+
+	entries, err := c.readSitemap(ctx, sitemap.URL, cutoff, now, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Пустое окно актуальности — не сбой: у новостного раздела бывают тихие дни
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	// Самые свежие первыми, как в RSS-лентах
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].published.After(entries[j].published)
+	})
+	if len(entries) > maxArticlesPerFeed {
+		entries = entries[:maxArticlesPerFeed]
+	}
+
+	articles := make([]news.ArticleRaw, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for i, entry := range entries {
+		if _, dup := seen[entry.item.Link]; dup {
+			continue
+		}
+		seen[entry.item.Link] = struct{}{}
+		articles = append(articles, newArticle(site, sitemap.Category, i, entry.item, entry.published))
+	}
+	return articles, nil
+}
+
+// readSitemap загружает sitemap и возвращает записи новее cutoff. Sitemap без единой записи — errNoItems.
+// Для индекса рекурсивно читает дочерние sitemap (не больше maxSitemapChildren свежих, глубина maxSitemapDepth).
+func (c *SitemapCollector) readSitemap(ctx context.Context, sitemapURL string, cutoff, now time.Time, depth int) ([]sitemapEntry, error) {
+	resp, err := conditionalGet(ctx, c.client, sitemapURL, news.FeedState{})
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseSitemap(resp.body, resp.contentType)
+	if err != nil {
+		return nil, fmt.Errorf("parse sitemap: %w", err)
+	}
+
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "urlset":
+		if len(doc.URLs) == 0 {
+			return nil, errNoItems
+		}
+		return sitemapEntries(doc.URLs, cutoff, now), nil
+	case "sitemapindex":
+		if depth >= maxSitemapDepth {
+			return nil, fmt.Errorf("sitemap index nested deeper than %d levels", maxSitemapDepth)
+		}
+		if len(doc.Sitemaps) == 0 {
+			return nil, errNoItems
+		}
+		var entries []sitemapEntry
+		var errs []error
+		for _, child := range freshSitemapRefs(doc.Sitemaps, cutoff, now) {
+			// Дочерние sitemap читаем последовательно внутри уже занятого слота хоста, выдерживая паузу
+			if err := c.limiter.pace(ctx, child); err != nil {
+				return nil, err
+			}
+			childEntries, err := c.readSitemap(ctx, child, cutoff, now, depth+1)
+			if err != nil {
+				log.Printf("Sitemap: failed to read %s: %v", child, err)
+				errs = append(errs, fmt.Errorf("%s: %w", child, err))
+				continue
+			}
+			entries = append(entries, childEntries...)
+		}
+		if len(entries) == 0 && len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return entries, nil
+	default:
+		return nil, fmt.Errorf("unsupported sitemap format: root element <%s>", doc.XMLName.Local)
+	}
+}
+
+// parseSitemap разбирает sitemap (в том числе сжатый .xml.gz) с учётом кодировки.
+func parseSitemap(data []byte, contentType string) (sitemapDocument, error) {
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return sitemapDocument{}, fmt.Errorf("gzip: %w", err)
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return sitemapDocument{}, fmt.Errorf("gzip: %w", err)
+		}
+	}
+
+	var doc sitemapDocument
+	if err := newFeedDecoder(fixXMLEntities(data), contentType).Decode(&doc); err != nil {
+		return sitemapDocument{}, err
+	}
+	return doc, nil
+}
+
+// sitemapEntries превращает записи <url> в элементы ленты. Берутся только записи с news:title
+// (обычный sitemap перечисляет все страницы сайта) и датой не старше cutoff и не из будущего.
+func sitemapEntries(urls []sitemapURL, cutoff, now time.Time) []sitemapEntry {
+	var entries []sitemapEntry
+	for _, u := range urls {
+		link := strings.TrimSpace(u.Loc)
+		title := strings.TrimSpace(u.News.Title)
+		if link == "" || title == "" {
+			continue
+		}
+		date := strings.TrimSpace(u.News.PublicationDate)
+		if date == "" {
+			date = strings.TrimSpace(u.LastMod)
+		}
+		if date == "" {
+			continue
+		}
+		// Нераспознанная дата не должна выдавать старую запись за свежую
+		published := parseTime(date, time.Time{})
+		if published.Before(cutoff) || published.After(now.Add(time.Hour)) {
+			continue
+		}
+		entries = append(entries, sitemapEntry{
+			item:      sitemapItem(u, title, link, date),
+			published: published,
+		})
+	}
+	return entries
+}
+
+// sitemapItem собирает элемент ленты из записи sitemap: картинки из image:image,
+// рубрики — из news:keywords (через запятую).
+func sitemapItem(u sitemapURL, title, link, date string) rssItem {
+	item := rssItem{
+		Title:   title,
+		Link:    link,
+		PubDate: date,
+	}
+	for _, image := range u.Images {
+		item.Thumbnails = append(item.Thumbnails, rssEnclosure{URL: image.Loc})
+	}
+	for _, keyword := range strings.Split(u.News.Keywords, ",") {
+		item.Categories = append(item.Categories, keyword)
+	}
+	return item
+}
+
+// freshSitemapRefs выбирает дочерние sitemap индекса, обновлявшиеся после cutoff (или без lastmod),
+// самые свежие первыми, не больше maxSitemapChildren.
+func freshSitemapRefs(refs []sitemapRef, cutoff, now time.Time) []string {
+	type ref struct {
+		loc     string
+		lastMod time.Time
+	}
+	var fresh []ref
+	for _, r := range refs {
+		loc := strings.TrimSpace(r.Loc)
+		if loc == "" {
+			continue
+		}
+		var lastMod time.Time
+		if s := strings.TrimSpace(r.LastMod); s != "" {
+			if lastMod = parseTime(s, now); lastMod.Before(cutoff) {
+				continue
+			}
+		}
+		fresh = append(fresh, ref{loc: loc, lastMod: lastMod})
+	}
+	sort.SliceStable(fresh, func(i, j int) bool {
+		return fresh[i].lastMod.After(fresh[j].lastMod)
+	})
+	if len(fresh) > maxSitemapChildren {
+		fresh = fresh[:maxSitemapChildren]
+	}
+
+	locs := make([]string, 0, len(fresh))
+	for _, r := range fresh {
+		locs = append(locs, r.loc)
+	}
+	return locs
+}
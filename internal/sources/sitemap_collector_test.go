@@ -0,0 +1,151 @@
+package sources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maine/vietnam_bot_news/internal/clock"
+	"github.com/maine/vietnam_bot_news/internal/config"
+	"github.com/maine/vietnam_bot_news/internal/news"
+)
+
+func TestSitemapCollector_Collect(t *testing.T) {
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+
+	var serverURL string
+	newsSitemap := func() string {
+		return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:news="http://www.google.com/schemas/sitemap-news/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>%[1]s/tin-cu.html</loc>
+    <news:news><news:title>Tin cũ</news:title><news:publication_date>2024-12-01T08:00:00+07:00</news:publication_date></news:news>
+  </url>
+  <url>
+    <loc>%[1]s/tin-1.html</loc>
+    <news:news><news:title>Tin một</news:title><news:publication_date>2024-12-03T18:00:00+07:00</news:publication_date><news:keywords>Giao thông, Hà Nội</news:keywords></news:news>
+    <image:image><image:loc>%[1]s/anh-1.jpg</image:loc></image:image>
+  </url>
+  <url>
+    <loc>%[1]s/tin-2.html</loc>
+    <lastmod>2024-12-03T10:00Z</lastmod>
+    <news:news><news:title>Tin hai</news:title></news:news>
+  </url>
+  <url>
+    <loc>%[1]s/gioi-thieu.html</loc>
+    <lastmod>2024-12-03</lastmod>
+  </url>
+</urlset>`, serverURL)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/sitemap-archive-2023.xml</loc><lastmod>2023-01-01</lastmod></sitemap>
+  <sitemap><loc>%[1]s/sitemap-news.xml.gz</loc><lastmod>2024-12-03T11:00:00Z</lastmod></sitemap>
+</sitemapindex>`, serverURL)
+	})
+	mux.HandleFunc("/sitemap-news.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte(newsSitemap()))
+		_ = zw.Close()
+		_, _ = w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/sitemap-archive-2023.xml", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("archive sitemap older than the recency window should not be fetched")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL = server.URL
+
+	site := config.Site{
+		ID:       "sm",
+		Name:     "Sitemap Site",
+		Sitemaps: []config.RSSFeed{{URL: server.URL + "/sitemap.xml", Category: "Общество"}},
+		Priority: 2,
+	}
+	cfg := config.Sources{PolitenessDelayMs: -1}
+	collector := NewSitemapCollector([]config.Site{site}, cfg, 24*time.Hour, server.Client(), clock.NewFake(now))
+
+	state, articles, err := collector.Collect(context.Background(), news.State{})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(articles) != 2 {
+		t.Fatalf("Collect() len = %d, want 2 (old and non-news entries skipped): %+v", len(articles), articles)
+	}
+
+	first := articles[0]
+	if first.Title != "Tin một" || first.URL != server.URL+"/tin-1.html" {
+		t.Errorf("first article = %q %q, want the freshest news entry", first.Title, first.URL)
+	}
+	if first.ID != buildArticleID(site.ID, first.CanonicalURL, first.PublishedAt) {
+		t.Errorf("ID = %q, want the same scheme as RSSCollector", first.ID)
+	}
+	if first.Metadata["rss_category"] != "Общество" || first.Priority != 2 {
+		t.Errorf("metadata = %v priority %d, want sitemap category and site priority", first.Metadata, first.Priority)
+	}
+	if first.ImageURL() != server.URL+"/anh-1.jpg" || first.Metadata[news.MetaTags] != "Giao thông; Hà Nội" {
+		t.Errorf("metadata = %v, want image:image and news:keywords", first.Metadata)
+	}
+	if want := time.Date(2024, 12, 3, 10, 0, 0, 0, time.UTC); !articles[1].PublishedAt.Equal(want) {
+		t.Errorf("PublishedAt = %v, want lastmod fallback %v", articles[1].PublishedAt, want)
+	}
+	if state.Feeds[site.Sitemaps[0].URL].LastItemCount != 2 {
+		t.Errorf("sitemap health = %+v, want 2 items", state.Feeds[site.Sitemaps[0].URL])
+	}
+}
+
+func TestSitemapCollector_CollectEmpty(t *testing.T) {
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		body         string
+		wantErr      bool
+		wantFailures int
+	}{
+		{
+			name: "no entries within recency window is a successful fetch",
+			body: `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:news="http://www.google.com/schemas/sitemap-news/0.9">
+  <url><loc>https://example.com/tin-cu.html</loc><news:news><news:title>Tin cũ</news:title><news:publication_date>2024-11-28T08:00:00+07:00</news:publication_date></news:news></url>
+</urlset>`,
+		},
+		{
+			name:         "sitemap without url entries counts as failure",
+			body:         `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"></urlset>`,
+			wantErr:      true,
+			wantFailures: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			site := config.Site{ID: "sm", Sitemaps: []config.RSSFeed{{URL: server.URL + "/sitemap-news.xml"}}}
+			collector := NewSitemapCollector([]config.Site{site}, config.Sources{PolitenessDelayMs: -1}, 24*time.Hour, server.Client(), clock.NewFake(now))
+
+			state, articles, err := collector.Collect(context.Background(), news.State{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Collect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(articles) != 0 {
+				t.Errorf("Collect() len = %d, want 0", len(articles))
+			}
+			feed := state.Feeds[site.Sitemaps[0].URL]
+			if feed.ConsecutiveFailures != tt.wantFailures || feed.LastCheckedAt.IsZero() {
+				t.Errorf("sitemap health = %+v, want %d consecutive failures", feed, tt.wantFailures)
+			}
+		})
+	}
+}
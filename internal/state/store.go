@@ -130,3 +130,27 @@ func (s *FileStore) DeleteDigest(ctx context.Context) error {
 	return nil
 }
 
+// SaveFilterAudit сохраняет отчёт фильтра за запуск рядом с состоянием (filter_audit.json).
+// Файл не коммитится: в CI он публикуется как артефакт запуска.
+func (s *FileStore) SaveFilterAudit(ctx context.Context, audit news.FilterAudit) error {
+	data, err := json.MarshalIndent(audit, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal filter audit: %w", err)
+	}
+
+	auditPath := filepath.Join(filepath.Dir(s.path), "filter_audit.json")
+	if err := os.MkdirAll(filepath.Dir(auditPath), 0755); err != nil {
+		return fmt.Errorf("create filter audit directory: %w", err)
+	}
+
+	tmpPath := auditPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write temp filter audit file: %w", err)
+	}
+	if err := os.Rename(tmpPath, auditPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("rename temp filter audit file: %w", err)
+	}
+
+	return nil
+}
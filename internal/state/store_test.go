@@ -2,6 +2,7 @@ package state
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -133,3 +134,28 @@ func TestFileStore_Save_Atomic(t *testing.T) {
 	}
 }
 
+func TestFileStore_SaveFilterAudit(t *testing.T) {
+	tmpDir := t.TempDir()
+	store := NewFileStore(filepath.Join(tmpDir, "state", "state.json"))
+
+	audit := news.NewFilterAudit([]news.FilterDecision{
+		{ArticleID: "a", Source: "vnexpress", Kept: true, Reason: news.FilterKept},
+		{ArticleID: "b", Source: "vnexpress", Reason: news.FilterTooOld},
+	}, time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC))
+
+	if err := store.SaveFilterAudit(context.Background(), audit); err != nil {
+		t.Fatalf("SaveFilterAudit() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "state", "filter_audit.json"))
+	if err != nil {
+		t.Fatalf("read filter audit: %v", err)
+	}
+	var loaded news.FilterAudit
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("unmarshal filter audit: %v", err)
+	}
+	if loaded.Total != 2 || loaded.Kept != 1 || loaded.BySource["vnexpress"][news.FilterTooOld] != 1 || len(loaded.Decisions) != 2 {
+		t.Errorf("loaded audit = %+v", loaded)
+	}
+}
@@ -7,8 +7,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
 
@@ -16,13 +16,15 @@ import (
 type RecipientManager struct {
 	client        TelegramClient
 	autoSubscribe bool
+	clock         clock.Clock
 }
 
-// NewRecipientManager создаёт менеджер.
-func NewRecipientManager(client TelegramClient, auto bool) *RecipientManager {
+// NewRecipientManager создаёт менеджер. Если clk == nil, используются системные часы.
+func NewRecipientManager(client TelegramClient, auto bool, clk clock.Clock) *RecipientManager {
 	return &RecipientManager{
 		client:        client,
 		autoSubscribe: auto,
+		clock:         clock.OrSystem(clk),
 	}
 }
 
@@ -90,7 +92,7 @@ func (m *RecipientManager) Resolve(ctx context.Context, state news.State) (news.
 			recipients[chatID] = news.RecipientBinding{
 				Name:      name,
 				ChatID:    chatID,
-				UpdatedAt: time.Now(),
+				UpdatedAt: m.clock.Now(),
 			}
 		}
 
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
 
@@ -195,7 +197,7 @@ func TestRecipientManager_Resolve(t *testing.T) {
 				mockClient := &mockTelegramClientForRecipients{
 					getUpdatesFunc: tt.mockFunc,
 				}
-				manager = NewRecipientManager(mockClient, tt.autoSubscribe)
+				manager = NewRecipientManager(mockClient, tt.autoSubscribe, nil)
 			}
 
 			ctx := context.Background()
@@ -222,6 +224,23 @@ func TestRecipientManager_Resolve(t *testing.T) {
 	}
 }
 
+func TestRecipientManager_Resolve_UsesClock(t *testing.T) {
+	now := time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC)
+	mockClient := &mockTelegramClientForRecipients{
+		getUpdatesFunc: func(ctx context.Context, offset int64, timeout int) ([]Update, error) {
+			return []Update{{UpdateID: 1, Message: &Message{Chat: Chat{ID: 123, Type: "private"}, Text: "/start"}}}, nil
+		},
+	}
+
+	_, recipients, err := NewRecipientManager(mockClient, true, clock.NewFake(now)).Resolve(context.Background(), news.State{})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(recipients) != 1 || !recipients[0].UpdatedAt.Equal(now) {
+		t.Errorf("Resolve() recipients = %+v, want one stamped %v", recipients, now)
+	}
+}
+
 func TestRecipientManager_deriveRecipientName(t *testing.T) {
 	tests := []struct {
 		name string
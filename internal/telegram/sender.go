@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
 
@@ -24,12 +25,14 @@ const (
 // Sender реализует app.Sender для отправки сообщений получателям через Telegram.
 type Sender struct {
 	client TelegramClient
+	clock  clock.Clock
 }
 
-// NewSender создаёт новый экземпляр отправителя.
-func NewSender(client TelegramClient) *Sender {
+// NewSender создаёт новый экземпляр отправителя. Если clk == nil, используются системные часы.
+func NewSender(client TelegramClient, clk clock.Clock) *Sender {
 	return &Sender{
 		client: client,
+		clock:  clock.OrSystem(clk),
 	}
 }
 
@@ -47,18 +50,15 @@ func (s *Sender) Send(ctx context.Context, recipients []news.RecipientBinding, m
 	log.Printf("Sending %d messages to %d recipients (total: %d messages)", len(messages), len(recipients), totalMessages)
 
 	sentCount := 0
-	lastSentTime := time.Now()
+	lastSentTime := s.clock.Now()
 
 	for _, recipient := range recipients {
 		for _, message := range messages {
 			// Контроль rate limit: минимальная задержка между сообщениями
-			elapsed := time.Since(lastSentTime)
+			elapsed := s.clock.Now().Sub(lastSentTime)
 			if elapsed < rateLimitDelay {
-				waitTime := rateLimitDelay - elapsed
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case <-time.After(waitTime):
+				if err := s.clock.Sleep(ctx, rateLimitDelay-elapsed); err != nil {
+					return err
 				}
 			}
 
@@ -71,7 +71,7 @@ func (s *Sender) Send(ctx context.Context, recipients []news.RecipientBinding, m
 			}
 
 			sentCount++
-			lastSentTime = time.Now()
+			lastSentTime = s.clock.Now()
 		}
 	}
 
@@ -91,10 +91,8 @@ func (s *Sender) sendWithRetry(ctx context.Context, chatID string, message strin
 				delay = 10 * time.Second
 			}
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(delay):
+			if err := s.clock.Sleep(ctx, delay); err != nil {
+				return err
 			}
 		}
 
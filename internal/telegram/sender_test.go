@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/maine/vietnam_bot_news/internal/clock"
 	"github.com/maine/vietnam_bot_news/internal/news"
 )
 
@@ -121,7 +122,8 @@ func TestSender_Send(t *testing.T) {
 			mockClient := &mockTelegramClient{
 				sendMessageFunc: tt.mockFunc,
 			}
-			sender := NewSender(mockClient)
+			// Ручные часы: паузы rate limit и retry не ждут по-настоящему
+			sender := NewSender(mockClient, clock.NewFake(time.Now()))
 			ctx := context.Background()
 
 			err := sender.Send(ctx, tt.recipients, tt.messages)
@@ -225,7 +227,8 @@ func TestSender_RateLimit(t *testing.T) {
 			return nil
 		},
 	}
-	sender := NewSender(mockClient)
+	clk := clock.NewFake(time.Date(2024, 12, 3, 12, 0, 0, 0, time.UTC))
+	sender := NewSender(mockClient, clk)
 	ctx := context.Background()
 
 	recipients := []news.RecipientBinding{
@@ -234,17 +237,17 @@ func TestSender_RateLimit(t *testing.T) {
 	}
 	messages := []string{"Message 1", "Message 2", "Message 3"}
 
-	start := time.Now()
 	err := sender.Send(ctx, recipients, messages)
-	duration := time.Since(start)
-
 	if err != nil {
 		t.Errorf("Send() error = %v", err)
 	}
 
-	// Проверяем, что была задержка (минимум 5 сообщений * rateLimitDelay)
-	// rateLimitDelay = ~33ms, для 6 сообщений минимум ~165ms
-	if duration < 100*time.Millisecond {
-		t.Errorf("Send() should respect rate limit, duration = %v", duration)
+	// Проверяем, что между сообщениями выдерживалась пауза (минимум 5 сообщений * rateLimitDelay)
+	var duration time.Duration
+	for _, d := range clk.Slept() {
+		duration += d
+	}
+	if duration < 5*rateLimitDelay {
+		t.Errorf("Send() should respect rate limit, slept = %v", duration)
 	}
 }
@@ -0,0 +1,61 @@
+// Package textnorm содержит нормализацию текста новостей, общую для источников, фильтра и форматтера.
+package textnorm
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NFC приводит строку к Unicode NFC.
+// Вьетнамские сайты публикуют диакритику и в составной форме (a + U+0302 + U+0301), и в предсоставленной (ấ):
+// без нормализации одинаковые заголовки из разных источников не совпадают при сравнении.
+func NFC(s string) string {
+	return norm.NFC.String(s)
+}
+
+// minFingerprintWords — заголовки короче этого числа слов («Tin tức», «Video») слишком общие,
+// чтобы по ним узнавать уже отправленную новость.
+const minFingerprintWords = 4
+
+// TitleFingerprint возвращает отпечаток заголовка для поиска повторов: хэш слов заголовка
+// в NFC и нижнем регистре без пунктуации. Одинаковые заголовки с разной пунктуацией, регистром
+// или формой диакритики дают один отпечаток. Для слишком коротких заголовков возвращает "".
+func TitleFingerprint(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(NFC(title)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+	if len(words) < minFingerprintWords {
+		return ""
+	}
+	sum := sha1.Sum([]byte(strings.Join(words, " ")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// StripDiacritics убирает диакритику, сохраняя регистр: «Xổ số Đà Nẵng» → «Xo so Da Nang».
+// Вьетнамская «đ» не раскладывается в Unicode и заменяется на «d» явно.
+func StripDiacritics(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r == 'đ':
+			r = 'd'
+		case r == 'Đ':
+			r = 'D'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Fold приводит текст к виду для сравнения без учёта регистра и диакритики:
+// «Xổ số Đà Nẵng» → «xo so da nang». Нужен, потому что часть лент и читателей пишет без диакритики.
+func Fold(s string) string {
+	return strings.ToLower(StripDiacritics(s))
+}
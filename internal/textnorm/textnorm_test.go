@@ -0,0 +1,85 @@
+package textnorm
+
+import "testing"
+
+func TestNFC(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "decomposed vietnamese diacritics are composed",
+			input: "Vie\u0302\u0323t Nam",
+			want:  "Việt Nam",
+		},
+		{
+			name:  "precomposed text is unchanged",
+			input: "Việt Nam",
+			want:  "Việt Nam",
+		},
+		{
+			name:  "ascii is unchanged",
+			input: "Hanoi",
+			want:  "Hanoi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NFC(tt.input); got != tt.want {
+				t.Errorf("NFC(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTitleFingerprint(t *testing.T) {
+	base := TitleFingerprint("Hà Nội cấm xe máy ở nội thành từ năm 2030")
+	if base == "" {
+		t.Fatal("TitleFingerprint() of a regular title should not be empty")
+	}
+
+	tests := []struct {
+		name  string
+		title string
+		same  bool
+	}{
+		{"case and punctuation", "  HÀ NỘI cấm xe máy ở nội thành từ năm 2030!", true},
+		{"decomposed diacritics", "Ha\u0300 No\u0302\u0323i cấm xe máy ở nội thành từ năm 2030", true},
+		{"quotes and dashes", "Hà Nội cấm xe máy ở \"nội thành\" — từ năm 2030", true},
+		{"different year", "Hà Nội cấm xe máy ở nội thành từ năm 2035", false},
+		{"diacritics matter", "Ha Noi cam xe may o noi thanh tu nam 2030", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TitleFingerprint(tt.title); (got == base) != tt.same {
+				t.Errorf("TitleFingerprint(%q) = %q, base %q, want same = %v", tt.title, got, base, tt.same)
+			}
+		})
+	}
+
+	if got := TitleFingerprint("Tin tức 24h"); got != "" {
+		t.Errorf("TitleFingerprint() of a short title = %q, want empty", got)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Xổ số Đà Nẵng", "xo so da nang"},
+		{"Xổ số hôm nay", "xo so hom nay"},
+		{"TAI NẠN giao thông", "tai nan giao thong"},
+		{"Hanoi, 2024!", "hanoi, 2024!"},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.input); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	if got := StripDiacritics("Đà Nẵng"); got != "Da Nang" {
+		t.Errorf("StripDiacritics() = %q, want case preserved", got)
+	}
+}
@@ -0,0 +1,116 @@
+// Package urlnorm приводит ссылки на статьи к каноническому виду, чтобы одна и та же новость,
+// пришедшая с метками рассылки, через AMP-версию или мобильный поддомен, давала один ключ.
+package urlnorm
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// trackingParams — параметры запроса, которые не меняют содержимое страницы.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"mc_cid": true, "mc_eid": true, "_ga": true, "igshid": true,
+	"zarsrc": true, "gidzl": true, // Zalo
+	"ref": true, "ref_src": true, "cmpid": true, "vn_source": true, "vn_medium": true, "vn_campaign": true,
+	"amp": true, "outputtype": true, // AMP-версия: ?amp=1, ?outputType=amp
+}
+
+// trackingPrefixes — семейства параметров аналитики (utm_source, utm_medium, ...).
+var trackingPrefixes = []string{"utm_", "itm_", "at_"}
+
+// hostPrefixes — поддомены мобильных и AMP-версий, ведущие на ту же статью.
+var hostPrefixes = []string{"www.", "m.", "mobile.", "amp."}
+
+// Canonical возвращает канонический вид ссылки: схема https, хост в нижнем регистре без www/m/amp
+// и порта по умолчанию, путь без AMP-суффиксов и завершающего слэша, без фрагмента и меток
+// отслеживания, оставшиеся параметры отсортированы. Результат — ключ для сравнения ссылок,
+// а не обязательно рабочий адрес. Нераспознанная строка возвращается без пробелов по краям.
+func Canonical(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	if scheme := strings.ToLower(u.Scheme); scheme != "http" && scheme != "https" {
+		return raw
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range hostPrefixes {
+		// Хост из одного домена второго уровня (m.vn) не трогаем
+		if rest := strings.TrimPrefix(host, prefix); rest != host && strings.Contains(rest, ".") {
+			host = rest
+			break
+		}
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	return (&url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     canonicalPath(u.Path),
+		RawQuery: canonicalQuery(u.Query()),
+	}).String()
+}
+
+// canonicalPath убирает AMP-варианты пути (/amp/..., .../amp, name.amp.html) и завершающий слэш.
+func canonicalPath(p string) string {
+	p = strings.TrimPrefix(p, "/amp/")
+	if p != "" && !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	p = strings.TrimSuffix(strings.TrimSuffix(p, "/"), "/amp")
+	if ext := path.Ext(p); ext != "" {
+		p = strings.TrimSuffix(strings.TrimSuffix(p, ext), ".amp") + ext
+	}
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if !isTrackingParam(key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(url.QueryEscape(key))
+			sb.WriteByte('=')
+			sb.WriteString(url.QueryEscape(value))
+		}
+	}
+	return sb.String()
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	if trackingParams[key] {
+		return true
+	}
+	for _, prefix := range trackingPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
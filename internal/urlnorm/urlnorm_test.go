@@ -0,0 +1,75 @@
+package urlnorm
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "already canonical",
+			in:   "https://vnexpress.net/tin-1.html",
+			want: "https://vnexpress.net/tin-1.html",
+		},
+		{
+			name: "tracking params and fragment",
+			in:   "https://vnexpress.net/tin-1.html?utm_source=rss&utm_medium=feed&zarsrc=30#box_comment",
+			want: "https://vnexpress.net/tin-1.html",
+		},
+		{
+			name: "meaningful params kept and sorted",
+			in:   "https://example.vn/news?page=2&id=10&fbclid=abc",
+			want: "https://example.vn/news?id=10&page=2",
+		},
+		{
+			name: "http, www, uppercase host and default port",
+			in:   "HTTP://WWW.Tuoitre.VN:80/Tin-2.htm",
+			want: "https://tuoitre.vn/Tin-2.htm",
+		},
+		{
+			name: "mobile subdomain and trailing slash",
+			in:   "https://m.thanhnien.vn/thoi-su/tin-3/",
+			want: "https://thanhnien.vn/thoi-su/tin-3",
+		},
+		{
+			name: "amp path prefix",
+			in:   "https://dantri.com.vn/amp/xa-hoi/tin-4.htm",
+			want: "https://dantri.com.vn/xa-hoi/tin-4.htm",
+		},
+		{
+			name: "amp path suffix",
+			in:   "https://example.vn/tin-5/amp/",
+			want: "https://example.vn/tin-5",
+		},
+		{
+			name: "amp extension and query",
+			in:   "https://amp.example.vn/tin-6.amp.html?amp=1",
+			want: "https://example.vn/tin-6.html",
+		},
+		{
+			name: "root path",
+			in:   "https://www.example.vn",
+			want: "https://example.vn/",
+		},
+		{
+			name: "non-default port kept",
+			in:   "http://localhost:8080/a/",
+			want: "https://localhost:8080/a",
+		},
+		{
+			name: "not an absolute URL",
+			in:   "  /relative/path ",
+			want: "/relative/path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Canonical(tt.in); got != tt.want {
+				t.Errorf("Canonical(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}